@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	kitgrpc "github.com/go-kit/kit/transport/grpc"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthgrpc "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+
+	pb "github.com/cage1016/gokitconsulk8s/pb/addsvc"
+	"github.com/cage1016/gokitconsulk8s/pkg/addsvc/endpoints"
+	"github.com/cage1016/gokitconsulk8s/pkg/addsvc/service"
+	"github.com/cage1016/gokitconsulk8s/pkg/addsvc/transports"
+	"github.com/cage1016/gokitconsulk8s/pkg/admin"
+	"github.com/cage1016/gokitconsulk8s/pkg/config"
+	"github.com/cage1016/gokitconsulk8s/pkg/debuglog"
+	"github.com/cage1016/gokitconsulk8s/pkg/fault"
+)
+
+// startAddsvc starts the HTTP, gRPC and admin servers of addsvc, reporting
+// their failure on errs, and returns its endpoints.
+func startAddsvc(cfg config.Addsvc, errs chan error) (endpoints.Endpoints, error) {
+	logger, levels, err := newServiceLogger(cfg.Service)
+	if err != nil {
+		return endpoints.Endpoints{}, err
+	}
+
+	tracer := initOpentracing()
+	zipkinTracer, err := initZipkin(cfg.ServiceName, cfg.HTTPPort, cfg.ZipkinV2URL, logger)
+	if err != nil {
+		return endpoints.Endpoints{}, err
+	}
+	rc := resilienceConfig(cfg.Resilience)
+
+	service := service.New(logger)
+	dl := debuglog.NewRegistry(debuglog.Config{
+		Methods:  cfg.DebugLog.Methods,
+		MaxBytes: cfg.DebugLog.MaxBytes,
+		Redact:   cfg.DebugLog.Redact,
+	})
+	fi, err := fault.NewInjector(fault.Config{
+		Enabled: cfg.Fault.Enabled,
+		Headers: cfg.Fault.Headers,
+		Rules:   cfg.Fault.Rules,
+	})
+	if err != nil {
+		return endpoints.Endpoints{}, fmt.Errorf("fault rules: %v", err)
+	}
+	if cfg.Fault.Enabled {
+		level.Warn(logger).Log("fault", "injection enabled", "rules", cfg.Fault.Rules, "headers", cfg.Fault.Headers)
+	}
+	endpoints := endpoints.New(service, logger, tracer, zipkinTracer, rc, dl, fi)
+
+	m := http.NewServeMux()
+	m.Handle("/metrics", promhttp.Handler())
+	m.Handle("/", transports.NewHTTPHandler(endpoints, tracer, zipkinTracer, logger))
+	go startHTTPServer(compressHTTP(m, cfg.Compression), cfg.HTTPPort, logger, errs)
+
+	am := admin.NewHandler(cfg.ServiceName, levels)
+	am.Handle(debuglog.Path, dl)
+	go startHTTPServer(am, cfg.AdminPort, log.With(logger, "server", "admin"), errs)
+
+	hs := health.NewServer()
+	hs.SetServingStatus(cfg.ServiceName, healthgrpc.HealthCheckResponse_SERVING)
+	server := grpc.NewServer(grpc.UnaryInterceptor(kitgrpc.Interceptor))
+	pb.RegisterAddsvcServer(server, transports.MakeGRPCServer(endpoints, tracer, zipkinTracer, logger))
+	healthgrpc.RegisterHealthServer(server, hs)
+	reflection.Register(server)
+	go startGRPCServer(server, cfg.GRPCPort, logger, errs)
+
+	return endpoints, nil
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"golang.org/x/time/rate"
+
+	"github.com/cage1016/gokitconsulk8s/pkg/resilience"
+)
+
+// benchReport summarizes a bench run. Latencies are in milliseconds so that
+// JSON reports of different runs can be compared directly.
+type benchReport struct {
+	Method      string         `json:"method"`
+	Transport   string         `json:"transport"`
+	Target      string         `json:"target"`
+	Concurrency int            `json:"concurrency"`
+	TargetRPS   float64        `json:"target_rps,omitempty"`
+	Calls       int            `json:"calls"`
+	Failed      int            `json:"failed"`
+	Duration    float64        `json:"duration_s"`
+	Throughput  float64        `json:"throughput_rps"`
+	Latency     benchLatency   `json:"latency_ms"`
+	Errors      map[string]int `json:"errors,omitempty"`
+}
+
+type benchLatency struct {
+	Min  float64 `json:"min"`
+	Mean float64 `json:"mean"`
+	P50  float64 `json:"p50"`
+	P90  float64 `json:"p90"`
+	P95  float64 `json:"p95"`
+	P99  float64 `json:"p99"`
+	P999 float64 `json:"p99.9"`
+	Max  float64 `json:"max"`
+}
+
+// benchWorker holds what a single worker observed, merged into the report
+// once the run is over.
+type benchWorker struct {
+	latencies []time.Duration
+	errors    map[string]int
+}
+
+// bench calls a method of addsvc or foosvc repeatedly, from -c concurrent
+// workers and at most -rps calls per second, and reports the latency, errors
+// and throughput of the calls.
+func bench(args []string, logger log.Logger) error {
+	var (
+		fs          = flag.NewFlagSet("bench", flag.ContinueOnError)
+		cf          clientFlags
+		output      = fs.String("output", "text", "output format: text or json")
+		concurrency = fs.Int("c", 10, "number of concurrent workers")
+		rps         = fs.Float64("rps", 0, "target calls per second across workers, 0 for as many as the workers make")
+		duration    = fs.Duration("d", 10*time.Second, "duration of the run")
+		total       = fs.Int("n", 0, "number of calls, ends the run before -d when reached; 0 for no limit")
+	)
+	cf.register(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: bench [flags] METHOD A B | foo S\n"+
+			"METHOD is sum, concat, subtract, multiply, divide or modulo, or one of sumfloat, subtractfloat, multiplyfloat, dividefloat and modulofloat\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+	if *output != "text" && *output != "json" {
+		return fmt.Errorf("unknown output %q, want text or json", *output)
+	}
+	if *concurrency < 1 || *rps < 0 || *duration <= 0 || *total < 0 {
+		return fmt.Errorf("-c must be at least 1, -d positive and -rps and -n not negative")
+	}
+
+	zipkinTracer, r, err := newClientTracer(cf.zipkinURL)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	method := fs.Arg(0)
+	// The client neither limits, queues nor breaks the calls, so that the
+	// errors reported are those of the service.
+	invoke, err := cf.newInvoker(method, fs.Args()[1:], resilience.Config{}, initOpentracing(), zipkinTracer, logger)
+	if err != nil {
+		return err
+	}
+
+	limiter := rate.NewLimiter(rate.Inf, 0)
+	if *rps > 0 {
+		limiter = rate.NewLimiter(rate.Limit(*rps), 1)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), *duration)
+	defer cancel()
+
+	var (
+		started int64
+		wg      sync.WaitGroup
+		workers = make([]benchWorker, *concurrency)
+		begin   = time.Now()
+	)
+	for i := range workers {
+		wg.Add(1)
+		go func(w *benchWorker) {
+			defer wg.Done()
+			w.errors = map[string]int{}
+			for {
+				if *total > 0 && atomic.AddInt64(&started, 1) > int64(*total) {
+					return
+				}
+				if limiter.Wait(ctx) != nil {
+					return
+				}
+				callCtx, callCancel := context.WithTimeout(context.Background(), cf.timeout)
+				callBegin := time.Now()
+				_, err := invoke(callCtx)
+				w.latencies = append(w.latencies, time.Since(callBegin))
+				callCancel()
+				if err != nil {
+					w.errors[errorCode(err).String()]++
+				}
+			}
+		}(&workers[i])
+	}
+	wg.Wait()
+	elapsed := time.Since(begin)
+
+	report := benchReport{
+		Method:      method,
+		Transport:   cf.transport,
+		Target:      cf.targetOf(methods[method].service),
+		Concurrency: *concurrency,
+		TargetRPS:   *rps,
+		Duration:    elapsed.Seconds(),
+		Errors:      map[string]int{},
+	}
+	var latencies []time.Duration
+	for _, w := range workers {
+		latencies = append(latencies, w.latencies...)
+		for code, n := range w.errors {
+			report.Errors[code] += n
+			report.Failed += n
+		}
+	}
+	report.Calls = len(latencies)
+	report.Throughput = float64(report.Calls) / elapsed.Seconds()
+	report.Latency = summarizeLatencies(latencies)
+
+	if *output == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+	printBenchReport(os.Stdout, report)
+	return nil
+}
+
+// summarizeLatencies returns the distribution of latencies, which it sorts.
+func summarizeLatencies(latencies []time.Duration) benchLatency {
+	if len(latencies) == 0 {
+		return benchLatency{}
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+	var sum time.Duration
+	for _, l := range latencies {
+		sum += l
+	}
+	ms := func(d time.Duration) float64 { return float64(d) / float64(time.Millisecond) }
+	// percentile uses the nearest-rank method.
+	percentile := func(p float64) float64 {
+		i := int(math.Ceil(p/100*float64(len(latencies)))) - 1
+		if i < 0 {
+			i = 0
+		}
+		return ms(latencies[i])
+	}
+	return benchLatency{
+		Min:  ms(latencies[0]),
+		Mean: ms(sum / time.Duration(len(latencies))),
+		P50:  percentile(50),
+		P90:  percentile(90),
+		P95:  percentile(95),
+		P99:  percentile(99),
+		P999: percentile(99.9),
+		Max:  ms(latencies[len(latencies)-1]),
+	}
+}
+
+func printBenchReport(w io.Writer, r benchReport) {
+	fmt.Fprintf(w, "%s over %s to %s, %d workers", r.Method, r.Transport, r.Target, r.Concurrency)
+	if r.TargetRPS > 0 {
+		fmt.Fprintf(w, ", target %g calls/s", r.TargetRPS)
+	}
+	fmt.Fprintf(w, "\n\n")
+	fmt.Fprintf(w, "calls:       %d (%d failed) in %.2fs\n", r.Calls, r.Failed, r.Duration)
+	fmt.Fprintf(w, "throughput:  %.1f calls/s\n\n", r.Throughput)
+
+	l := r.Latency
+	fmt.Fprintf(w, "latency (ms):\n")
+	for _, p := range []struct {
+		name  string
+		value float64
+	}{
+		{"min", l.Min}, {"mean", l.Mean}, {"p50", l.P50}, {"p90", l.P90},
+		{"p95", l.P95}, {"p99", l.P99}, {"p99.9", l.P999}, {"max", l.Max},
+	} {
+		fmt.Fprintf(w, "  %-6s %9.3f\n", p.name, p.value)
+	}
+
+	if len(r.Errors) == 0 {
+		return
+	}
+	codes := make([]string, 0, len(r.Errors))
+	for code := range r.Errors {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	fmt.Fprintf(w, "\nerrors:\n")
+	for _, code := range codes {
+		fmt.Fprintf(w, "  %-18s %d\n", code, r.Errors[code])
+	}
+}
@@ -0,0 +1,333 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	stdopentracing "github.com/opentracing/opentracing-go"
+	stdzipkin "github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/reporter"
+	zipkinhttp "github.com/openzipkin/zipkin-go/reporter/http"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/cage1016/gokitconsulk8s/pkg/addsvc/endpoints"
+	addsvcservice "github.com/cage1016/gokitconsulk8s/pkg/addsvc/service"
+	addsvctransports "github.com/cage1016/gokitconsulk8s/pkg/addsvc/transports"
+	"github.com/cage1016/gokitconsulk8s/pkg/config"
+	foosvctransports "github.com/cage1016/gokitconsulk8s/pkg/foosvc/transports"
+	"github.com/cage1016/gokitconsulk8s/pkg/resilience"
+)
+
+// defaultTargets are the addresses of the services, by transport, when run
+// with their default configuration.
+var defaultTargets = map[string]map[string]string{
+	"addsvc": {config.TransportGRPC: "localhost:8181", config.TransportHTTP: "localhost:8180"},
+	"foosvc": {config.TransportGRPC: "localhost:7181", config.TransportHTTP: "localhost:7180"},
+}
+
+// methods maps the methods the client can call to their service and
+// arguments.
+var methods = map[string]struct {
+	service string
+	args    []string
+}{
+	"sum":           {"addsvc", []string{"a", "b"}},
+	"concat":        {"addsvc", []string{"a", "b"}},
+	"subtract":      {"addsvc", []string{"a", "b"}},
+	"multiply":      {"addsvc", []string{"a", "b"}},
+	"divide":        {"addsvc", []string{"a", "b"}},
+	"modulo":        {"addsvc", []string{"a", "b"}},
+	"sumfloat":      {"addsvc", []string{"a", "b"}},
+	"subtractfloat": {"addsvc", []string{"a", "b"}},
+	"multiplyfloat": {"addsvc", []string{"a", "b"}},
+	"dividefloat":   {"addsvc", []string{"a", "b"}},
+	"modulofloat":   {"addsvc", []string{"a", "b"}},
+	"foo":           {"foosvc", []string{"s"}},
+}
+
+// clientFlags configure how the client and bench commands reach a service.
+type clientFlags struct {
+	transport string
+	target    string
+	timeout   time.Duration
+	zipkinURL string
+}
+
+func (f *clientFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.transport, "transport", config.TransportGRPC, "transport: grpc or http")
+	fs.StringVar(&f.target, "target", "", "address of the service or of the router, defaults to the local service; "+
+		"HTTP targets may have a path prefix, e.g. localhost:8080/addsvc through the router")
+	fs.DurationVar(&f.timeout, "timeout", 5*time.Second, "timeout of a call")
+	fs.StringVar(&f.zipkinURL, "zipkin-v2-url", os.Getenv("QS_ZIPKIN_V2_URL"), "Zipkin v2 span collector URL the client spans are reported to")
+}
+
+// targetOf returns the target of service, defaulting to its local address.
+func (f clientFlags) targetOf(service string) string {
+	if f.target != "" {
+		return f.target
+	}
+	return defaultTargets[service][f.transport]
+}
+
+// invoker makes one call of a method.
+type invoker func(ctx context.Context) (interface{}, error)
+
+// newInvoker returns an invoker calling method with args on a client built as
+// described by f and guarded by rc.
+func (f clientFlags) newInvoker(method string, args []string, rc resilience.Config, tracer stdopentracing.Tracer, zipkinTracer *stdzipkin.Tracer, logger log.Logger) (invoker, error) {
+	m, ok := methods[method]
+	if !ok {
+		return nil, fmt.Errorf("unknown method %q, want a method of addsvc or foo", method)
+	}
+	if len(args) != len(m.args) {
+		return nil, fmt.Errorf("%s takes %d arguments, got %d", method, len(m.args), len(args))
+	}
+	target := f.targetOf(m.service)
+
+	var err error
+	switch m.service {
+	case "addsvc":
+		var svc addsvcservice.AddsvcService
+		switch f.transport {
+		case config.TransportGRPC:
+			conn, err := grpc.Dial(target, grpc.WithInsecure())
+			if err != nil {
+				return nil, err
+			}
+			svc = addsvctransports.NewGRPCClient(conn, tracer, zipkinTracer, logger, rc)
+		case config.TransportHTTP:
+			if svc, err = addsvctransports.NewHTTPClient(target, tracer, zipkinTracer, logger, rc); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("unknown transport %q, want grpc or http", f.transport)
+		}
+		if method == "concat" {
+			return func(ctx context.Context) (interface{}, error) {
+				return svc.Concat(ctx, args[0], args[1])
+			}, nil
+		}
+		if op, ok := map[string]func(context.Context, float64, float64) (float64, error){
+			"sumfloat":      svc.SumFloat,
+			"subtractfloat": svc.SubtractFloat,
+			"multiplyfloat": svc.MultiplyFloat,
+			"dividefloat":   svc.DivideFloat,
+			"modulofloat":   svc.ModuloFloat,
+		}[method]; ok {
+			a, err := strconv.ParseFloat(args[0], 64)
+			if err != nil {
+				return nil, fmt.Errorf("a: %v", err)
+			}
+			b, err := strconv.ParseFloat(args[1], 64)
+			if err != nil {
+				return nil, fmt.Errorf("b: %v", err)
+			}
+			return func(ctx context.Context) (interface{}, error) {
+				// Float prints NaN and infinities in JSON output too.
+				rs, err := op(ctx, a, b)
+				return endpoints.Float(rs), err
+			}, nil
+		}
+		op := map[string]func(context.Context, int64, int64) (int64, error){
+			"sum":      svc.Sum,
+			"subtract": svc.Subtract,
+			"multiply": svc.Multiply,
+			"divide":   svc.Divide,
+			"modulo":   svc.Modulo,
+		}[method]
+		a, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("a: %v", err)
+		}
+		b, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("b: %v", err)
+		}
+		return func(ctx context.Context) (interface{}, error) {
+			return op(ctx, a, b)
+		}, nil
+	default:
+		var svc interface {
+			Foo(ctx context.Context, s string) (string, error)
+		}
+		switch f.transport {
+		case config.TransportGRPC:
+			conn, err := grpc.Dial(target, grpc.WithInsecure())
+			if err != nil {
+				return nil, err
+			}
+			svc = foosvctransports.NewGRPCClient(conn, tracer, zipkinTracer, logger, rc)
+		case config.TransportHTTP:
+			if svc, err = foosvctransports.NewHTTPClient(target, tracer, zipkinTracer, logger, rc); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("unknown transport %q, want grpc or http", f.transport)
+		}
+		return func(ctx context.Context) (interface{}, error) {
+			return svc.Foo(ctx, args[0])
+		}, nil
+	}
+}
+
+// newClientTracer returns the Zipkin tracer of the client, reporting to url
+// when set. Spans are always recorded so that their trace IDs can be printed.
+func newClientTracer(url string) (*stdzipkin.Tracer, reporter.Reporter, error) {
+	r := reporter.NewNoopReporter()
+	if url != "" {
+		r = zipkinhttp.NewReporter(url)
+	}
+	zEP, _ := stdzipkin.NewEndpoint("client", "")
+	tracer, err := stdzipkin.NewTracer(r, stdzipkin.WithLocalEndpoint(zEP))
+	return tracer, r, err
+}
+
+// callResult is the outcome of a single call, as printed by client.
+type callResult struct {
+	Method  string      `json:"method"`
+	Result  interface{} `json:"result,omitempty"`
+	Error   string      `json:"error,omitempty"`
+	Code    string      `json:"code,omitempty"`
+	TraceID string      `json:"trace_id"`
+	Took    string      `json:"took"`
+}
+
+// client calls a method of addsvc or foosvc and prints its result.
+func client(args []string, logger log.Logger) error {
+	var (
+		fs          = flag.NewFlagSet("client", flag.ContinueOnError)
+		cf          clientFlags
+		output      = fs.String("output", "text", "output format: text or json")
+		repeat      = fs.Int("n", 1, "number of calls")
+		concurrency = fs.Int("c", 1, "number of concurrent calls")
+	)
+	cf.register(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: client [flags] METHOD A B | foo S\n"+
+			"METHOD is sum, concat, subtract, multiply, divide or modulo, or one of sumfloat, subtractfloat, multiplyfloat, dividefloat and modulofloat\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+	if *output != "text" && *output != "json" {
+		return fmt.Errorf("unknown output %q, want text or json", *output)
+	}
+	if *repeat < 1 || *concurrency < 1 {
+		return fmt.Errorf("-n and -c must be at least 1")
+	}
+
+	zipkinTracer, r, err := newClientTracer(cf.zipkinURL)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	cfg, err := config.LoadClient()
+	if err != nil {
+		return err
+	}
+	method := fs.Arg(0)
+	invoke, err := cf.newInvoker(method, fs.Args()[1:], resilienceConfig(cfg.Resilience), initOpentracing(), zipkinTracer, logger)
+	if err != nil {
+		return err
+	}
+
+	var (
+		mtx    sync.Mutex
+		failed int
+		wg     sync.WaitGroup
+		calls  = make(chan struct{})
+		begin  = time.Now()
+	)
+	for i := 0; i < *concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range calls {
+				res := call(method, invoke, cf.timeout, zipkinTracer)
+				mtx.Lock()
+				if res.Error != "" {
+					failed++
+				}
+				printResult(os.Stdout, *output, res)
+				mtx.Unlock()
+			}
+		}()
+	}
+	for i := 0; i < *repeat; i++ {
+		calls <- struct{}{}
+	}
+	close(calls)
+	wg.Wait()
+
+	if *repeat > 1 {
+		fmt.Fprintf(os.Stderr, "calls=%d failed=%d took=%s\n", *repeat, failed, time.Since(begin))
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d calls failed", failed, *repeat)
+	}
+	return nil
+}
+
+// call runs invoke under a new root span, so that every call has its own
+// trace.
+func call(method string, invoke invoker, timeout time.Duration, zipkinTracer *stdzipkin.Tracer) callResult {
+	span, ctx := zipkinTracer.StartSpanFromContext(context.Background(), method)
+	defer span.Finish()
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	begin := time.Now()
+	rs, err := invoke(ctx)
+	res := callResult{
+		Method:  method,
+		TraceID: span.Context().TraceID.String(),
+		Took:    time.Since(begin).String(),
+	}
+	if err != nil {
+		res.Error = err.Error()
+		res.Code = errorCode(err).String()
+	} else {
+		res.Result = rs
+	}
+	return res
+}
+
+// errorCode returns the gRPC code of err, including the context, rate limiter
+// and circuit breaker errors the clients return without a status.
+func errorCode(err error) codes.Code {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return codes.DeadlineExceeded
+	case errors.Is(err, context.Canceled):
+		return codes.Canceled
+	}
+	return status.Code(resilience.Status(err))
+}
+
+func printResult(w io.Writer, output string, res callResult) {
+	if output == "json" {
+		json.NewEncoder(w).Encode(res)
+		return
+	}
+	if res.Error != "" {
+		fmt.Fprintf(w, "error: %s (%s)\ttrace_id=%s took=%s\n", res.Error, res.Code, res.TraceID, res.Took)
+		return
+	}
+	fmt.Fprintf(w, "%v\ttrace_id=%s took=%s\n", res.Result, res.TraceID, res.Took)
+}
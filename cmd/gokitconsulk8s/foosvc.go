@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	kitgrpc "github.com/go-kit/kit/transport/grpc"
+	stdopentracing "github.com/opentracing/opentracing-go"
+	stdzipkin "github.com/openzipkin/zipkin-go"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthgrpc "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+
+	pb "github.com/cage1016/gokitconsulk8s/pb/foosvc"
+	addsvcservice "github.com/cage1016/gokitconsulk8s/pkg/addsvc/service"
+	addsvctransports "github.com/cage1016/gokitconsulk8s/pkg/addsvc/transports"
+	"github.com/cage1016/gokitconsulk8s/pkg/admin"
+	"github.com/cage1016/gokitconsulk8s/pkg/compression"
+	"github.com/cage1016/gokitconsulk8s/pkg/config"
+	"github.com/cage1016/gokitconsulk8s/pkg/debuglog"
+	"github.com/cage1016/gokitconsulk8s/pkg/discovery"
+	"github.com/cage1016/gokitconsulk8s/pkg/fault"
+	"github.com/cage1016/gokitconsulk8s/pkg/foosvc/endpoints"
+	"github.com/cage1016/gokitconsulk8s/pkg/foosvc/service"
+	"github.com/cage1016/gokitconsulk8s/pkg/foosvc/transports"
+)
+
+// startFoosvc starts the HTTP, gRPC and admin servers of foosvc, reporting
+// their failure on errs, and returns its endpoints. local is the addsvc used by
+// the local transport; when nil a private addsvc service is created.
+func startFoosvc(cfg config.Foosvc, local addsvcservice.AddsvcService, errs chan error) (endpoints.Endpoints, error) {
+	logger, levels, err := newServiceLogger(cfg.Service)
+	if err != nil {
+		return endpoints.Endpoints{}, err
+	}
+
+	tracer := initOpentracing()
+	zipkinTracer, err := initZipkin(cfg.ServiceName, cfg.HTTPPort, cfg.ZipkinV2URL, logger)
+	if err != nil {
+		return endpoints.Endpoints{}, err
+	}
+	rc := resilienceConfig(cfg.Resilience)
+
+	addsvc, err := newAddsvcClient(cfg, local, tracer, zipkinTracer, logger)
+	if err != nil {
+		return endpoints.Endpoints{}, fmt.Errorf("addsvc %s transport: %v", cfg.Addsvc.Transport, err)
+	}
+	service := service.New(addsvc, logger)
+	dl := debuglog.NewRegistry(debuglog.Config{
+		Methods:  cfg.DebugLog.Methods,
+		MaxBytes: cfg.DebugLog.MaxBytes,
+		Redact:   cfg.DebugLog.Redact,
+	})
+	fi, err := fault.NewInjector(fault.Config{
+		Enabled: cfg.Fault.Enabled,
+		Headers: cfg.Fault.Headers,
+		Rules:   cfg.Fault.Rules,
+	})
+	if err != nil {
+		return endpoints.Endpoints{}, fmt.Errorf("fault rules: %v", err)
+	}
+	if cfg.Fault.Enabled {
+		level.Warn(logger).Log("fault", "injection enabled", "rules", cfg.Fault.Rules, "headers", cfg.Fault.Headers)
+	}
+	endpoints := endpoints.New(service, logger, tracer, zipkinTracer, rc, dl, fi)
+
+	m := http.NewServeMux()
+	m.Handle("/metrics", promhttp.Handler())
+	m.Handle("/", transports.NewHTTPHandler(endpoints, tracer, zipkinTracer, logger))
+	go startHTTPServer(compressHTTP(m, cfg.Compression), cfg.HTTPPort, logger, errs)
+
+	am := admin.NewHandler(cfg.ServiceName, levels)
+	am.Handle(debuglog.Path, dl)
+	go startHTTPServer(am, cfg.AdminPort, log.With(logger, "server", "admin"), errs)
+
+	hs := health.NewServer()
+	hs.SetServingStatus(cfg.ServiceName, healthgrpc.HealthCheckResponse_SERVING)
+	server := grpc.NewServer(grpc.UnaryInterceptor(kitgrpc.Interceptor))
+	pb.RegisterFoosvcServer(server, transports.MakeGRPCServer(endpoints, tracer, zipkinTracer, logger))
+	healthgrpc.RegisterHealthServer(server, hs)
+	reflection.Register(server)
+	go startGRPCServer(server, cfg.GRPCPort, logger, errs)
+
+	return endpoints, nil
+}
+
+// newAddsvcClient builds the addsvc client selected by cfg.Addsvc.Transport.
+// The gRPC client balances over every instance QS_ADDSVC_URL resolves to, and
+// fails over to HTTP when QS_ADDSVC_HTTP_URL is set as well.
+func newAddsvcClient(cfg config.Foosvc, local addsvcservice.AddsvcService, tracer stdopentracing.Tracer, zipkinTracer *stdzipkin.Tracer, logger log.Logger) (addsvcservice.AddsvcService, error) {
+	rc := resilienceConfig(cfg.Addsvc.Resilience)
+
+	switch cfg.Addsvc.Transport {
+	case config.TransportGRPC:
+		balancer, err := discovery.NewBalancer(cfg.Addsvc.Balancer)
+		if err != nil {
+			return nil, err
+		}
+		instancer, err := discovery.NewInstancer(cfg.Addsvc.URL, logger)
+		if err != nil {
+			return nil, err
+		}
+		if cfg.Addsvc.HealthInterval > 0 {
+			instancer = discovery.NewOutlierInstancer(
+				instancer,
+				discovery.GRPCHealthChecker(cfg.Addsvc.ServiceName),
+				cfg.Addsvc.HealthInterval.Duration(),
+				cfg.Addsvc.EjectionTime.Duration(),
+				log.With(logger, "outlier", "addsvc"),
+			)
+		}
+		svc := addsvctransports.NewLoadBalancedGRPCClient(instancer, balancer, cfg.RetryMax, cfg.RetryTimeout.Duration(), tracer, zipkinTracer, logger, rc, compression.DialOption(cfg.Compression.GRPC))
+		if cfg.Addsvc.HTTPURL == "" {
+			return svc, nil
+		}
+		fallback, err := addsvctransports.NewHTTPClient(cfg.Addsvc.HTTPURL, tracer, zipkinTracer, logger, rc)
+		if err != nil {
+			return nil, err
+		}
+		return addsvctransports.FailoverMiddleware(fallback, log.With(logger, "addsvc", cfg.Addsvc.HTTPURL))(svc), nil
+	case config.TransportHTTP:
+		return addsvctransports.NewHTTPClient(cfg.Addsvc.HTTPURL, tracer, zipkinTracer, logger, rc)
+	case config.TransportLocal:
+		if local != nil {
+			return local, nil
+		}
+		return addsvcservice.New(logger), nil
+	default:
+		return nil, fmt.Errorf("unknown addsvc transport %q", cfg.Addsvc.Transport)
+	}
+}
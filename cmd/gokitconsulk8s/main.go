@@ -0,0 +1,74 @@
+// Command gokitconsulk8s runs the addsvc, foosvc and router services, calls
+// them and benchmarks them.
+//
+// Usage:
+//
+//	gokitconsulk8s serve addsvc|foosvc|router|all [flags]
+//	gokitconsulk8s client [flags] sum A B | concat A B | foo S
+//	gokitconsulk8s bench [flags] sum A B | concat A B | foo S
+//
+// Run a subcommand with -h to list its flags.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+type command struct {
+	name  string
+	usage string
+	run   func(args []string, logger log.Logger) error
+}
+
+var commands = []command{
+	{"serve", "serve addsvc|foosvc|router|all [flags]", serve},
+	{"client", "client [flags] sum A B | concat A B | foo S", client},
+	{"bench", "bench [flags] sum A B | concat A B | foo S", bench},
+}
+
+// stderr is where every logger writes. serve filters it at the log level of
+// each service.
+var stderr = log.NewLogfmtLogger(os.Stderr)
+
+func main() {
+	logger := withContext(level.NewFilter(stderr, level.AllowInfo()))
+
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	for _, c := range commands {
+		if c.name != os.Args[1] {
+			continue
+		}
+		err := c.run(os.Args[2:], logger)
+		if err == flag.ErrHelp {
+			os.Exit(2)
+		}
+		if err != nil {
+			level.Error(logger).Log("command", c.name, "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+	usage()
+	os.Exit(2)
+}
+
+// withContext adds the timestamp and caller of each line to logger.
+func withContext(logger log.Logger) log.Logger {
+	logger = log.With(logger, "ts", log.DefaultTimestampUTC)
+	return log.With(logger, "caller", log.DefaultCaller)
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage of %s:\n", os.Args[0])
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %s %s\n", os.Args[0], c.usage)
+	}
+}
@@ -0,0 +1,369 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	kitgrpc "github.com/go-kit/kit/transport/grpc"
+	"github.com/mwitkow/grpc-proxy/proxy"
+	"github.com/openzipkin/zipkin-go"
+	zipkingrpc "github.com/openzipkin/zipkin-go/middleware/grpc"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/test/bufconn"
+
+	addsvcendpoints "github.com/cage1016/gokitconsulk8s/pkg/addsvc/endpoints"
+	addsvctransports "github.com/cage1016/gokitconsulk8s/pkg/addsvc/transports"
+	"github.com/cage1016/gokitconsulk8s/pkg/admin"
+	"github.com/cage1016/gokitconsulk8s/pkg/compression"
+	"github.com/cage1016/gokitconsulk8s/pkg/config"
+	"github.com/cage1016/gokitconsulk8s/pkg/discovery"
+	"github.com/cage1016/gokitconsulk8s/pkg/fault"
+	foosvcendpoints "github.com/cage1016/gokitconsulk8s/pkg/foosvc/endpoints"
+	foosvctransports "github.com/cage1016/gokitconsulk8s/pkg/foosvc/transports"
+	"github.com/cage1016/gokitconsulk8s/pkg/openapi"
+	"github.com/cage1016/gokitconsulk8s/pkg/requestid"
+	routertransport "github.com/cage1016/gokitconsulk8s/pkg/router/transport"
+)
+
+const grpcRouterReg = `([a-zA-Z]+)/`
+
+const (
+	routerAddsvc = "addsvc"
+	routerFoosvc = "foosvc"
+)
+
+// localServices holds the endpoints of the services running in the same
+// process as the router. Nil services are reached over gRPC.
+type localServices struct {
+	addsvc *addsvcendpoints.Endpoints
+	foosvc *foosvcendpoints.Endpoints
+}
+
+// startRouter starts the HTTP, gRPC and admin servers of the router, reporting
+// their failure on errs. The HTTP routes of local services call their endpoints
+// in-process, the others are forwarded over gRPC.
+func startRouter(cfg config.Router, local localServices, errs chan error) error {
+	logger, levels, err := newServiceLogger(cfg.Service)
+	if err != nil {
+		return err
+	}
+
+	tracer := initOpentracing()
+	zipkinTracer, err := initZipkin(cfg.ServiceName, cfg.HTTPPort, cfg.ZipkinV2URL, logger)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	rc := resilienceConfig(cfg.Resilience)
+
+	hedge := routertransport.HedgeConfig{
+		Percentile: cfg.Hedge.Percentile,
+		MinDelay:   cfg.Hedge.MinDelay.Duration(),
+		MaxDelay:   cfg.Hedge.MaxDelay.Duration(),
+		MaxRatio:   cfg.Hedge.MaxRatio,
+	}
+	retry := routertransport.RetryConfig{Max: cfg.RetryMax, Timeout: cfg.RetryTimeout.Duration()}
+	// compressor compresses the gRPC calls to the services.
+	compressor := compression.DialOption(cfg.Compression.GRPC)
+	routerMap := map[string]string{
+		routerAddsvc: cfg.AddsvcURL,
+		routerFoosvc: cfg.FoosvcURL,
+	}
+
+	// httpUpstreams tells the access log where the HTTP routes go.
+	httpUpstreams := map[string]string{}
+	for k, v := range routerMap {
+		httpUpstreams[k] = v
+	}
+	var (
+		addsvcEndpoints addsvcendpoints.Endpoints
+		foosvcEndpoints foosvcendpoints.Endpoints
+	)
+	if local.addsvc != nil {
+		addsvcEndpoints = *local.addsvc
+		httpUpstreams[routerAddsvc] = "local"
+	} else {
+		addsvcEndpoints = routertransport.MakeAddSvcEndpoints(ctx, cfg.AddsvcURL, tracer, zipkinTracer, logger, rc, hedge, retry, compressor)
+	}
+	if local.foosvc != nil {
+		foosvcEndpoints = *local.foosvc
+		httpUpstreams[routerFoosvc] = "local"
+	} else {
+		foosvcEndpoints = routertransport.MakeFooSvcEndpoints(ctx, cfg.FoosvcURL, tracer, zipkinTracer, logger, rc, hedge, retry, compressor)
+	}
+
+	// Only the calls answered by the stable version are mirrored. Calls to
+	// the shadows are neither hedged nor retried.
+	shadows := routertransport.Shadows{}
+	for service, m := range map[string]config.Mirror{routerAddsvc: cfg.Shadow.Addsvc, routerFoosvc: cfg.Shadow.Foosvc} {
+		if m.URL == "" {
+			continue
+		}
+		s, err := routertransport.NewShadow(service, routertransport.ShadowConfig{
+			Target:      m.URL,
+			Percent:     m.Percent,
+			Timeout:     cfg.Shadow.Timeout.Duration(),
+			MaxInFlight: cfg.Shadow.MaxInFlight,
+		}, zipkinTracer, logger, compressor)
+		if err != nil {
+			return err
+		}
+		shadows[service] = s
+		level.Info(logger).Log("route", service, "shadow", m.URL, "percent", m.Percent)
+	}
+	if s, ok := shadows[routerAddsvc]; ok {
+		shadow := routertransport.MakeAddSvcEndpoints(ctx, s.Target(), tracer, zipkinTracer, log.With(logger, "shadow", s.Target()), rc, routertransport.HedgeConfig{}, routertransport.RetryConfig{}, compressor)
+		addsvcEndpoints.SumEndpoint = s.Middleware("sum", shadow.SumEndpoint)(addsvcEndpoints.SumEndpoint)
+		addsvcEndpoints.ConcatEndpoint = s.Middleware("concat", shadow.ConcatEndpoint)(addsvcEndpoints.ConcatEndpoint)
+		addsvcEndpoints.SubtractEndpoint = s.Middleware("subtract", shadow.SubtractEndpoint)(addsvcEndpoints.SubtractEndpoint)
+		addsvcEndpoints.MultiplyEndpoint = s.Middleware("multiply", shadow.MultiplyEndpoint)(addsvcEndpoints.MultiplyEndpoint)
+		addsvcEndpoints.DivideEndpoint = s.Middleware("divide", shadow.DivideEndpoint)(addsvcEndpoints.DivideEndpoint)
+		addsvcEndpoints.ModuloEndpoint = s.Middleware("modulo", shadow.ModuloEndpoint)(addsvcEndpoints.ModuloEndpoint)
+		addsvcEndpoints.SumFloatEndpoint = s.Middleware("sumfloat", shadow.SumFloatEndpoint)(addsvcEndpoints.SumFloatEndpoint)
+		addsvcEndpoints.SubtractFloatEndpoint = s.Middleware("subtractfloat", shadow.SubtractFloatEndpoint)(addsvcEndpoints.SubtractFloatEndpoint)
+		addsvcEndpoints.MultiplyFloatEndpoint = s.Middleware("multiplyfloat", shadow.MultiplyFloatEndpoint)(addsvcEndpoints.MultiplyFloatEndpoint)
+		addsvcEndpoints.DivideFloatEndpoint = s.Middleware("dividefloat", shadow.DivideFloatEndpoint)(addsvcEndpoints.DivideFloatEndpoint)
+		addsvcEndpoints.ModuloFloatEndpoint = s.Middleware("modulofloat", shadow.ModuloFloatEndpoint)(addsvcEndpoints.ModuloFloatEndpoint)
+	}
+	if s, ok := shadows[routerFoosvc]; ok {
+		shadow := routertransport.MakeFooSvcEndpoints(ctx, s.Target(), tracer, zipkinTracer, log.With(logger, "shadow", s.Target()), rc, routertransport.HedgeConfig{}, routertransport.RetryConfig{}, compressor)
+		foosvcEndpoints.FooEndpoint = s.Middleware("foo", shadow.FooEndpoint)(foosvcEndpoints.FooEndpoint)
+	}
+	var addsvcHandler http.Handler = addsvctransports.NewHTTPHandler(addsvcEndpoints, tracer, zipkinTracer, logger)
+	var foosvcHandler http.Handler = foosvctransports.NewHTTPHandler(foosvcEndpoints, tracer, zipkinTracer, logger)
+
+	// The versions besides the stable one are always reached over gRPC, the
+	// stable version being local or not.
+	splits := routertransport.Splits{}
+	if len(cfg.Split.Addsvc) > 0 {
+		s := routertransport.NewSplitter(cfg.AddsvcURL, splitConfig(cfg.Split, cfg.Split.Addsvc))
+		handlers := map[string]http.Handler{routertransport.StableVersion: addsvcHandler}
+		for _, v := range cfg.Split.Addsvc {
+			handlers[v.Name] = routertransport.MakeAddSvcHandler(ctx, v.URL, tracer, zipkinTracer, log.With(logger, "version", v.Name), rc, hedge, retry, compressor)
+		}
+		addsvcHandler = s.Handler(handlers)
+		splits[routerAddsvc] = s
+	}
+	if len(cfg.Split.Foosvc) > 0 {
+		s := routertransport.NewSplitter(cfg.FoosvcURL, splitConfig(cfg.Split, cfg.Split.Foosvc))
+		handlers := map[string]http.Handler{routertransport.StableVersion: foosvcHandler}
+		for _, v := range cfg.Split.Foosvc {
+			handlers[v.Name] = routertransport.MakeFooSvcHandler(ctx, v.URL, tracer, zipkinTracer, log.With(logger, "version", v.Name), rc, hedge, retry, compressor)
+		}
+		foosvcHandler = s.Handler(handlers)
+		splits[routerFoosvc] = s
+	}
+	accessLog := routertransport.NewAccessLogger(routertransport.AccessLogConfig{
+		SampleRate:     cfg.AccessLog.SampleRate,
+		Headers:        cfg.AccessLog.Headers,
+		Redact:         cfg.AccessLog.Redact,
+		TrustedProxies: cfg.AccessLog.TrustedProxies,
+	}, zipkinTracer, logger)
+
+	hb := routertransport.NewHandlerBuilder()
+	hb.Router.Handle("/metrics", promhttp.Handler())
+	hb.Router.Handle(openapi.Path, openapi.Merge(cfg.ServiceName, "v1",
+		addsvctransports.OpenAPI().WithPrefix("/"+routerAddsvc),
+		foosvctransports.OpenAPI().WithPrefix("/"+routerFoosvc),
+	))
+	hb.AddHandler(routerAddsvc, addsvcHandler)
+	hb.AddHandler(routerFoosvc, foosvcHandler)
+
+	var routeConfigs []routertransport.RouteConfig
+	for _, r := range cfg.Routes {
+		routeConfigs = append(routeConfigs, routertransport.RouteConfig{
+			Name:     r.Name,
+			Method:   r.Method,
+			Metadata: r.Metadata,
+			Callers:  r.Callers,
+			Upstream: r.Upstream,
+		})
+	}
+	routes, err := routertransport.NewRoutes(routeConfigs)
+	if err != nil {
+		return err
+	}
+	grpcProxy := newGRPCProxy(zipkinTracer, compressor, routerMap, routes, splits, shadows, accessLog, cfg.FaultHeaders)
+
+	cors := routertransport.CORSConfig{
+		AllowedOrigins:   cfg.CORS.AllowedOrigins,
+		AllowedMethods:   cfg.CORS.AllowedMethods,
+		AllowedHeaders:   cfg.CORS.AllowedHeaders,
+		ExposedHeaders:   cfg.CORS.ExposedHeaders,
+		AllowCredentials: cfg.CORS.AllowCredentials,
+		MaxAge:           cfg.CORS.MaxAge.Duration(),
+	}
+	// gRPC-Web calls are served by the gRPC proxy, which gives them their
+	// request ID and writes them to the access log.
+	var httpHandler http.Handler = requestid.Handler(accessLog.Handler(routertransport.CORS(compressHTTP(hb.Router, cfg.Compression), cors), httpUpstreams))
+	if cfg.GRPCWeb {
+		httpHandler = routertransport.GRPCWeb(grpcProxy, httpHandler, cors)
+	}
+	if cfg.WebSocket.Enabled {
+		gateway, err := newWebSocketGateway(grpcProxy, cfg.WebSocket, cors, zipkinTracer, logger, errs)
+		if err != nil {
+			return err
+		}
+		mux := http.NewServeMux()
+		mux.Handle(routertransport.WebSocketPath, gateway)
+		mux.Handle("/", httpHandler)
+		httpHandler = mux
+	}
+	if !cfg.FaultHeaders {
+		httpHandler = fault.StripHTTP(httpHandler)
+	}
+	go startHTTPServer(httpHandler, cfg.HTTPPort, logger, errs)
+	go startGRPCServer(grpcProxy, cfg.GRPCPort, log.With(logger, "GRPC", "proxy"), errs)
+
+	am := admin.NewHandler(cfg.ServiceName, levels)
+	am.Handle(routertransport.SplitPath, splits)
+	go startHTTPServer(am, cfg.AdminPort, log.With(logger, "server", "admin"), errs)
+	return nil
+}
+
+// newWebSocketGateway returns a WebSocket gateway calling grpcProxy, which
+// serves it in-process, so that the calls of browsers are handled like any
+// other.
+func newWebSocketGateway(grpcProxy *grpc.Server, cfg config.WebSocket, cors routertransport.CORSConfig, zipkinTracer *zipkin.Tracer, logger log.Logger, errs chan error) (*routertransport.WebSocketGateway, error) {
+	lis := bufconn.Listen(1 << 20)
+	go func() {
+		errs <- grpcProxy.Serve(lis)
+	}()
+	conn, err := grpc.Dial(
+		"bufconn",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return lis.Dial()
+		}),
+		grpc.WithInsecure(),
+		grpc.WithStatsHandler(zipkingrpc.NewClientHandler(zipkinTracer)),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return routertransport.NewWebSocketGateway(conn, []string{"addsvc.proto", "foosvc.proto"}, routertransport.WebSocketConfig{
+		MaxCalls:        cfg.MaxCalls,
+		MaxMessageBytes: int64(cfg.MaxMessageBytes),
+	}, cors, zipkinTracer, logger)
+}
+
+func splitConfig(cfg config.Split, versions config.Versions) routertransport.SplitConfig {
+	sc := routertransport.SplitConfig{Header: cfg.Header, HashHeader: cfg.HashHeader}
+	for _, v := range versions {
+		sc.Versions = append(sc.Versions, routertransport.Version{Name: v.Name, Target: v.URL, Weight: v.Weight})
+	}
+	return sc
+}
+
+// newGRPCProxy returns a gRPC server forwarding every call to the service
+// named by the first element of its method, with its request ID, and writing
+// it to accessLog. Calls go to the upstream of the first of routes they match,
+// or else to the version picked by the Splitter of the service in splits, or
+// else to the service's target in routerMap. Targets are resolved as
+// described by discovery.NewInstancer, and calls to a target share a
+// connection dialed with compressor. The calls to the target in routerMap are
+// mirrored to the service's shadow in shadows. Unless faultHeaders is set, the
+// x-fault metadata of the calls is dropped.
+func newGRPCProxy(zipkinTracer *zipkin.Tracer, compressor grpc.DialOption, routerMap map[string]string, routes routertransport.Routes, splits routertransport.Splits, shadows routertransport.Shadows, accessLog *routertransport.AccessLogger, faultHeaders bool) *grpc.Server {
+	re := regexp.MustCompile(grpcRouterReg)
+	conns := discovery.NewConns(
+		grpc.WithInsecure(),
+		grpc.WithStatsHandler(zipkingrpc.NewClientHandler(zipkinTracer)),
+		grpc.WithDefaultCallOptions(grpc.CallCustomCodec(proxy.Codec()), grpc.FailFast(false)),
+		compressor,
+	)
+	director := func(ctx context.Context, fullMethodName string) (context.Context, *grpc.ClientConn, error) {
+		serviceName := func(fullMethodName string) string {
+			x := re.FindSubmatch([]byte(fullMethodName))
+			return strings.ToLower(string(x[1]))
+		}(fullMethodName)
+
+		// Make sure we never forward internal services.
+		if _, ok := routerMap[serviceName]; !ok {
+			return nil, nil, grpc.Errorf(codes.Unimplemented, "Unknown method")
+		}
+
+		md, ok := metadata.FromIncomingContext(ctx)
+		// Copy the inbound metadata explicitly, the request ID included.
+		outCtx := metadata.NewOutgoingContext(ctx, md.Copy())
+
+		if ok {
+			target := routerMap[serviceName]
+			if r, matched := routes.Match(fullMethodName, md, callerIP(ctx)); matched {
+				target = r.Upstream
+			} else if s, split := splits[serviceName]; split {
+				target = s.Pick(func(key string) string {
+					if vs := md.Get(key); len(vs) > 0 {
+						return vs[0]
+					}
+					return ""
+				}).Target
+			}
+			routertransport.SetUpstream(ctx, target)
+			conn, err := conns.Get(target)
+			return outCtx, conn, err
+		}
+		return nil, nil, grpc.Errorf(codes.Unimplemented, "Unknown method")
+	}
+
+	var interceptors []grpc.StreamServerInterceptor
+	if !faultHeaders {
+		// Ahead of the shadow, which mirrors the incoming metadata too.
+		interceptors = append(interceptors, fault.StripStreamServerInterceptor)
+	}
+	interceptors = append(interceptors,
+		requestid.StreamServerInterceptor,
+		accessLog.StreamInterceptor(routerMap),
+		shadows.StreamInterceptor(routerMap),
+	)
+	server := grpc.NewServer(
+		grpc.CustomCodec(proxy.Codec()),
+		grpc.UnknownServiceHandler(proxy.TransparentHandler(director)),
+		grpc.UnaryInterceptor(kitgrpc.Interceptor),
+		grpc.StreamInterceptor(chainStreamInterceptors(interceptors...)),
+		grpc.StatsHandler(zipkingrpc.NewServerHandler(zipkinTracer)),
+	)
+	reflection.Register(server)
+	return server
+}
+
+// callerIP returns the IP address of the peer of ctx, nil when unknown.
+// The peers of gRPC-Web calls only have the address of their HTTP request.
+func callerIP(ctx context.Context) net.IP {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return nil
+	}
+	if addr, ok := p.Addr.(*net.TCPAddr); ok {
+		return addr.IP
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return nil
+	}
+	return net.ParseIP(host)
+}
+
+// chainStreamInterceptors returns the interceptor running interceptors in
+// order, the first one outermost.
+func chainStreamInterceptors(interceptors ...grpc.StreamServerInterceptor) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		for i := len(interceptors) - 1; i >= 0; i-- {
+			interceptor, next := interceptors[i], handler
+			handler = func(srv interface{}, ss grpc.ServerStream) error {
+				return interceptor(srv, ss, info, next)
+			}
+		}
+		return handler(srv, ss)
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+
+	"github.com/cage1016/gokitconsulk8s/pkg/config"
+)
+
+// serve runs the service named by args[0] until it fails or is interrupted.
+// "all" runs addsvc, foosvc and the router together, foosvc and the router's
+// HTTP routes calling the other services in-process.
+func serve(args []string, logger log.Logger) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing service, want addsvc, foosvc, router or all")
+	}
+
+	errs := make(chan error, 10)
+	switch args[0] {
+	case "addsvc":
+		cfg, err := config.LoadAddsvc(args[1:])
+		if err != nil {
+			return err
+		}
+		if cfg.PrintConfig {
+			return config.Print(os.Stdout, cfg)
+		}
+		if _, err := startAddsvc(cfg, errs); err != nil {
+			return err
+		}
+	case "foosvc":
+		cfg, err := config.LoadFoosvc(args[1:])
+		if err != nil {
+			return err
+		}
+		if cfg.PrintConfig {
+			return config.Print(os.Stdout, cfg)
+		}
+		if _, err := startFoosvc(cfg, nil, errs); err != nil {
+			return err
+		}
+	case "router":
+		cfg, err := config.LoadRouter(args[1:])
+		if err != nil {
+			return err
+		}
+		if cfg.PrintConfig {
+			return config.Print(os.Stdout, cfg)
+		}
+		if err := startRouter(cfg, localServices{}, errs); err != nil {
+			return err
+		}
+	case "all":
+		cfg, err := config.LoadAll(args[1:])
+		if err != nil {
+			return err
+		}
+		if cfg.PrintConfig {
+			return config.Print(os.Stdout, cfg)
+		}
+		addsvc, err := startAddsvc(cfg.Addsvc, errs)
+		if err != nil {
+			return err
+		}
+		foosvc, err := startFoosvc(cfg.Foosvc, addsvc, errs)
+		if err != nil {
+			return err
+		}
+		if err := startRouter(cfg.Router, localServices{addsvc: &addsvc, foosvc: &foosvc}, errs); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown service %q, want addsvc, foosvc, router or all", args[0])
+	}
+
+	go func() {
+		c := make(chan os.Signal, 1)
+		signal.Notify(c, syscall.SIGINT, syscall.SIGTERM)
+		errs <- fmt.Errorf("%s", <-c)
+	}()
+
+	level.Info(logger).Log("serve", args[0], "terminated", <-errs)
+	return nil
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	stdopentracing "github.com/opentracing/opentracing-go"
+	"github.com/openzipkin/zipkin-go"
+	zipkinhttp "github.com/openzipkin/zipkin-go/reporter/http"
+	"google.golang.org/grpc"
+
+	"github.com/cage1016/gokitconsulk8s/pkg/admin"
+	"github.com/cage1016/gokitconsulk8s/pkg/compression"
+	"github.com/cage1016/gokitconsulk8s/pkg/config"
+	"github.com/cage1016/gokitconsulk8s/pkg/resilience"
+)
+
+// newServiceLogger returns the logger of the service configured by cfg, and
+// the filter of its log level, which the admin server changes at runtime.
+func newServiceLogger(cfg config.Service) (log.Logger, *admin.LevelFilter, error) {
+	levels, err := admin.NewLevelFilter(stderr, cfg.LogLevel)
+	if err != nil {
+		return nil, nil, err
+	}
+	return log.With(withContext(levels), "service", cfg.ServiceName), levels, nil
+}
+
+// compressHTTP returns handler compressing its responses as configured by cfg.
+func compressHTTP(handler http.Handler, cfg config.Compression) http.Handler {
+	return compression.Handler(handler, compression.Config{
+		Encodings: cfg.HTTP,
+		MinSize:   cfg.MinSize,
+	})
+}
+
+// resilienceConfig returns the resilience of every method as configured by
+// cfg.
+func resilienceConfig(cfg config.Resilience) resilience.Config {
+	rc := resilience.Config{Default: resilienceSettings(cfg.Default)}
+	if len(cfg.Methods) > 0 {
+		rc.Methods = make(map[string]resilience.Settings, len(cfg.Methods))
+		for method, s := range cfg.Methods {
+			rc.Methods[method] = resilienceSettings(s)
+		}
+	}
+	return rc
+}
+
+func resilienceSettings(s config.ResilienceSettings) resilience.Settings {
+	return resilience.Settings{
+		RateLimit:      s.RateLimit,
+		RateBurst:      s.RateBurst,
+		MaxConcurrency: s.MaxConcurrency,
+		MaxQueue:       s.MaxQueue,
+		QueueTimeout:   s.QueueTimeout.Duration(),
+		Breaker: resilience.BreakerSettings{
+			MaxRequests:         uint32(s.Breaker.MaxRequests),
+			Interval:            s.Breaker.Interval.Duration(),
+			Timeout:             s.Breaker.Timeout.Duration(),
+			ConsecutiveFailures: uint32(s.Breaker.ConsecutiveFailures),
+			FailureRatio:        s.Breaker.FailureRatio,
+			MinRequests:         uint32(s.Breaker.MinRequests),
+		},
+	}
+}
+
+func initOpentracing() (tracer stdopentracing.Tracer) {
+	return stdopentracing.GlobalTracer()
+}
+
+func initZipkin(serviceName, httpPort, zipkinV2URL string, logger log.Logger) (zipkinTracer *zipkin.Tracer, err error) {
+	var (
+		hostPort      = fmt.Sprintf("localhost:%s", httpPort)
+		useNoopTracer = (zipkinV2URL == "")
+		reporter      = zipkinhttp.NewReporter(zipkinV2URL)
+	)
+	zEP, _ := zipkin.NewEndpoint(serviceName, hostPort)
+	zipkinTracer, err = zipkin.NewTracer(reporter, zipkin.WithLocalEndpoint(zEP), zipkin.WithNoopTracer(useNoopTracer))
+	if err != nil {
+		return nil, err
+	}
+	if !useNoopTracer {
+		logger.Log("tracer", "Zipkin", "type", "Native", "URL", zipkinV2URL)
+	}
+
+	return
+}
+
+// startHTTPServer serves handler on port until it fails. An empty port
+// disables the server.
+func startHTTPServer(handler http.Handler, port string, logger log.Logger, errs chan error) {
+	if port == "" {
+		return
+	}
+	p := fmt.Sprintf(":%s", port)
+	level.Info(logger).Log("protocol", "HTTP", "exposed", port)
+	errs <- http.ListenAndServe(p, handler)
+}
+
+// startGRPCServer serves server on port until it fails. An empty port
+// disables the server.
+func startGRPCServer(server *grpc.Server, port string, logger log.Logger, errs chan error) {
+	if port == "" {
+		return
+	}
+	p := fmt.Sprintf(":%s", port)
+	listener, err := net.Listen("tcp", p)
+	if err != nil {
+		errs <- fmt.Errorf("gRPC listen on %s: %v", port, err)
+		return
+	}
+
+	level.Info(logger).Log("protocol", "GRPC", "exposed", port)
+	errs <- server.Serve(listener)
+}
@@ -210,30 +210,909 @@ func (m *ConcatReply) GetErr() string {
 	return ""
 }
 
+type SubtractRequest struct {
+	A                    int64    `protobuf:"varint,1,opt,name=a,proto3" json:"a,omitempty"`
+	B                    int64    `protobuf:"varint,2,opt,name=b,proto3" json:"b,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SubtractRequest) Reset()         { *m = SubtractRequest{} }
+func (m *SubtractRequest) String() string { return proto.CompactTextString(m) }
+func (*SubtractRequest) ProtoMessage()    {}
+func (*SubtractRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_174367f558d60c26, []int{4}
+}
+
+func (m *SubtractRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SubtractRequest.Unmarshal(m, b)
+}
+func (m *SubtractRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SubtractRequest.Marshal(b, m, deterministic)
+}
+func (m *SubtractRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SubtractRequest.Merge(m, src)
+}
+func (m *SubtractRequest) XXX_Size() int {
+	return xxx_messageInfo_SubtractRequest.Size(m)
+}
+func (m *SubtractRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_SubtractRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SubtractRequest proto.InternalMessageInfo
+
+func (m *SubtractRequest) GetA() int64 {
+	if m != nil {
+		return m.A
+	}
+	return 0
+}
+
+func (m *SubtractRequest) GetB() int64 {
+	if m != nil {
+		return m.B
+	}
+	return 0
+}
+
+type SubtractReply struct {
+	Rs                   int64    `protobuf:"varint,1,opt,name=rs,proto3" json:"rs,omitempty"`
+	Err                  string   `protobuf:"bytes,2,opt,name=err,proto3" json:"err,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SubtractReply) Reset()         { *m = SubtractReply{} }
+func (m *SubtractReply) String() string { return proto.CompactTextString(m) }
+func (*SubtractReply) ProtoMessage()    {}
+func (*SubtractReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_174367f558d60c26, []int{5}
+}
+
+func (m *SubtractReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SubtractReply.Unmarshal(m, b)
+}
+func (m *SubtractReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SubtractReply.Marshal(b, m, deterministic)
+}
+func (m *SubtractReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SubtractReply.Merge(m, src)
+}
+func (m *SubtractReply) XXX_Size() int {
+	return xxx_messageInfo_SubtractReply.Size(m)
+}
+func (m *SubtractReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_SubtractReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SubtractReply proto.InternalMessageInfo
+
+func (m *SubtractReply) GetRs() int64 {
+	if m != nil {
+		return m.Rs
+	}
+	return 0
+}
+
+func (m *SubtractReply) GetErr() string {
+	if m != nil {
+		return m.Err
+	}
+	return ""
+}
+
+type MultiplyRequest struct {
+	A                    int64    `protobuf:"varint,1,opt,name=a,proto3" json:"a,omitempty"`
+	B                    int64    `protobuf:"varint,2,opt,name=b,proto3" json:"b,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *MultiplyRequest) Reset()         { *m = MultiplyRequest{} }
+func (m *MultiplyRequest) String() string { return proto.CompactTextString(m) }
+func (*MultiplyRequest) ProtoMessage()    {}
+func (*MultiplyRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_174367f558d60c26, []int{6}
+}
+
+func (m *MultiplyRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_MultiplyRequest.Unmarshal(m, b)
+}
+func (m *MultiplyRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_MultiplyRequest.Marshal(b, m, deterministic)
+}
+func (m *MultiplyRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_MultiplyRequest.Merge(m, src)
+}
+func (m *MultiplyRequest) XXX_Size() int {
+	return xxx_messageInfo_MultiplyRequest.Size(m)
+}
+func (m *MultiplyRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_MultiplyRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_MultiplyRequest proto.InternalMessageInfo
+
+func (m *MultiplyRequest) GetA() int64 {
+	if m != nil {
+		return m.A
+	}
+	return 0
+}
+
+func (m *MultiplyRequest) GetB() int64 {
+	if m != nil {
+		return m.B
+	}
+	return 0
+}
+
+type MultiplyReply struct {
+	Rs                   int64    `protobuf:"varint,1,opt,name=rs,proto3" json:"rs,omitempty"`
+	Err                  string   `protobuf:"bytes,2,opt,name=err,proto3" json:"err,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *MultiplyReply) Reset()         { *m = MultiplyReply{} }
+func (m *MultiplyReply) String() string { return proto.CompactTextString(m) }
+func (*MultiplyReply) ProtoMessage()    {}
+func (*MultiplyReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_174367f558d60c26, []int{7}
+}
+
+func (m *MultiplyReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_MultiplyReply.Unmarshal(m, b)
+}
+func (m *MultiplyReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_MultiplyReply.Marshal(b, m, deterministic)
+}
+func (m *MultiplyReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_MultiplyReply.Merge(m, src)
+}
+func (m *MultiplyReply) XXX_Size() int {
+	return xxx_messageInfo_MultiplyReply.Size(m)
+}
+func (m *MultiplyReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_MultiplyReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_MultiplyReply proto.InternalMessageInfo
+
+func (m *MultiplyReply) GetRs() int64 {
+	if m != nil {
+		return m.Rs
+	}
+	return 0
+}
+
+func (m *MultiplyReply) GetErr() string {
+	if m != nil {
+		return m.Err
+	}
+	return ""
+}
+
+type DivideRequest struct {
+	A                    int64    `protobuf:"varint,1,opt,name=a,proto3" json:"a,omitempty"`
+	B                    int64    `protobuf:"varint,2,opt,name=b,proto3" json:"b,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DivideRequest) Reset()         { *m = DivideRequest{} }
+func (m *DivideRequest) String() string { return proto.CompactTextString(m) }
+func (*DivideRequest) ProtoMessage()    {}
+func (*DivideRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_174367f558d60c26, []int{8}
+}
+
+func (m *DivideRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DivideRequest.Unmarshal(m, b)
+}
+func (m *DivideRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DivideRequest.Marshal(b, m, deterministic)
+}
+func (m *DivideRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DivideRequest.Merge(m, src)
+}
+func (m *DivideRequest) XXX_Size() int {
+	return xxx_messageInfo_DivideRequest.Size(m)
+}
+func (m *DivideRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_DivideRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DivideRequest proto.InternalMessageInfo
+
+func (m *DivideRequest) GetA() int64 {
+	if m != nil {
+		return m.A
+	}
+	return 0
+}
+
+func (m *DivideRequest) GetB() int64 {
+	if m != nil {
+		return m.B
+	}
+	return 0
+}
+
+type DivideReply struct {
+	Rs                   int64    `protobuf:"varint,1,opt,name=rs,proto3" json:"rs,omitempty"`
+	Err                  string   `protobuf:"bytes,2,opt,name=err,proto3" json:"err,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DivideReply) Reset()         { *m = DivideReply{} }
+func (m *DivideReply) String() string { return proto.CompactTextString(m) }
+func (*DivideReply) ProtoMessage()    {}
+func (*DivideReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_174367f558d60c26, []int{9}
+}
+
+func (m *DivideReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DivideReply.Unmarshal(m, b)
+}
+func (m *DivideReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DivideReply.Marshal(b, m, deterministic)
+}
+func (m *DivideReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DivideReply.Merge(m, src)
+}
+func (m *DivideReply) XXX_Size() int {
+	return xxx_messageInfo_DivideReply.Size(m)
+}
+func (m *DivideReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_DivideReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DivideReply proto.InternalMessageInfo
+
+func (m *DivideReply) GetRs() int64 {
+	if m != nil {
+		return m.Rs
+	}
+	return 0
+}
+
+func (m *DivideReply) GetErr() string {
+	if m != nil {
+		return m.Err
+	}
+	return ""
+}
+
+type ModuloRequest struct {
+	A                    int64    `protobuf:"varint,1,opt,name=a,proto3" json:"a,omitempty"`
+	B                    int64    `protobuf:"varint,2,opt,name=b,proto3" json:"b,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ModuloRequest) Reset()         { *m = ModuloRequest{} }
+func (m *ModuloRequest) String() string { return proto.CompactTextString(m) }
+func (*ModuloRequest) ProtoMessage()    {}
+func (*ModuloRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_174367f558d60c26, []int{10}
+}
+
+func (m *ModuloRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ModuloRequest.Unmarshal(m, b)
+}
+func (m *ModuloRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ModuloRequest.Marshal(b, m, deterministic)
+}
+func (m *ModuloRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ModuloRequest.Merge(m, src)
+}
+func (m *ModuloRequest) XXX_Size() int {
+	return xxx_messageInfo_ModuloRequest.Size(m)
+}
+func (m *ModuloRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ModuloRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ModuloRequest proto.InternalMessageInfo
+
+func (m *ModuloRequest) GetA() int64 {
+	if m != nil {
+		return m.A
+	}
+	return 0
+}
+
+func (m *ModuloRequest) GetB() int64 {
+	if m != nil {
+		return m.B
+	}
+	return 0
+}
+
+type ModuloReply struct {
+	Rs                   int64    `protobuf:"varint,1,opt,name=rs,proto3" json:"rs,omitempty"`
+	Err                  string   `protobuf:"bytes,2,opt,name=err,proto3" json:"err,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ModuloReply) Reset()         { *m = ModuloReply{} }
+func (m *ModuloReply) String() string { return proto.CompactTextString(m) }
+func (*ModuloReply) ProtoMessage()    {}
+func (*ModuloReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_174367f558d60c26, []int{11}
+}
+
+func (m *ModuloReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ModuloReply.Unmarshal(m, b)
+}
+func (m *ModuloReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ModuloReply.Marshal(b, m, deterministic)
+}
+func (m *ModuloReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ModuloReply.Merge(m, src)
+}
+func (m *ModuloReply) XXX_Size() int {
+	return xxx_messageInfo_ModuloReply.Size(m)
+}
+func (m *ModuloReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_ModuloReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ModuloReply proto.InternalMessageInfo
+
+func (m *ModuloReply) GetRs() int64 {
+	if m != nil {
+		return m.Rs
+	}
+	return 0
+}
+
+func (m *ModuloReply) GetErr() string {
+	if m != nil {
+		return m.Err
+	}
+	return ""
+}
+
+type SumFloatRequest struct {
+	A                    float64  `protobuf:"fixed64,1,opt,name=a,proto3" json:"a,omitempty"`
+	B                    float64  `protobuf:"fixed64,2,opt,name=b,proto3" json:"b,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SumFloatRequest) Reset()         { *m = SumFloatRequest{} }
+func (m *SumFloatRequest) String() string { return proto.CompactTextString(m) }
+func (*SumFloatRequest) ProtoMessage()    {}
+func (*SumFloatRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_174367f558d60c26, []int{12}
+}
+
+func (m *SumFloatRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SumFloatRequest.Unmarshal(m, b)
+}
+func (m *SumFloatRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SumFloatRequest.Marshal(b, m, deterministic)
+}
+func (m *SumFloatRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SumFloatRequest.Merge(m, src)
+}
+func (m *SumFloatRequest) XXX_Size() int {
+	return xxx_messageInfo_SumFloatRequest.Size(m)
+}
+func (m *SumFloatRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_SumFloatRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SumFloatRequest proto.InternalMessageInfo
+
+func (m *SumFloatRequest) GetA() float64 {
+	if m != nil {
+		return m.A
+	}
+	return 0
+}
+
+func (m *SumFloatRequest) GetB() float64 {
+	if m != nil {
+		return m.B
+	}
+	return 0
+}
+
+type SumFloatReply struct {
+	Rs                   float64  `protobuf:"fixed64,1,opt,name=rs,proto3" json:"rs,omitempty"`
+	Err                  string   `protobuf:"bytes,2,opt,name=err,proto3" json:"err,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SumFloatReply) Reset()         { *m = SumFloatReply{} }
+func (m *SumFloatReply) String() string { return proto.CompactTextString(m) }
+func (*SumFloatReply) ProtoMessage()    {}
+func (*SumFloatReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_174367f558d60c26, []int{13}
+}
+
+func (m *SumFloatReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SumFloatReply.Unmarshal(m, b)
+}
+func (m *SumFloatReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SumFloatReply.Marshal(b, m, deterministic)
+}
+func (m *SumFloatReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SumFloatReply.Merge(m, src)
+}
+func (m *SumFloatReply) XXX_Size() int {
+	return xxx_messageInfo_SumFloatReply.Size(m)
+}
+func (m *SumFloatReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_SumFloatReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SumFloatReply proto.InternalMessageInfo
+
+func (m *SumFloatReply) GetRs() float64 {
+	if m != nil {
+		return m.Rs
+	}
+	return 0
+}
+
+func (m *SumFloatReply) GetErr() string {
+	if m != nil {
+		return m.Err
+	}
+	return ""
+}
+
+type SubtractFloatRequest struct {
+	A                    float64  `protobuf:"fixed64,1,opt,name=a,proto3" json:"a,omitempty"`
+	B                    float64  `protobuf:"fixed64,2,opt,name=b,proto3" json:"b,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SubtractFloatRequest) Reset()         { *m = SubtractFloatRequest{} }
+func (m *SubtractFloatRequest) String() string { return proto.CompactTextString(m) }
+func (*SubtractFloatRequest) ProtoMessage()    {}
+func (*SubtractFloatRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_174367f558d60c26, []int{14}
+}
+
+func (m *SubtractFloatRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SubtractFloatRequest.Unmarshal(m, b)
+}
+func (m *SubtractFloatRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SubtractFloatRequest.Marshal(b, m, deterministic)
+}
+func (m *SubtractFloatRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SubtractFloatRequest.Merge(m, src)
+}
+func (m *SubtractFloatRequest) XXX_Size() int {
+	return xxx_messageInfo_SubtractFloatRequest.Size(m)
+}
+func (m *SubtractFloatRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_SubtractFloatRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SubtractFloatRequest proto.InternalMessageInfo
+
+func (m *SubtractFloatRequest) GetA() float64 {
+	if m != nil {
+		return m.A
+	}
+	return 0
+}
+
+func (m *SubtractFloatRequest) GetB() float64 {
+	if m != nil {
+		return m.B
+	}
+	return 0
+}
+
+type SubtractFloatReply struct {
+	Rs                   float64  `protobuf:"fixed64,1,opt,name=rs,proto3" json:"rs,omitempty"`
+	Err                  string   `protobuf:"bytes,2,opt,name=err,proto3" json:"err,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SubtractFloatReply) Reset()         { *m = SubtractFloatReply{} }
+func (m *SubtractFloatReply) String() string { return proto.CompactTextString(m) }
+func (*SubtractFloatReply) ProtoMessage()    {}
+func (*SubtractFloatReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_174367f558d60c26, []int{15}
+}
+
+func (m *SubtractFloatReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SubtractFloatReply.Unmarshal(m, b)
+}
+func (m *SubtractFloatReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SubtractFloatReply.Marshal(b, m, deterministic)
+}
+func (m *SubtractFloatReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SubtractFloatReply.Merge(m, src)
+}
+func (m *SubtractFloatReply) XXX_Size() int {
+	return xxx_messageInfo_SubtractFloatReply.Size(m)
+}
+func (m *SubtractFloatReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_SubtractFloatReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SubtractFloatReply proto.InternalMessageInfo
+
+func (m *SubtractFloatReply) GetRs() float64 {
+	if m != nil {
+		return m.Rs
+	}
+	return 0
+}
+
+func (m *SubtractFloatReply) GetErr() string {
+	if m != nil {
+		return m.Err
+	}
+	return ""
+}
+
+type MultiplyFloatRequest struct {
+	A                    float64  `protobuf:"fixed64,1,opt,name=a,proto3" json:"a,omitempty"`
+	B                    float64  `protobuf:"fixed64,2,opt,name=b,proto3" json:"b,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *MultiplyFloatRequest) Reset()         { *m = MultiplyFloatRequest{} }
+func (m *MultiplyFloatRequest) String() string { return proto.CompactTextString(m) }
+func (*MultiplyFloatRequest) ProtoMessage()    {}
+func (*MultiplyFloatRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_174367f558d60c26, []int{16}
+}
+
+func (m *MultiplyFloatRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_MultiplyFloatRequest.Unmarshal(m, b)
+}
+func (m *MultiplyFloatRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_MultiplyFloatRequest.Marshal(b, m, deterministic)
+}
+func (m *MultiplyFloatRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_MultiplyFloatRequest.Merge(m, src)
+}
+func (m *MultiplyFloatRequest) XXX_Size() int {
+	return xxx_messageInfo_MultiplyFloatRequest.Size(m)
+}
+func (m *MultiplyFloatRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_MultiplyFloatRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_MultiplyFloatRequest proto.InternalMessageInfo
+
+func (m *MultiplyFloatRequest) GetA() float64 {
+	if m != nil {
+		return m.A
+	}
+	return 0
+}
+
+func (m *MultiplyFloatRequest) GetB() float64 {
+	if m != nil {
+		return m.B
+	}
+	return 0
+}
+
+type MultiplyFloatReply struct {
+	Rs                   float64  `protobuf:"fixed64,1,opt,name=rs,proto3" json:"rs,omitempty"`
+	Err                  string   `protobuf:"bytes,2,opt,name=err,proto3" json:"err,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *MultiplyFloatReply) Reset()         { *m = MultiplyFloatReply{} }
+func (m *MultiplyFloatReply) String() string { return proto.CompactTextString(m) }
+func (*MultiplyFloatReply) ProtoMessage()    {}
+func (*MultiplyFloatReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_174367f558d60c26, []int{17}
+}
+
+func (m *MultiplyFloatReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_MultiplyFloatReply.Unmarshal(m, b)
+}
+func (m *MultiplyFloatReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_MultiplyFloatReply.Marshal(b, m, deterministic)
+}
+func (m *MultiplyFloatReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_MultiplyFloatReply.Merge(m, src)
+}
+func (m *MultiplyFloatReply) XXX_Size() int {
+	return xxx_messageInfo_MultiplyFloatReply.Size(m)
+}
+func (m *MultiplyFloatReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_MultiplyFloatReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_MultiplyFloatReply proto.InternalMessageInfo
+
+func (m *MultiplyFloatReply) GetRs() float64 {
+	if m != nil {
+		return m.Rs
+	}
+	return 0
+}
+
+func (m *MultiplyFloatReply) GetErr() string {
+	if m != nil {
+		return m.Err
+	}
+	return ""
+}
+
+type DivideFloatRequest struct {
+	A                    float64  `protobuf:"fixed64,1,opt,name=a,proto3" json:"a,omitempty"`
+	B                    float64  `protobuf:"fixed64,2,opt,name=b,proto3" json:"b,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DivideFloatRequest) Reset()         { *m = DivideFloatRequest{} }
+func (m *DivideFloatRequest) String() string { return proto.CompactTextString(m) }
+func (*DivideFloatRequest) ProtoMessage()    {}
+func (*DivideFloatRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_174367f558d60c26, []int{18}
+}
+
+func (m *DivideFloatRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DivideFloatRequest.Unmarshal(m, b)
+}
+func (m *DivideFloatRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DivideFloatRequest.Marshal(b, m, deterministic)
+}
+func (m *DivideFloatRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DivideFloatRequest.Merge(m, src)
+}
+func (m *DivideFloatRequest) XXX_Size() int {
+	return xxx_messageInfo_DivideFloatRequest.Size(m)
+}
+func (m *DivideFloatRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_DivideFloatRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DivideFloatRequest proto.InternalMessageInfo
+
+func (m *DivideFloatRequest) GetA() float64 {
+	if m != nil {
+		return m.A
+	}
+	return 0
+}
+
+func (m *DivideFloatRequest) GetB() float64 {
+	if m != nil {
+		return m.B
+	}
+	return 0
+}
+
+type DivideFloatReply struct {
+	Rs                   float64  `protobuf:"fixed64,1,opt,name=rs,proto3" json:"rs,omitempty"`
+	Err                  string   `protobuf:"bytes,2,opt,name=err,proto3" json:"err,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DivideFloatReply) Reset()         { *m = DivideFloatReply{} }
+func (m *DivideFloatReply) String() string { return proto.CompactTextString(m) }
+func (*DivideFloatReply) ProtoMessage()    {}
+func (*DivideFloatReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_174367f558d60c26, []int{19}
+}
+
+func (m *DivideFloatReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DivideFloatReply.Unmarshal(m, b)
+}
+func (m *DivideFloatReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DivideFloatReply.Marshal(b, m, deterministic)
+}
+func (m *DivideFloatReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DivideFloatReply.Merge(m, src)
+}
+func (m *DivideFloatReply) XXX_Size() int {
+	return xxx_messageInfo_DivideFloatReply.Size(m)
+}
+func (m *DivideFloatReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_DivideFloatReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DivideFloatReply proto.InternalMessageInfo
+
+func (m *DivideFloatReply) GetRs() float64 {
+	if m != nil {
+		return m.Rs
+	}
+	return 0
+}
+
+func (m *DivideFloatReply) GetErr() string {
+	if m != nil {
+		return m.Err
+	}
+	return ""
+}
+
+type ModuloFloatRequest struct {
+	A                    float64  `protobuf:"fixed64,1,opt,name=a,proto3" json:"a,omitempty"`
+	B                    float64  `protobuf:"fixed64,2,opt,name=b,proto3" json:"b,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ModuloFloatRequest) Reset()         { *m = ModuloFloatRequest{} }
+func (m *ModuloFloatRequest) String() string { return proto.CompactTextString(m) }
+func (*ModuloFloatRequest) ProtoMessage()    {}
+func (*ModuloFloatRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_174367f558d60c26, []int{20}
+}
+
+func (m *ModuloFloatRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ModuloFloatRequest.Unmarshal(m, b)
+}
+func (m *ModuloFloatRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ModuloFloatRequest.Marshal(b, m, deterministic)
+}
+func (m *ModuloFloatRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ModuloFloatRequest.Merge(m, src)
+}
+func (m *ModuloFloatRequest) XXX_Size() int {
+	return xxx_messageInfo_ModuloFloatRequest.Size(m)
+}
+func (m *ModuloFloatRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ModuloFloatRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ModuloFloatRequest proto.InternalMessageInfo
+
+func (m *ModuloFloatRequest) GetA() float64 {
+	if m != nil {
+		return m.A
+	}
+	return 0
+}
+
+func (m *ModuloFloatRequest) GetB() float64 {
+	if m != nil {
+		return m.B
+	}
+	return 0
+}
+
+type ModuloFloatReply struct {
+	Rs                   float64  `protobuf:"fixed64,1,opt,name=rs,proto3" json:"rs,omitempty"`
+	Err                  string   `protobuf:"bytes,2,opt,name=err,proto3" json:"err,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ModuloFloatReply) Reset()         { *m = ModuloFloatReply{} }
+func (m *ModuloFloatReply) String() string { return proto.CompactTextString(m) }
+func (*ModuloFloatReply) ProtoMessage()    {}
+func (*ModuloFloatReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_174367f558d60c26, []int{21}
+}
+
+func (m *ModuloFloatReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ModuloFloatReply.Unmarshal(m, b)
+}
+func (m *ModuloFloatReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ModuloFloatReply.Marshal(b, m, deterministic)
+}
+func (m *ModuloFloatReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ModuloFloatReply.Merge(m, src)
+}
+func (m *ModuloFloatReply) XXX_Size() int {
+	return xxx_messageInfo_ModuloFloatReply.Size(m)
+}
+func (m *ModuloFloatReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_ModuloFloatReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ModuloFloatReply proto.InternalMessageInfo
+
+func (m *ModuloFloatReply) GetRs() float64 {
+	if m != nil {
+		return m.Rs
+	}
+	return 0
+}
+
+func (m *ModuloFloatReply) GetErr() string {
+	if m != nil {
+		return m.Err
+	}
+	return ""
+}
+
 func init() {
 	proto.RegisterType((*SumRequest)(nil), "pb.SumRequest")
 	proto.RegisterType((*SumReply)(nil), "pb.SumReply")
 	proto.RegisterType((*ConcatRequest)(nil), "pb.ConcatRequest")
 	proto.RegisterType((*ConcatReply)(nil), "pb.ConcatReply")
+	proto.RegisterType((*SubtractRequest)(nil), "pb.SubtractRequest")
+	proto.RegisterType((*SubtractReply)(nil), "pb.SubtractReply")
+	proto.RegisterType((*MultiplyRequest)(nil), "pb.MultiplyRequest")
+	proto.RegisterType((*MultiplyReply)(nil), "pb.MultiplyReply")
+	proto.RegisterType((*DivideRequest)(nil), "pb.DivideRequest")
+	proto.RegisterType((*DivideReply)(nil), "pb.DivideReply")
+	proto.RegisterType((*ModuloRequest)(nil), "pb.ModuloRequest")
+	proto.RegisterType((*ModuloReply)(nil), "pb.ModuloReply")
+	proto.RegisterType((*SumFloatRequest)(nil), "pb.SumFloatRequest")
+	proto.RegisterType((*SumFloatReply)(nil), "pb.SumFloatReply")
+	proto.RegisterType((*SubtractFloatRequest)(nil), "pb.SubtractFloatRequest")
+	proto.RegisterType((*SubtractFloatReply)(nil), "pb.SubtractFloatReply")
+	proto.RegisterType((*MultiplyFloatRequest)(nil), "pb.MultiplyFloatRequest")
+	proto.RegisterType((*MultiplyFloatReply)(nil), "pb.MultiplyFloatReply")
+	proto.RegisterType((*DivideFloatRequest)(nil), "pb.DivideFloatRequest")
+	proto.RegisterType((*DivideFloatReply)(nil), "pb.DivideFloatReply")
+	proto.RegisterType((*ModuloFloatRequest)(nil), "pb.ModuloFloatRequest")
+	proto.RegisterType((*ModuloFloatReply)(nil), "pb.ModuloFloatReply")
 }
 
 func init() { proto.RegisterFile("addsvc.proto", fileDescriptor_174367f558d60c26) }
 
 var fileDescriptor_174367f558d60c26 = []byte{
-	// 195 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xe2, 0xe2, 0x49, 0x4c, 0x49, 0x29,
-	0x2e, 0x4b, 0xd6, 0x2b, 0x28, 0xca, 0x2f, 0xc9, 0x17, 0x62, 0x2a, 0x48, 0x52, 0xd2, 0xe0, 0xe2,
-	0x0a, 0x2e, 0xcd, 0x0d, 0x4a, 0x2d, 0x2c, 0x4d, 0x2d, 0x2e, 0x11, 0xe2, 0xe1, 0x62, 0x4c, 0x94,
-	0x60, 0x54, 0x60, 0xd4, 0x60, 0x0e, 0x62, 0x4c, 0x04, 0xf1, 0x92, 0x24, 0x98, 0x20, 0xbc, 0x24,
-	0x25, 0x1d, 0x2e, 0x0e, 0xb0, 0xca, 0x82, 0x9c, 0x4a, 0x21, 0x3e, 0x2e, 0xa6, 0xa2, 0x62, 0xa8,
-	0x42, 0xa6, 0xa2, 0x62, 0x21, 0x01, 0x2e, 0xe6, 0xd4, 0xa2, 0x22, 0xb0, 0x5a, 0xce, 0x20, 0x10,
-	0x53, 0x49, 0x9b, 0x8b, 0xd7, 0x39, 0x3f, 0x2f, 0x39, 0xb1, 0x04, 0xc3, 0x68, 0x4e, 0x14, 0xa3,
-	0x39, 0x41, 0x46, 0xeb, 0x73, 0x71, 0xc3, 0x14, 0xa3, 0x9a, 0xce, 0x89, 0xdd, 0x74, 0xa3, 0x78,
-	0x2e, 0x36, 0x47, 0xb0, 0x4f, 0x84, 0x54, 0xb9, 0x98, 0x83, 0x4b, 0x73, 0x85, 0xf8, 0xf4, 0x0a,
-	0x92, 0xf4, 0x10, 0x1e, 0x91, 0xe2, 0x81, 0xf3, 0x0b, 0x72, 0x2a, 0x95, 0x18, 0x84, 0xf4, 0xb8,
-	0xd8, 0x20, 0x36, 0x08, 0x09, 0x82, 0x64, 0x50, 0x9c, 0x26, 0xc5, 0x8f, 0x2c, 0x04, 0x56, 0x9f,
-	0xc4, 0x06, 0x0e, 0x21, 0x63, 0x40, 0x00, 0x00, 0x00, 0xff, 0xff, 0x2a, 0xc3, 0x1f, 0x88, 0x31,
-	0x01, 0x00, 0x00,
+	// 438 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x55, 0x4f, 0x6f, 0xaa, 0x40,
+	0x10, 0x7f, 0xe0, 0x8b, 0x91, 0xf1, 0x0f, 0xb2, 0xcf, 0x18, 0xb3, 0xa7, 0x17, 0x92, 0x26, 0x26,
+	0xad, 0xd8, 0x5a, 0xd3, 0x5b, 0x0f, 0x8d, 0x4d, 0x6f, 0x5e, 0xe4, 0x13, 0x80, 0x70, 0x30, 0xc1,
+	0x42, 0xf9, 0x63, 0xe2, 0x47, 0xee, 0xb7, 0x68, 0x60, 0xba, 0xc0, 0x08, 0x26, 0xeb, 0x8d, 0x9d,
+	0xfd, 0xfd, 0xd9, 0x9d, 0xd9, 0x19, 0x60, 0xe0, 0x78, 0x5e, 0x72, 0xda, 0x5b, 0x51, 0x1c, 0xa6,
+	0x21, 0x53, 0x23, 0xd7, 0x9c, 0x03, 0xd8, 0xd9, 0x71, 0xe7, 0x7f, 0x65, 0x7e, 0x92, 0xb2, 0x01,
+	0x28, 0xce, 0x4c, 0xf9, 0xaf, 0xcc, 0x3b, 0x3b, 0xc5, 0xc9, 0x57, 0xee, 0x4c, 0xc5, 0x95, 0x6b,
+	0x3e, 0x40, 0xaf, 0x40, 0x46, 0xc1, 0x99, 0x8d, 0x40, 0x8d, 0x93, 0x5f, 0xa0, 0x1a, 0x27, 0x6c,
+	0x0c, 0x1d, 0x3f, 0x8e, 0x0b, 0xac, 0xb6, 0xcb, 0x3f, 0xcd, 0x7b, 0x18, 0x6e, 0xc2, 0xcf, 0xbd,
+	0x93, 0x36, 0xa4, 0x35, 0x22, 0xad, 0xe5, 0xd2, 0x4b, 0xe8, 0x0b, 0x30, 0x55, 0xd7, 0xae, 0xa8,
+	0x2f, 0x40, 0xb7, 0x33, 0x37, 0x8d, 0x9d, 0x7d, 0x2a, 0x73, 0xf4, 0x27, 0x18, 0x56, 0x70, 0xb9,
+	0xf3, 0x2f, 0x40, 0xdf, 0x66, 0x41, 0x7a, 0x88, 0x82, 0xb3, 0xa4, 0x43, 0x05, 0x97, 0xce, 0xd0,
+	0xfb, 0xe1, 0x74, 0xf0, 0x7c, 0x19, 0xfd, 0x25, 0xf4, 0x05, 0x58, 0x5a, 0x7d, 0x1b, 0x7a, 0x59,
+	0x10, 0x4a, 0xaa, 0x0b, 0xb0, 0x74, 0x76, 0xec, 0xec, 0xf8, 0x11, 0x84, 0x2d, 0xf5, 0x55, 0x88,
+	0xbe, 0x52, 0xe6, 0x5f, 0xc0, 0xa9, 0x83, 0x72, 0xc5, 0x61, 0x05, 0x13, 0x51, 0x32, 0x69, 0x9b,
+	0x17, 0x60, 0x17, 0x1c, 0x69, 0x2f, 0x51, 0xbc, 0x5b, 0xbc, 0x2e, 0x38, 0x72, 0x5e, 0x8f, 0xc0,
+	0xb0, 0x90, 0xd2, 0x4e, 0x6b, 0x18, 0x13, 0x86, 0xb4, 0x0f, 0x96, 0xf4, 0x16, 0x1f, 0xc2, 0x90,
+	0xf2, 0x59, 0x7d, 0xff, 0x85, 0xee, 0x5b, 0x31, 0x54, 0xd8, 0x1d, 0x74, 0xec, 0xec, 0xc8, 0x46,
+	0x56, 0xe4, 0x5a, 0xd5, 0x4c, 0xe1, 0x83, 0x72, 0x1d, 0x05, 0x67, 0xf3, 0x0f, 0xb3, 0xa0, 0x8b,
+	0xcd, 0xce, 0x8c, 0x7c, 0x87, 0x4c, 0x09, 0xae, 0xd7, 0x43, 0x88, 0x5f, 0x43, 0x4f, 0x54, 0x95,
+	0xfd, 0x43, 0x2d, 0xd2, 0xf9, 0xdc, 0xa0, 0xc1, 0x92, 0x25, 0xea, 0x83, 0xac, 0x8b, 0x6e, 0xe6,
+	0x06, 0x0d, 0x96, 0x67, 0xc3, 0x5c, 0xe3, 0xd9, 0x48, 0x7f, 0x72, 0xbd, 0x1e, 0x2a, 0xf1, 0x98,
+	0x33, 0xc4, 0x93, 0x8e, 0xe3, 0x7a, 0x3d, 0x54, 0xbb, 0x0b, 0x36, 0x82, 0xb8, 0x0b, 0xe9, 0x22,
+	0x6e, 0xd0, 0x20, 0xb2, 0x36, 0xd5, 0xf8, 0x42, 0xea, 0xac, 0x7e, 0x63, 0xc2, 0x9f, 0xb6, 0xec,
+	0x94, 0x22, 0xe4, 0xc1, 0xa2, 0x48, 0xdb, 0xbb, 0xe7, 0xd3, 0x96, 0x1d, 0x14, 0x79, 0x15, 0x63,
+	0x08, 0x25, 0xa6, 0x55, 0x46, 0x88, 0xc0, 0xa4, 0x11, 0x2f, 0xe9, 0xb5, 0x27, 0x86, 0xf4, 0xe6,
+	0x2b, 0xe5, 0x93, 0x46, 0xbc, 0xa0, 0xbb, 0xdd, 0xe2, 0xb7, 0xf5, 0xfc, 0x33, 0x00, 0x03, 0x3c,
+	0x1e, 0xc6, 0xc6, 0x06, 0x00, 0x00,
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -250,6 +1129,15 @@ const _ = grpc.SupportPackageIsVersion4
 type AddsvcClient interface {
 	Sum(ctx context.Context, in *SumRequest, opts ...grpc.CallOption) (*SumReply, error)
 	Concat(ctx context.Context, in *ConcatRequest, opts ...grpc.CallOption) (*ConcatReply, error)
+	Subtract(ctx context.Context, in *SubtractRequest, opts ...grpc.CallOption) (*SubtractReply, error)
+	Multiply(ctx context.Context, in *MultiplyRequest, opts ...grpc.CallOption) (*MultiplyReply, error)
+	Divide(ctx context.Context, in *DivideRequest, opts ...grpc.CallOption) (*DivideReply, error)
+	Modulo(ctx context.Context, in *ModuloRequest, opts ...grpc.CallOption) (*ModuloReply, error)
+	SumFloat(ctx context.Context, in *SumFloatRequest, opts ...grpc.CallOption) (*SumFloatReply, error)
+	SubtractFloat(ctx context.Context, in *SubtractFloatRequest, opts ...grpc.CallOption) (*SubtractFloatReply, error)
+	MultiplyFloat(ctx context.Context, in *MultiplyFloatRequest, opts ...grpc.CallOption) (*MultiplyFloatReply, error)
+	DivideFloat(ctx context.Context, in *DivideFloatRequest, opts ...grpc.CallOption) (*DivideFloatReply, error)
+	ModuloFloat(ctx context.Context, in *ModuloFloatRequest, opts ...grpc.CallOption) (*ModuloFloatReply, error)
 }
 
 type addsvcClient struct {
@@ -278,10 +1166,100 @@ func (c *addsvcClient) Concat(ctx context.Context, in *ConcatRequest, opts ...gr
 	return out, nil
 }
 
+func (c *addsvcClient) Subtract(ctx context.Context, in *SubtractRequest, opts ...grpc.CallOption) (*SubtractReply, error) {
+	out := new(SubtractReply)
+	err := c.cc.Invoke(ctx, "/pb.Addsvc/Subtract", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *addsvcClient) Multiply(ctx context.Context, in *MultiplyRequest, opts ...grpc.CallOption) (*MultiplyReply, error) {
+	out := new(MultiplyReply)
+	err := c.cc.Invoke(ctx, "/pb.Addsvc/Multiply", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *addsvcClient) Divide(ctx context.Context, in *DivideRequest, opts ...grpc.CallOption) (*DivideReply, error) {
+	out := new(DivideReply)
+	err := c.cc.Invoke(ctx, "/pb.Addsvc/Divide", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *addsvcClient) Modulo(ctx context.Context, in *ModuloRequest, opts ...grpc.CallOption) (*ModuloReply, error) {
+	out := new(ModuloReply)
+	err := c.cc.Invoke(ctx, "/pb.Addsvc/Modulo", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *addsvcClient) SumFloat(ctx context.Context, in *SumFloatRequest, opts ...grpc.CallOption) (*SumFloatReply, error) {
+	out := new(SumFloatReply)
+	err := c.cc.Invoke(ctx, "/pb.Addsvc/SumFloat", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *addsvcClient) SubtractFloat(ctx context.Context, in *SubtractFloatRequest, opts ...grpc.CallOption) (*SubtractFloatReply, error) {
+	out := new(SubtractFloatReply)
+	err := c.cc.Invoke(ctx, "/pb.Addsvc/SubtractFloat", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *addsvcClient) MultiplyFloat(ctx context.Context, in *MultiplyFloatRequest, opts ...grpc.CallOption) (*MultiplyFloatReply, error) {
+	out := new(MultiplyFloatReply)
+	err := c.cc.Invoke(ctx, "/pb.Addsvc/MultiplyFloat", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *addsvcClient) DivideFloat(ctx context.Context, in *DivideFloatRequest, opts ...grpc.CallOption) (*DivideFloatReply, error) {
+	out := new(DivideFloatReply)
+	err := c.cc.Invoke(ctx, "/pb.Addsvc/DivideFloat", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *addsvcClient) ModuloFloat(ctx context.Context, in *ModuloFloatRequest, opts ...grpc.CallOption) (*ModuloFloatReply, error) {
+	out := new(ModuloFloatReply)
+	err := c.cc.Invoke(ctx, "/pb.Addsvc/ModuloFloat", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // AddsvcServer is the server API for Addsvc service.
 type AddsvcServer interface {
 	Sum(context.Context, *SumRequest) (*SumReply, error)
 	Concat(context.Context, *ConcatRequest) (*ConcatReply, error)
+	Subtract(context.Context, *SubtractRequest) (*SubtractReply, error)
+	Multiply(context.Context, *MultiplyRequest) (*MultiplyReply, error)
+	Divide(context.Context, *DivideRequest) (*DivideReply, error)
+	Modulo(context.Context, *ModuloRequest) (*ModuloReply, error)
+	SumFloat(context.Context, *SumFloatRequest) (*SumFloatReply, error)
+	SubtractFloat(context.Context, *SubtractFloatRequest) (*SubtractFloatReply, error)
+	MultiplyFloat(context.Context, *MultiplyFloatRequest) (*MultiplyFloatReply, error)
+	DivideFloat(context.Context, *DivideFloatRequest) (*DivideFloatReply, error)
+	ModuloFloat(context.Context, *ModuloFloatRequest) (*ModuloFloatReply, error)
 }
 
 func RegisterAddsvcServer(s *grpc.Server, srv AddsvcServer) {
@@ -324,6 +1302,168 @@ func _Addsvc_Concat_Handler(srv interface{}, ctx context.Context, dec func(inter
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Addsvc_Subtract_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SubtractRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AddsvcServer).Subtract(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.Addsvc/Subtract",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AddsvcServer).Subtract(ctx, req.(*SubtractRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Addsvc_Multiply_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MultiplyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AddsvcServer).Multiply(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.Addsvc/Multiply",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AddsvcServer).Multiply(ctx, req.(*MultiplyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Addsvc_Divide_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DivideRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AddsvcServer).Divide(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.Addsvc/Divide",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AddsvcServer).Divide(ctx, req.(*DivideRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Addsvc_Modulo_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ModuloRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AddsvcServer).Modulo(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.Addsvc/Modulo",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AddsvcServer).Modulo(ctx, req.(*ModuloRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Addsvc_SumFloat_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SumFloatRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AddsvcServer).SumFloat(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.Addsvc/SumFloat",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AddsvcServer).SumFloat(ctx, req.(*SumFloatRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Addsvc_SubtractFloat_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SubtractFloatRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AddsvcServer).SubtractFloat(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.Addsvc/SubtractFloat",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AddsvcServer).SubtractFloat(ctx, req.(*SubtractFloatRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Addsvc_MultiplyFloat_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MultiplyFloatRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AddsvcServer).MultiplyFloat(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.Addsvc/MultiplyFloat",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AddsvcServer).MultiplyFloat(ctx, req.(*MultiplyFloatRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Addsvc_DivideFloat_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DivideFloatRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AddsvcServer).DivideFloat(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.Addsvc/DivideFloat",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AddsvcServer).DivideFloat(ctx, req.(*DivideFloatRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Addsvc_ModuloFloat_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ModuloFloatRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AddsvcServer).ModuloFloat(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.Addsvc/ModuloFloat",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AddsvcServer).ModuloFloat(ctx, req.(*ModuloFloatRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _Addsvc_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "pb.Addsvc",
 	HandlerType: (*AddsvcServer)(nil),
@@ -336,6 +1476,42 @@ var _Addsvc_serviceDesc = grpc.ServiceDesc{
 			MethodName: "Concat",
 			Handler:    _Addsvc_Concat_Handler,
 		},
+		{
+			MethodName: "Subtract",
+			Handler:    _Addsvc_Subtract_Handler,
+		},
+		{
+			MethodName: "Multiply",
+			Handler:    _Addsvc_Multiply_Handler,
+		},
+		{
+			MethodName: "Divide",
+			Handler:    _Addsvc_Divide_Handler,
+		},
+		{
+			MethodName: "Modulo",
+			Handler:    _Addsvc_Modulo_Handler,
+		},
+		{
+			MethodName: "SumFloat",
+			Handler:    _Addsvc_SumFloat_Handler,
+		},
+		{
+			MethodName: "SubtractFloat",
+			Handler:    _Addsvc_SubtractFloat_Handler,
+		},
+		{
+			MethodName: "MultiplyFloat",
+			Handler:    _Addsvc_MultiplyFloat_Handler,
+		},
+		{
+			MethodName: "DivideFloat",
+			Handler:    _Addsvc_DivideFloat_Handler,
+		},
+		{
+			MethodName: "ModuloFloat",
+			Handler:    _Addsvc_ModuloFloat_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "addsvc.proto",
@@ -2,41 +2,54 @@ package endpoints
 
 import (
 	"context"
-	"time"
 
-	"github.com/go-kit/kit/circuitbreaker"
 	"github.com/go-kit/kit/endpoint"
 	"github.com/go-kit/kit/log"
-	"github.com/go-kit/kit/ratelimit"
 	"github.com/go-kit/kit/tracing/opentracing"
 	"github.com/go-kit/kit/tracing/zipkin"
 	stdopentracing "github.com/opentracing/opentracing-go"
 	stdzipkin "github.com/openzipkin/zipkin-go"
-	"github.com/sony/gobreaker"
-	"golang.org/x/time/rate"
 
 	"github.com/cage1016/gokitconsulk8s/pkg/addsvc/service"
+	"github.com/cage1016/gokitconsulk8s/pkg/debuglog"
+	"github.com/cage1016/gokitconsulk8s/pkg/fault"
+	"github.com/cage1016/gokitconsulk8s/pkg/resilience"
 )
 
 // Endpoints collects all of the endpoints that compose the addsvc service. It's
 // meant to be used as a helper struct, to collect all of the endpoints into a
 // single parameter.
 type Endpoints struct {
-	SumEndpoint    endpoint.Endpoint `json:""`
-	ConcatEndpoint endpoint.Endpoint `json:""`
+	SumEndpoint           endpoint.Endpoint `json:""`
+	ConcatEndpoint        endpoint.Endpoint `json:""`
+	SubtractEndpoint      endpoint.Endpoint `json:""`
+	MultiplyEndpoint      endpoint.Endpoint `json:""`
+	DivideEndpoint        endpoint.Endpoint `json:""`
+	ModuloEndpoint        endpoint.Endpoint `json:""`
+	SumFloatEndpoint      endpoint.Endpoint `json:""`
+	SubtractFloatEndpoint endpoint.Endpoint `json:""`
+	MultiplyFloatEndpoint endpoint.Endpoint `json:""`
+	DivideFloatEndpoint   endpoint.Endpoint `json:""`
+	ModuloFloatEndpoint   endpoint.Endpoint `json:""`
 }
 
 // New return a new instance of the endpoint that wraps the provided service.
-func New(svc service.AddsvcService, logger log.Logger, otTracer stdopentracing.Tracer, zipkinTracer *stdzipkin.Tracer) (ep Endpoints) {
+// The rate limiter, circuit breaker and bulkhead of each method come from rc,
+// dl switches the logging of their payloads and fi injects faults into them.
+// Faults are injected outside the rate limiter, bulkhead and circuit breaker:
+// injected errors do not open the breaker of the method, only those of its
+// callers, whose handling of them is what fault injection tests.
+func New(svc service.AddsvcService, logger log.Logger, otTracer stdopentracing.Tracer, zipkinTracer *stdzipkin.Tracer, rc resilience.Config, dl *debuglog.Registry, fi *fault.Injector) (ep Endpoints) {
 	var sumEndpoint endpoint.Endpoint
 	{
 		method := "sum"
 		sumEndpoint = MakeSumEndpoint(svc)
-		sumEndpoint = ratelimit.NewErroringLimiter(rate.NewLimiter(rate.Every(time.Second), 100))(sumEndpoint)
-		sumEndpoint = circuitbreaker.Gobreaker(gobreaker.NewCircuitBreaker(gobreaker.Settings{}))(sumEndpoint)
+		sumEndpoint = rc.For(method).Middleware("addsvc."+method, logger)(sumEndpoint)
+		sumEndpoint = fi.Middleware(method, logger)(sumEndpoint)
 		sumEndpoint = opentracing.TraceServer(otTracer, method)(sumEndpoint)
 		sumEndpoint = zipkin.TraceEndpoint(zipkinTracer, method)(sumEndpoint)
 		sumEndpoint = LoggingMiddleware(log.With(logger, "method", method))(sumEndpoint)
+		sumEndpoint = dl.Middleware(method, logger)(sumEndpoint)
 		ep.SumEndpoint = sumEndpoint
 	}
 
@@ -44,14 +57,132 @@ func New(svc service.AddsvcService, logger log.Logger, otTracer stdopentracing.T
 	{
 		method := "concat"
 		concatEndpoint = MakeConcatEndpoint(svc)
-		concatEndpoint = ratelimit.NewErroringLimiter(rate.NewLimiter(rate.Every(time.Second), 100))(concatEndpoint)
-		concatEndpoint = circuitbreaker.Gobreaker(gobreaker.NewCircuitBreaker(gobreaker.Settings{}))(concatEndpoint)
+		concatEndpoint = rc.For(method).Middleware("addsvc."+method, logger)(concatEndpoint)
+		concatEndpoint = fi.Middleware(method, logger)(concatEndpoint)
 		concatEndpoint = opentracing.TraceServer(otTracer, method)(concatEndpoint)
 		concatEndpoint = zipkin.TraceEndpoint(zipkinTracer, method)(concatEndpoint)
 		concatEndpoint = LoggingMiddleware(log.With(logger, "method", method))(concatEndpoint)
+		concatEndpoint = dl.Middleware(method, logger)(concatEndpoint)
 		ep.ConcatEndpoint = concatEndpoint
 	}
 
+	var subtractEndpoint endpoint.Endpoint
+	{
+		method := "subtract"
+		subtractEndpoint = MakeSubtractEndpoint(svc)
+		subtractEndpoint = rc.For(method).Middleware("addsvc."+method, logger)(subtractEndpoint)
+		subtractEndpoint = fi.Middleware(method, logger)(subtractEndpoint)
+		subtractEndpoint = opentracing.TraceServer(otTracer, method)(subtractEndpoint)
+		subtractEndpoint = zipkin.TraceEndpoint(zipkinTracer, method)(subtractEndpoint)
+		subtractEndpoint = LoggingMiddleware(log.With(logger, "method", method))(subtractEndpoint)
+		subtractEndpoint = dl.Middleware(method, logger)(subtractEndpoint)
+		ep.SubtractEndpoint = subtractEndpoint
+	}
+
+	var multiplyEndpoint endpoint.Endpoint
+	{
+		method := "multiply"
+		multiplyEndpoint = MakeMultiplyEndpoint(svc)
+		multiplyEndpoint = rc.For(method).Middleware("addsvc."+method, logger)(multiplyEndpoint)
+		multiplyEndpoint = fi.Middleware(method, logger)(multiplyEndpoint)
+		multiplyEndpoint = opentracing.TraceServer(otTracer, method)(multiplyEndpoint)
+		multiplyEndpoint = zipkin.TraceEndpoint(zipkinTracer, method)(multiplyEndpoint)
+		multiplyEndpoint = LoggingMiddleware(log.With(logger, "method", method))(multiplyEndpoint)
+		multiplyEndpoint = dl.Middleware(method, logger)(multiplyEndpoint)
+		ep.MultiplyEndpoint = multiplyEndpoint
+	}
+
+	var divideEndpoint endpoint.Endpoint
+	{
+		method := "divide"
+		divideEndpoint = MakeDivideEndpoint(svc)
+		divideEndpoint = rc.For(method).Middleware("addsvc."+method, logger)(divideEndpoint)
+		divideEndpoint = fi.Middleware(method, logger)(divideEndpoint)
+		divideEndpoint = opentracing.TraceServer(otTracer, method)(divideEndpoint)
+		divideEndpoint = zipkin.TraceEndpoint(zipkinTracer, method)(divideEndpoint)
+		divideEndpoint = LoggingMiddleware(log.With(logger, "method", method))(divideEndpoint)
+		divideEndpoint = dl.Middleware(method, logger)(divideEndpoint)
+		ep.DivideEndpoint = divideEndpoint
+	}
+
+	var moduloEndpoint endpoint.Endpoint
+	{
+		method := "modulo"
+		moduloEndpoint = MakeModuloEndpoint(svc)
+		moduloEndpoint = rc.For(method).Middleware("addsvc."+method, logger)(moduloEndpoint)
+		moduloEndpoint = fi.Middleware(method, logger)(moduloEndpoint)
+		moduloEndpoint = opentracing.TraceServer(otTracer, method)(moduloEndpoint)
+		moduloEndpoint = zipkin.TraceEndpoint(zipkinTracer, method)(moduloEndpoint)
+		moduloEndpoint = LoggingMiddleware(log.With(logger, "method", method))(moduloEndpoint)
+		moduloEndpoint = dl.Middleware(method, logger)(moduloEndpoint)
+		ep.ModuloEndpoint = moduloEndpoint
+	}
+
+	var sumfloatEndpoint endpoint.Endpoint
+	{
+		method := "sumfloat"
+		sumfloatEndpoint = MakeSumFloatEndpoint(svc)
+		sumfloatEndpoint = rc.For(method).Middleware("addsvc."+method, logger)(sumfloatEndpoint)
+		sumfloatEndpoint = fi.Middleware(method, logger)(sumfloatEndpoint)
+		sumfloatEndpoint = opentracing.TraceServer(otTracer, method)(sumfloatEndpoint)
+		sumfloatEndpoint = zipkin.TraceEndpoint(zipkinTracer, method)(sumfloatEndpoint)
+		sumfloatEndpoint = LoggingMiddleware(log.With(logger, "method", method))(sumfloatEndpoint)
+		sumfloatEndpoint = dl.Middleware(method, logger)(sumfloatEndpoint)
+		ep.SumFloatEndpoint = sumfloatEndpoint
+	}
+
+	var subtractfloatEndpoint endpoint.Endpoint
+	{
+		method := "subtractfloat"
+		subtractfloatEndpoint = MakeSubtractFloatEndpoint(svc)
+		subtractfloatEndpoint = rc.For(method).Middleware("addsvc."+method, logger)(subtractfloatEndpoint)
+		subtractfloatEndpoint = fi.Middleware(method, logger)(subtractfloatEndpoint)
+		subtractfloatEndpoint = opentracing.TraceServer(otTracer, method)(subtractfloatEndpoint)
+		subtractfloatEndpoint = zipkin.TraceEndpoint(zipkinTracer, method)(subtractfloatEndpoint)
+		subtractfloatEndpoint = LoggingMiddleware(log.With(logger, "method", method))(subtractfloatEndpoint)
+		subtractfloatEndpoint = dl.Middleware(method, logger)(subtractfloatEndpoint)
+		ep.SubtractFloatEndpoint = subtractfloatEndpoint
+	}
+
+	var multiplyfloatEndpoint endpoint.Endpoint
+	{
+		method := "multiplyfloat"
+		multiplyfloatEndpoint = MakeMultiplyFloatEndpoint(svc)
+		multiplyfloatEndpoint = rc.For(method).Middleware("addsvc."+method, logger)(multiplyfloatEndpoint)
+		multiplyfloatEndpoint = fi.Middleware(method, logger)(multiplyfloatEndpoint)
+		multiplyfloatEndpoint = opentracing.TraceServer(otTracer, method)(multiplyfloatEndpoint)
+		multiplyfloatEndpoint = zipkin.TraceEndpoint(zipkinTracer, method)(multiplyfloatEndpoint)
+		multiplyfloatEndpoint = LoggingMiddleware(log.With(logger, "method", method))(multiplyfloatEndpoint)
+		multiplyfloatEndpoint = dl.Middleware(method, logger)(multiplyfloatEndpoint)
+		ep.MultiplyFloatEndpoint = multiplyfloatEndpoint
+	}
+
+	var dividefloatEndpoint endpoint.Endpoint
+	{
+		method := "dividefloat"
+		dividefloatEndpoint = MakeDivideFloatEndpoint(svc)
+		dividefloatEndpoint = rc.For(method).Middleware("addsvc."+method, logger)(dividefloatEndpoint)
+		dividefloatEndpoint = fi.Middleware(method, logger)(dividefloatEndpoint)
+		dividefloatEndpoint = opentracing.TraceServer(otTracer, method)(dividefloatEndpoint)
+		dividefloatEndpoint = zipkin.TraceEndpoint(zipkinTracer, method)(dividefloatEndpoint)
+		dividefloatEndpoint = LoggingMiddleware(log.With(logger, "method", method))(dividefloatEndpoint)
+		dividefloatEndpoint = dl.Middleware(method, logger)(dividefloatEndpoint)
+		ep.DivideFloatEndpoint = dividefloatEndpoint
+	}
+
+	var modulofloatEndpoint endpoint.Endpoint
+	{
+		method := "modulofloat"
+		modulofloatEndpoint = MakeModuloFloatEndpoint(svc)
+		modulofloatEndpoint = rc.For(method).Middleware("addsvc."+method, logger)(modulofloatEndpoint)
+		modulofloatEndpoint = fi.Middleware(method, logger)(modulofloatEndpoint)
+		modulofloatEndpoint = opentracing.TraceServer(otTracer, method)(modulofloatEndpoint)
+		modulofloatEndpoint = zipkin.TraceEndpoint(zipkinTracer, method)(modulofloatEndpoint)
+		modulofloatEndpoint = LoggingMiddleware(log.With(logger, "method", method))(modulofloatEndpoint)
+		modulofloatEndpoint = dl.Middleware(method, logger)(modulofloatEndpoint)
+		ep.ModuloFloatEndpoint = modulofloatEndpoint
+	}
+
 	return ep
 }
 
@@ -102,3 +233,219 @@ func (e Endpoints) Concat(ctx context.Context, a string, b string) (rs string, e
 	response := resp.(ConcatResponse)
 	return response.Rs, nil
 }
+
+// MakeSubtractEndpoint returns an endpoint that invokes Subtract on the service.
+// Primarily useful in a server.
+func MakeSubtractEndpoint(svc service.AddsvcService) (ep endpoint.Endpoint) {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(SubtractRequest)
+		if err := req.validate(); err != nil {
+			return SubtractResponse{}, err
+		}
+		rs, err := svc.Subtract(ctx, req.A, req.B)
+		return SubtractResponse{Rs: rs}, err
+	}
+}
+
+// Subtract implements the service interface, so Endpoints may be used as a service.
+// This is primarily useful in the context of a client library.
+func (e Endpoints) Subtract(ctx context.Context, a int64, b int64) (rs int64, err error) {
+	resp, err := e.SubtractEndpoint(ctx, SubtractRequest{A: a, B: b})
+	if err != nil {
+		return
+	}
+	response := resp.(SubtractResponse)
+	return response.Rs, nil
+}
+
+// MakeMultiplyEndpoint returns an endpoint that invokes Multiply on the service.
+// Primarily useful in a server.
+func MakeMultiplyEndpoint(svc service.AddsvcService) (ep endpoint.Endpoint) {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(MultiplyRequest)
+		if err := req.validate(); err != nil {
+			return MultiplyResponse{}, err
+		}
+		rs, err := svc.Multiply(ctx, req.A, req.B)
+		return MultiplyResponse{Rs: rs}, err
+	}
+}
+
+// Multiply implements the service interface, so Endpoints may be used as a service.
+// This is primarily useful in the context of a client library.
+func (e Endpoints) Multiply(ctx context.Context, a int64, b int64) (rs int64, err error) {
+	resp, err := e.MultiplyEndpoint(ctx, MultiplyRequest{A: a, B: b})
+	if err != nil {
+		return
+	}
+	response := resp.(MultiplyResponse)
+	return response.Rs, nil
+}
+
+// MakeDivideEndpoint returns an endpoint that invokes Divide on the service.
+// Primarily useful in a server.
+func MakeDivideEndpoint(svc service.AddsvcService) (ep endpoint.Endpoint) {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(DivideRequest)
+		if err := req.validate(); err != nil {
+			return DivideResponse{}, err
+		}
+		rs, err := svc.Divide(ctx, req.A, req.B)
+		return DivideResponse{Rs: rs}, err
+	}
+}
+
+// Divide implements the service interface, so Endpoints may be used as a service.
+// This is primarily useful in the context of a client library.
+func (e Endpoints) Divide(ctx context.Context, a int64, b int64) (rs int64, err error) {
+	resp, err := e.DivideEndpoint(ctx, DivideRequest{A: a, B: b})
+	if err != nil {
+		return
+	}
+	response := resp.(DivideResponse)
+	return response.Rs, nil
+}
+
+// MakeModuloEndpoint returns an endpoint that invokes Modulo on the service.
+// Primarily useful in a server.
+func MakeModuloEndpoint(svc service.AddsvcService) (ep endpoint.Endpoint) {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(ModuloRequest)
+		if err := req.validate(); err != nil {
+			return ModuloResponse{}, err
+		}
+		rs, err := svc.Modulo(ctx, req.A, req.B)
+		return ModuloResponse{Rs: rs}, err
+	}
+}
+
+// Modulo implements the service interface, so Endpoints may be used as a service.
+// This is primarily useful in the context of a client library.
+func (e Endpoints) Modulo(ctx context.Context, a int64, b int64) (rs int64, err error) {
+	resp, err := e.ModuloEndpoint(ctx, ModuloRequest{A: a, B: b})
+	if err != nil {
+		return
+	}
+	response := resp.(ModuloResponse)
+	return response.Rs, nil
+}
+
+// MakeSumFloatEndpoint returns an endpoint that invokes SumFloat on the service.
+// Primarily useful in a server.
+func MakeSumFloatEndpoint(svc service.AddsvcService) (ep endpoint.Endpoint) {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(SumFloatRequest)
+		if err := req.validate(); err != nil {
+			return SumFloatResponse{}, err
+		}
+		rs, err := svc.SumFloat(ctx, float64(req.A), float64(req.B))
+		return SumFloatResponse{Rs: Float(rs)}, err
+	}
+}
+
+// SumFloat implements the service interface, so Endpoints may be used as a service.
+// This is primarily useful in the context of a client library.
+func (e Endpoints) SumFloat(ctx context.Context, a float64, b float64) (rs float64, err error) {
+	resp, err := e.SumFloatEndpoint(ctx, SumFloatRequest{A: Float(a), B: Float(b)})
+	if err != nil {
+		return
+	}
+	response := resp.(SumFloatResponse)
+	return float64(response.Rs), nil
+}
+
+// MakeSubtractFloatEndpoint returns an endpoint that invokes SubtractFloat on the service.
+// Primarily useful in a server.
+func MakeSubtractFloatEndpoint(svc service.AddsvcService) (ep endpoint.Endpoint) {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(SubtractFloatRequest)
+		if err := req.validate(); err != nil {
+			return SubtractFloatResponse{}, err
+		}
+		rs, err := svc.SubtractFloat(ctx, float64(req.A), float64(req.B))
+		return SubtractFloatResponse{Rs: Float(rs)}, err
+	}
+}
+
+// SubtractFloat implements the service interface, so Endpoints may be used as a service.
+// This is primarily useful in the context of a client library.
+func (e Endpoints) SubtractFloat(ctx context.Context, a float64, b float64) (rs float64, err error) {
+	resp, err := e.SubtractFloatEndpoint(ctx, SubtractFloatRequest{A: Float(a), B: Float(b)})
+	if err != nil {
+		return
+	}
+	response := resp.(SubtractFloatResponse)
+	return float64(response.Rs), nil
+}
+
+// MakeMultiplyFloatEndpoint returns an endpoint that invokes MultiplyFloat on the service.
+// Primarily useful in a server.
+func MakeMultiplyFloatEndpoint(svc service.AddsvcService) (ep endpoint.Endpoint) {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(MultiplyFloatRequest)
+		if err := req.validate(); err != nil {
+			return MultiplyFloatResponse{}, err
+		}
+		rs, err := svc.MultiplyFloat(ctx, float64(req.A), float64(req.B))
+		return MultiplyFloatResponse{Rs: Float(rs)}, err
+	}
+}
+
+// MultiplyFloat implements the service interface, so Endpoints may be used as a service.
+// This is primarily useful in the context of a client library.
+func (e Endpoints) MultiplyFloat(ctx context.Context, a float64, b float64) (rs float64, err error) {
+	resp, err := e.MultiplyFloatEndpoint(ctx, MultiplyFloatRequest{A: Float(a), B: Float(b)})
+	if err != nil {
+		return
+	}
+	response := resp.(MultiplyFloatResponse)
+	return float64(response.Rs), nil
+}
+
+// MakeDivideFloatEndpoint returns an endpoint that invokes DivideFloat on the service.
+// Primarily useful in a server.
+func MakeDivideFloatEndpoint(svc service.AddsvcService) (ep endpoint.Endpoint) {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(DivideFloatRequest)
+		if err := req.validate(); err != nil {
+			return DivideFloatResponse{}, err
+		}
+		rs, err := svc.DivideFloat(ctx, float64(req.A), float64(req.B))
+		return DivideFloatResponse{Rs: Float(rs)}, err
+	}
+}
+
+// DivideFloat implements the service interface, so Endpoints may be used as a service.
+// This is primarily useful in the context of a client library.
+func (e Endpoints) DivideFloat(ctx context.Context, a float64, b float64) (rs float64, err error) {
+	resp, err := e.DivideFloatEndpoint(ctx, DivideFloatRequest{A: Float(a), B: Float(b)})
+	if err != nil {
+		return
+	}
+	response := resp.(DivideFloatResponse)
+	return float64(response.Rs), nil
+}
+
+// MakeModuloFloatEndpoint returns an endpoint that invokes ModuloFloat on the service.
+// Primarily useful in a server.
+func MakeModuloFloatEndpoint(svc service.AddsvcService) (ep endpoint.Endpoint) {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(ModuloFloatRequest)
+		if err := req.validate(); err != nil {
+			return ModuloFloatResponse{}, err
+		}
+		rs, err := svc.ModuloFloat(ctx, float64(req.A), float64(req.B))
+		return ModuloFloatResponse{Rs: Float(rs)}, err
+	}
+}
+
+// ModuloFloat implements the service interface, so Endpoints may be used as a service.
+// This is primarily useful in the context of a client library.
+func (e Endpoints) ModuloFloat(ctx context.Context, a float64, b float64) (rs float64, err error) {
+	resp, err := e.ModuloFloatEndpoint(ctx, ModuloFloatRequest{A: Float(a), B: Float(b)})
+	if err != nil {
+		return
+	}
+	response := resp.(ModuloFloatResponse)
+	return float64(response.Rs), nil
+}
@@ -0,0 +1,61 @@
+package endpoints
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/cage1016/gokitconsulk8s/pkg/openapi"
+)
+
+// Float is a float64 whose NaN and infinite values are JSON encoded as the
+// strings "NaN", "Infinity" and "-Infinity", as in the JSON mapping of
+// protobuf, since JSON numbers cannot hold them. It decodes numbers and
+// strings holding a number or one of these values.
+type Float float64
+
+// OpenAPISchema implements openapi.Schemer.
+func (Float) OpenAPISchema() *openapi.Schema {
+	return &openapi.Schema{
+		Description: `a number, or "NaN", "Infinity" or "-Infinity"; numbers are also accepted as strings`,
+		OneOf: []*openapi.Schema{
+			{Type: "number", Format: "double"},
+			{Type: "string"},
+		},
+	}
+}
+
+func (f Float) MarshalJSON() ([]byte, error) {
+	switch v := float64(f); {
+	case math.IsNaN(v):
+		return []byte(`"NaN"`), nil
+	case math.IsInf(v, 1):
+		return []byte(`"Infinity"`), nil
+	case math.IsInf(v, -1):
+		return []byte(`"-Infinity"`), nil
+	default:
+		return json.Marshal(v)
+	}
+}
+
+func (f *Float) UnmarshalJSON(b []byte) error {
+	if string(b) == "null" {
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		var v float64
+		if err := json.Unmarshal(b, &v); err != nil {
+			return err
+		}
+		*f = Float(v)
+		return nil
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return fmt.Errorf("%q is not a number, NaN, Infinity or -Infinity", s)
+	}
+	*f = Float(v)
+	return nil
+}
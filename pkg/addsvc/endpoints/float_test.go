@@ -0,0 +1,61 @@
+package endpoints
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func TestFloatJSON(t *testing.T) {
+	for _, c := range []struct {
+		f    float64
+		json string
+	}{
+		{1.5, `1.5`},
+		{-2, `-2`},
+		{0, `0`},
+		{math.MaxFloat64, `1.7976931348623157e+308`},
+		{math.NaN(), `"NaN"`},
+		{math.Inf(1), `"Infinity"`},
+		{math.Inf(-1), `"-Infinity"`},
+	} {
+		b, err := json.Marshal(Float(c.f))
+		if err != nil || string(b) != c.json {
+			t.Errorf("Marshal(%v) = %s, %v, want %s", c.f, b, err, c.json)
+			continue
+		}
+		var f Float
+		if err := json.Unmarshal(b, &f); err != nil {
+			t.Errorf("Unmarshal(%s): %v", b, err)
+			continue
+		}
+		if got := float64(f); got != c.f && !(math.IsNaN(got) && math.IsNaN(c.f)) {
+			t.Errorf("Unmarshal(%s) = %v, want %v", b, got, c.f)
+		}
+	}
+}
+
+func TestFloatUnmarshalJSON(t *testing.T) {
+	for _, c := range []struct {
+		json string
+		want float64
+		err  bool
+	}{
+		{`"2.5"`, 2.5, false},
+		{`"-1e3"`, -1000, false},
+		{`"inf"`, math.Inf(1), false},
+		{`null`, 7, false},
+		{`"two"`, 0, true},
+		{`true`, 0, true},
+	} {
+		f := Float(7)
+		err := json.Unmarshal([]byte(c.json), &f)
+		if (err != nil) != c.err {
+			t.Errorf("Unmarshal(%s) error %v, want error %v", c.json, err, c.err)
+			continue
+		}
+		if !c.err && float64(f) != c.want {
+			t.Errorf("Unmarshal(%s) = %v, want %v", c.json, float64(f), c.want)
+		}
+	}
+}
@@ -7,18 +7,20 @@ import (
 	"github.com/go-kit/kit/endpoint"
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
+
+	"github.com/cage1016/gokitconsulk8s/pkg/requestid"
 )
 
 // LoggingMiddleware returns an endpoint middleware that logs the
-// duration of each invocation, and the resulting error, if any.
+// request ID and duration of each invocation, and the resulting error, if any.
 func LoggingMiddleware(logger log.Logger) endpoint.Middleware {
 	return func(next endpoint.Endpoint) endpoint.Endpoint {
 		return func(ctx context.Context, request interface{}) (response interface{}, err error) {
 			defer func(begin time.Time) {
 				if err == nil {
-					level.Info(logger).Log("transport_error", err, "took", time.Since(begin))
+					level.Info(logger).Log("request_id", requestid.FromContext(ctx), "transport_error", err, "took", time.Since(begin))
 				} else {
-					level.Error(logger).Log("transport_error", err, "took", time.Since(begin))
+					level.Error(logger).Log("request_id", requestid.FromContext(ctx), "transport_error", err, "took", time.Since(begin))
 				}
 			}(time.Now())
 			return next(ctx, request)
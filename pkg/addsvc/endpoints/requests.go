@@ -22,4 +22,94 @@ type ConcatRequest struct {
 
 func (r ConcatRequest) validate() error {
 	return nil // TBA
-}
\ No newline at end of file
+}
+
+// SubtractRequest collects the request parameters for the Subtract method.
+type SubtractRequest struct {
+	A int64 `json:"a"`
+	B int64 `json:"b"`
+}
+
+func (r SubtractRequest) validate() error {
+	return nil
+}
+
+// MultiplyRequest collects the request parameters for the Multiply method.
+type MultiplyRequest struct {
+	A int64 `json:"a"`
+	B int64 `json:"b"`
+}
+
+func (r MultiplyRequest) validate() error {
+	return nil
+}
+
+// DivideRequest collects the request parameters for the Divide method.
+type DivideRequest struct {
+	A int64 `json:"a"`
+	B int64 `json:"b"`
+}
+
+func (r DivideRequest) validate() error {
+	return nil
+}
+
+// ModuloRequest collects the request parameters for the Modulo method.
+type ModuloRequest struct {
+	A int64 `json:"a"`
+	B int64 `json:"b"`
+}
+
+func (r ModuloRequest) validate() error {
+	return nil
+}
+
+// SumFloatRequest collects the request parameters for the SumFloat method.
+type SumFloatRequest struct {
+	A Float `json:"a"`
+	B Float `json:"b"`
+}
+
+func (r SumFloatRequest) validate() error {
+	return nil
+}
+
+// SubtractFloatRequest collects the request parameters for the SubtractFloat method.
+type SubtractFloatRequest struct {
+	A Float `json:"a"`
+	B Float `json:"b"`
+}
+
+func (r SubtractFloatRequest) validate() error {
+	return nil
+}
+
+// MultiplyFloatRequest collects the request parameters for the MultiplyFloat method.
+type MultiplyFloatRequest struct {
+	A Float `json:"a"`
+	B Float `json:"b"`
+}
+
+func (r MultiplyFloatRequest) validate() error {
+	return nil
+}
+
+// DivideFloatRequest collects the request parameters for the DivideFloat method.
+type DivideFloatRequest struct {
+	A Float `json:"a"`
+	B Float `json:"b"`
+}
+
+func (r DivideFloatRequest) validate() error {
+	return nil
+}
+
+// ModuloFloatRequest collects the request parameters for the ModuloFloat method.
+type ModuloFloatRequest struct {
+	A Float `json:"a"`
+	B Float `json:"b"`
+}
+
+func (r ModuloFloatRequest) validate() error {
+	return nil
+}
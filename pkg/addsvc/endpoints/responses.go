@@ -14,6 +14,42 @@ var (
 	_ httptransport.Headerer = (*ConcatResponse)(nil)
 
 	_ httptransport.StatusCoder = (*ConcatResponse)(nil)
+
+	_ httptransport.Headerer = (*SubtractResponse)(nil)
+
+	_ httptransport.StatusCoder = (*SubtractResponse)(nil)
+
+	_ httptransport.Headerer = (*MultiplyResponse)(nil)
+
+	_ httptransport.StatusCoder = (*MultiplyResponse)(nil)
+
+	_ httptransport.Headerer = (*DivideResponse)(nil)
+
+	_ httptransport.StatusCoder = (*DivideResponse)(nil)
+
+	_ httptransport.Headerer = (*ModuloResponse)(nil)
+
+	_ httptransport.StatusCoder = (*ModuloResponse)(nil)
+
+	_ httptransport.Headerer = (*SumFloatResponse)(nil)
+
+	_ httptransport.StatusCoder = (*SumFloatResponse)(nil)
+
+	_ httptransport.Headerer = (*SubtractFloatResponse)(nil)
+
+	_ httptransport.StatusCoder = (*SubtractFloatResponse)(nil)
+
+	_ httptransport.Headerer = (*MultiplyFloatResponse)(nil)
+
+	_ httptransport.StatusCoder = (*MultiplyFloatResponse)(nil)
+
+	_ httptransport.Headerer = (*DivideFloatResponse)(nil)
+
+	_ httptransport.StatusCoder = (*DivideFloatResponse)(nil)
+
+	_ httptransport.Headerer = (*ModuloFloatResponse)(nil)
+
+	_ httptransport.StatusCoder = (*ModuloFloatResponse)(nil)
 )
 
 // SumResponse collects the response values for the Sum method.
@@ -44,3 +80,128 @@ func (r ConcatResponse) Headers() http.Header {
 	return http.Header{}
 }
 
+// SubtractResponse collects the response values for the Subtract method.
+type SubtractResponse struct {
+	Rs  int64 `json:"rs"`
+	Err error `json:"err"`
+}
+
+func (r SubtractResponse) StatusCode() int {
+	return http.StatusOK
+}
+
+func (r SubtractResponse) Headers() http.Header {
+	return http.Header{}
+}
+
+// MultiplyResponse collects the response values for the Multiply method.
+type MultiplyResponse struct {
+	Rs  int64 `json:"rs"`
+	Err error `json:"err"`
+}
+
+func (r MultiplyResponse) StatusCode() int {
+	return http.StatusOK
+}
+
+func (r MultiplyResponse) Headers() http.Header {
+	return http.Header{}
+}
+
+// DivideResponse collects the response values for the Divide method.
+type DivideResponse struct {
+	Rs  int64 `json:"rs"`
+	Err error `json:"err"`
+}
+
+func (r DivideResponse) StatusCode() int {
+	return http.StatusOK
+}
+
+func (r DivideResponse) Headers() http.Header {
+	return http.Header{}
+}
+
+// ModuloResponse collects the response values for the Modulo method.
+type ModuloResponse struct {
+	Rs  int64 `json:"rs"`
+	Err error `json:"err"`
+}
+
+func (r ModuloResponse) StatusCode() int {
+	return http.StatusOK
+}
+
+func (r ModuloResponse) Headers() http.Header {
+	return http.Header{}
+}
+
+// SumFloatResponse collects the response values for the SumFloat method.
+type SumFloatResponse struct {
+	Rs  Float `json:"rs"`
+	Err error `json:"err"`
+}
+
+func (r SumFloatResponse) StatusCode() int {
+	return http.StatusOK
+}
+
+func (r SumFloatResponse) Headers() http.Header {
+	return http.Header{}
+}
+
+// SubtractFloatResponse collects the response values for the SubtractFloat method.
+type SubtractFloatResponse struct {
+	Rs  Float `json:"rs"`
+	Err error `json:"err"`
+}
+
+func (r SubtractFloatResponse) StatusCode() int {
+	return http.StatusOK
+}
+
+func (r SubtractFloatResponse) Headers() http.Header {
+	return http.Header{}
+}
+
+// MultiplyFloatResponse collects the response values for the MultiplyFloat method.
+type MultiplyFloatResponse struct {
+	Rs  Float `json:"rs"`
+	Err error `json:"err"`
+}
+
+func (r MultiplyFloatResponse) StatusCode() int {
+	return http.StatusOK
+}
+
+func (r MultiplyFloatResponse) Headers() http.Header {
+	return http.Header{}
+}
+
+// DivideFloatResponse collects the response values for the DivideFloat method.
+type DivideFloatResponse struct {
+	Rs  Float `json:"rs"`
+	Err error `json:"err"`
+}
+
+func (r DivideFloatResponse) StatusCode() int {
+	return http.StatusOK
+}
+
+func (r DivideFloatResponse) Headers() http.Header {
+	return http.Header{}
+}
+
+// ModuloFloatResponse collects the response values for the ModuloFloat method.
+type ModuloFloatResponse struct {
+	Rs  Float `json:"rs"`
+	Err error `json:"err"`
+}
+
+func (r ModuloFloatResponse) StatusCode() int {
+	return http.StatusOK
+}
+
+func (r ModuloFloatResponse) Headers() http.Header {
+	return http.Header{}
+}
@@ -6,6 +6,8 @@ import (
 
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
+
+	"github.com/cage1016/gokitconsulk8s/pkg/requestid"
 )
 
 type loggingMiddleware struct {
@@ -23,7 +25,7 @@ func LoggingMiddleware(logger log.Logger) Middleware {
 
 func (lm loggingMiddleware) Sum(ctx context.Context, a int64, b int64) (rs int64, err error) {
 	defer func(begin time.Time) {
-		lm.logger.Log("method", "Sum", "a", a, "b", b, "err", err)
+		lm.logger.Log("request_id", requestid.FromContext(ctx), "method", "Sum", "a", a, "b", b, "err", err)
 	}(time.Now())
 
 	return lm.next.Sum(ctx, a, b)
@@ -31,8 +33,80 @@ func (lm loggingMiddleware) Sum(ctx context.Context, a int64, b int64) (rs int64
 
 func (lm loggingMiddleware) Concat(ctx context.Context, a string, b string) (rs string, err error) {
 	defer func(begin time.Time) {
-		lm.logger.Log("method", "Concat", "a", a, "b", b, "err", err)
+		lm.logger.Log("request_id", requestid.FromContext(ctx), "method", "Concat", "a", a, "b", b, "err", err)
 	}(time.Now())
 
 	return lm.next.Concat(ctx, a, b)
 }
+
+func (lm loggingMiddleware) Subtract(ctx context.Context, a int64, b int64) (rs int64, err error) {
+	defer func(begin time.Time) {
+		lm.logger.Log("request_id", requestid.FromContext(ctx), "method", "Subtract", "a", a, "b", b, "err", err)
+	}(time.Now())
+
+	return lm.next.Subtract(ctx, a, b)
+}
+
+func (lm loggingMiddleware) Multiply(ctx context.Context, a int64, b int64) (rs int64, err error) {
+	defer func(begin time.Time) {
+		lm.logger.Log("request_id", requestid.FromContext(ctx), "method", "Multiply", "a", a, "b", b, "err", err)
+	}(time.Now())
+
+	return lm.next.Multiply(ctx, a, b)
+}
+
+func (lm loggingMiddleware) Divide(ctx context.Context, a int64, b int64) (rs int64, err error) {
+	defer func(begin time.Time) {
+		lm.logger.Log("request_id", requestid.FromContext(ctx), "method", "Divide", "a", a, "b", b, "err", err)
+	}(time.Now())
+
+	return lm.next.Divide(ctx, a, b)
+}
+
+func (lm loggingMiddleware) Modulo(ctx context.Context, a int64, b int64) (rs int64, err error) {
+	defer func(begin time.Time) {
+		lm.logger.Log("request_id", requestid.FromContext(ctx), "method", "Modulo", "a", a, "b", b, "err", err)
+	}(time.Now())
+
+	return lm.next.Modulo(ctx, a, b)
+}
+
+func (lm loggingMiddleware) SumFloat(ctx context.Context, a float64, b float64) (rs float64, err error) {
+	defer func(begin time.Time) {
+		lm.logger.Log("request_id", requestid.FromContext(ctx), "method", "SumFloat", "a", a, "b", b, "err", err)
+	}(time.Now())
+
+	return lm.next.SumFloat(ctx, a, b)
+}
+
+func (lm loggingMiddleware) SubtractFloat(ctx context.Context, a float64, b float64) (rs float64, err error) {
+	defer func(begin time.Time) {
+		lm.logger.Log("request_id", requestid.FromContext(ctx), "method", "SubtractFloat", "a", a, "b", b, "err", err)
+	}(time.Now())
+
+	return lm.next.SubtractFloat(ctx, a, b)
+}
+
+func (lm loggingMiddleware) MultiplyFloat(ctx context.Context, a float64, b float64) (rs float64, err error) {
+	defer func(begin time.Time) {
+		lm.logger.Log("request_id", requestid.FromContext(ctx), "method", "MultiplyFloat", "a", a, "b", b, "err", err)
+	}(time.Now())
+
+	return lm.next.MultiplyFloat(ctx, a, b)
+}
+
+func (lm loggingMiddleware) DivideFloat(ctx context.Context, a float64, b float64) (rs float64, err error) {
+	defer func(begin time.Time) {
+		lm.logger.Log("request_id", requestid.FromContext(ctx), "method", "DivideFloat", "a", a, "b", b, "err", err)
+	}(time.Now())
+
+	return lm.next.DivideFloat(ctx, a, b)
+}
+
+func (lm loggingMiddleware) ModuloFloat(ctx context.Context, a float64, b float64) (rs float64, err error) {
+	defer func(begin time.Time) {
+		lm.logger.Log("request_id", requestid.FromContext(ctx), "method", "ModuloFloat", "a", a, "b", b, "err", err)
+	}(time.Now())
+
+	return lm.next.ModuloFloat(ctx, a, b)
+}
@@ -2,8 +2,22 @@ package service
 
 import (
 	"context"
+	"math"
 
 	"github.com/go-kit/kit/log"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Errors of the arithmetic methods, as gRPC statuses so that every transport
+// reports them with their code. Over the wire they are told apart by code.
+var (
+	// ErrDivideByZero is returned by the divisions and modulos by zero.
+	ErrDivideByZero = status.Error(codes.InvalidArgument, "division by zero")
+	// ErrOverflow is returned when the result of an integer method does not
+	// fit in an int64, or that of a float method with finite operands is not
+	// finite.
+	ErrOverflow = status.Error(codes.OutOfRange, "result overflows")
 )
 
 // Middleware describes a service (as opposed to endpoint) middleware.
@@ -12,14 +26,29 @@ type Middleware func(AddsvcService) AddsvcService
 // Service describes a service that adds things together
 // Implement yor service methods methods.
 // e.x: Foo(ctx context.Context, s string)(rs string, err error)
+//
+// The integer methods fail with ErrOverflow instead of wrapping around, and
+// divide and take the remainder truncating towards zero, as Go does. The
+// float methods follow IEEE 754: NaN and infinite operands give NaN or
+// infinite results, but finite operands giving an infinite result fail with
+// ErrOverflow. Divisions and modulos by zero fail with ErrDivideByZero.
 type AddsvcService interface {
 	Sum(ctx context.Context, a int64, b int64) (rs int64, err error)
 	Concat(ctx context.Context, a string, b string) (rs string, err error)
+	Subtract(ctx context.Context, a int64, b int64) (rs int64, err error)
+	Multiply(ctx context.Context, a int64, b int64) (rs int64, err error)
+	Divide(ctx context.Context, a int64, b int64) (rs int64, err error)
+	Modulo(ctx context.Context, a int64, b int64) (rs int64, err error)
+	SumFloat(ctx context.Context, a float64, b float64) (rs float64, err error)
+	SubtractFloat(ctx context.Context, a float64, b float64) (rs float64, err error)
+	MultiplyFloat(ctx context.Context, a float64, b float64) (rs float64, err error)
+	DivideFloat(ctx context.Context, a float64, b float64) (rs float64, err error)
+	ModuloFloat(ctx context.Context, a float64, b float64) (rs float64, err error)
 }
 
 // the concrete implementation of service interface
 type stubAddsvcService struct {
-	logger log.Logger `json:"logger"`
+	logger log.Logger
 }
 
 // New return a new instance of the service.
@@ -35,10 +64,97 @@ func New(logger log.Logger) (s AddsvcService) {
 
 // Implement the business logic of Sum
 func (ad *stubAddsvcService) Sum(ctx context.Context, a int64, b int64) (rs int64, err error) {
-	return a + b, err
+	rs = a + b
+	// The sum overflowed when its sign differs from those of both operands.
+	if (a^rs)&(b^rs) < 0 {
+		return 0, ErrOverflow
+	}
+	return rs, nil
 }
 
 // Implement the business logic of Concat
 func (ad *stubAddsvcService) Concat(ctx context.Context, a string, b string) (rs string, err error) {
 	return a + b, err
 }
+
+// Implement the business logic of Subtract
+func (ad *stubAddsvcService) Subtract(ctx context.Context, a int64, b int64) (rs int64, err error) {
+	rs = a - b
+	// The difference overflowed when the operands have different signs and
+	// it has the sign of b.
+	if (a^b)&(a^rs) < 0 {
+		return 0, ErrOverflow
+	}
+	return rs, nil
+}
+
+// Implement the business logic of Multiply
+func (ad *stubAddsvcService) Multiply(ctx context.Context, a int64, b int64) (rs int64, err error) {
+	if a == 0 || b == 0 {
+		return 0, nil
+	}
+	rs = a * b
+	if rs/b != a || (a == -1 && b == math.MinInt64) || (b == -1 && a == math.MinInt64) {
+		return 0, ErrOverflow
+	}
+	return rs, nil
+}
+
+// Implement the business logic of Divide
+func (ad *stubAddsvcService) Divide(ctx context.Context, a int64, b int64) (rs int64, err error) {
+	if b == 0 {
+		return 0, ErrDivideByZero
+	}
+	if a == math.MinInt64 && b == -1 {
+		return 0, ErrOverflow
+	}
+	return a / b, nil
+}
+
+// Implement the business logic of Modulo
+func (ad *stubAddsvcService) Modulo(ctx context.Context, a int64, b int64) (rs int64, err error) {
+	if b == 0 {
+		return 0, ErrDivideByZero
+	}
+	return a % b, nil
+}
+
+// Implement the business logic of SumFloat
+func (ad *stubAddsvcService) SumFloat(ctx context.Context, a float64, b float64) (rs float64, err error) {
+	return finite(a+b, a, b)
+}
+
+// Implement the business logic of SubtractFloat
+func (ad *stubAddsvcService) SubtractFloat(ctx context.Context, a float64, b float64) (rs float64, err error) {
+	return finite(a-b, a, b)
+}
+
+// Implement the business logic of MultiplyFloat
+func (ad *stubAddsvcService) MultiplyFloat(ctx context.Context, a float64, b float64) (rs float64, err error) {
+	return finite(a*b, a, b)
+}
+
+// Implement the business logic of DivideFloat
+func (ad *stubAddsvcService) DivideFloat(ctx context.Context, a float64, b float64) (rs float64, err error) {
+	if b == 0 {
+		return 0, ErrDivideByZero
+	}
+	return finite(a/b, a, b)
+}
+
+// Implement the business logic of ModuloFloat
+func (ad *stubAddsvcService) ModuloFloat(ctx context.Context, a float64, b float64) (rs float64, err error) {
+	if b == 0 {
+		return 0, ErrDivideByZero
+	}
+	return math.Mod(a, b), nil
+}
+
+// finite returns rs, the result of an operation on a and b, or ErrOverflow
+// when it is infinite while a and b are finite.
+func finite(rs, a, b float64) (float64, error) {
+	if math.IsInf(rs, 0) && !math.IsInf(a, 0) && !math.IsInf(b, 0) {
+		return 0, ErrOverflow
+	}
+	return rs, nil
+}
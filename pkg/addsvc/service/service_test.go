@@ -0,0 +1,59 @@
+package service
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+)
+
+func TestIntegerMethods(t *testing.T) {
+	svc := New(log.NewNopLogger())
+	methods := map[string]func(context.Context, int64, int64) (int64, error){
+		"sum":      svc.Sum,
+		"subtract": svc.Subtract,
+		"multiply": svc.Multiply,
+		"divide":   svc.Divide,
+		"modulo":   svc.Modulo,
+	}
+	for _, c := range []struct {
+		method string
+		a, b   int64
+		want   int64
+		err    error
+	}{
+		{"sum", 1, 2, 3, nil},
+		{"sum", math.MaxInt64, 1, 0, ErrOverflow},
+		{"sum", math.MinInt64, -1, 0, ErrOverflow},
+		{"sum", math.MaxInt64, math.MinInt64, -1, nil},
+		{"subtract", 1, 2, -1, nil},
+		{"subtract", math.MinInt64, 1, 0, ErrOverflow},
+		{"subtract", 0, math.MinInt64, 0, ErrOverflow},
+		{"subtract", -1, math.MinInt64, math.MaxInt64, nil},
+		{"multiply", 6, 7, 42, nil},
+		{"multiply", -3, 4, -12, nil},
+		{"multiply", 0, math.MinInt64, 0, nil},
+		{"multiply", math.MaxInt64, 2, 0, ErrOverflow},
+		{"multiply", 1 << 32, 1 << 31, 0, ErrOverflow},
+		{"multiply", 1 << 32, -(1 << 31), math.MinInt64, nil},
+		{"multiply", math.MinInt64, -1, 0, ErrOverflow},
+		{"multiply", -1, math.MinInt64, 0, ErrOverflow},
+		{"multiply", math.MinInt64, 1, math.MinInt64, nil},
+		{"multiply", -1, math.MaxInt64, -math.MaxInt64, nil},
+		{"divide", 7, 2, 3, nil},
+		{"divide", -7, 2, -3, nil},
+		{"divide", 7, 0, 0, ErrDivideByZero},
+		{"divide", math.MinInt64, -1, 0, ErrOverflow},
+		{"divide", math.MinInt64, 1, math.MinInt64, nil},
+		{"divide", math.MaxInt64, -1, -math.MaxInt64, nil},
+		{"modulo", -7, 3, -1, nil},
+		{"modulo", 7, 0, 0, ErrDivideByZero},
+		{"modulo", math.MinInt64, -1, 0, nil},
+	} {
+		got, err := methods[c.method](context.Background(), c.a, c.b)
+		if got != c.want || err != c.err {
+			t.Errorf("%s(%d, %d) = %d, %v, want %d, %v", c.method, c.a, c.b, got, err, c.want, c.err)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package transports
+
+import (
+	"context"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/go-kit/kit/sd/lb"
+	"github.com/sony/gobreaker"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/cage1016/gokitconsulk8s/pkg/addsvc/service"
+)
+
+type failoverMiddleware struct {
+	logger   log.Logger            `json:""`
+	next     service.AddsvcService `json:""`
+	fallback service.AddsvcService `json:""`
+}
+
+// FailoverMiddleware returns a service Middleware that repeats a call against
+// fallback whenever the wrapped client reports addsvc as unreachable, e.g. a
+// gRPC client whose endpoint is down failing over to an HTTP client.
+func FailoverMiddleware(fallback service.AddsvcService, logger log.Logger) service.Middleware {
+	return func(next service.AddsvcService) service.AddsvcService {
+		return failoverMiddleware{logger: logger, next: next, fallback: fallback}
+	}
+}
+
+func (fm failoverMiddleware) Sum(ctx context.Context, a int64, b int64) (rs int64, err error) {
+	rs, err = fm.next.Sum(ctx, a, b)
+	if unavailable(err) {
+		level.Warn(fm.logger).Log("method", "Sum", "failover", err)
+		return fm.fallback.Sum(ctx, a, b)
+	}
+	return rs, err
+}
+
+func (fm failoverMiddleware) Concat(ctx context.Context, a string, b string) (rs string, err error) {
+	rs, err = fm.next.Concat(ctx, a, b)
+	if unavailable(err) {
+		level.Warn(fm.logger).Log("method", "Concat", "failover", err)
+		return fm.fallback.Concat(ctx, a, b)
+	}
+	return rs, err
+}
+
+func (fm failoverMiddleware) Subtract(ctx context.Context, a int64, b int64) (rs int64, err error) {
+	rs, err = fm.next.Subtract(ctx, a, b)
+	if unavailable(err) {
+		level.Warn(fm.logger).Log("method", "Subtract", "failover", err)
+		return fm.fallback.Subtract(ctx, a, b)
+	}
+	return rs, err
+}
+
+func (fm failoverMiddleware) Multiply(ctx context.Context, a int64, b int64) (rs int64, err error) {
+	rs, err = fm.next.Multiply(ctx, a, b)
+	if unavailable(err) {
+		level.Warn(fm.logger).Log("method", "Multiply", "failover", err)
+		return fm.fallback.Multiply(ctx, a, b)
+	}
+	return rs, err
+}
+
+func (fm failoverMiddleware) Divide(ctx context.Context, a int64, b int64) (rs int64, err error) {
+	rs, err = fm.next.Divide(ctx, a, b)
+	if unavailable(err) {
+		level.Warn(fm.logger).Log("method", "Divide", "failover", err)
+		return fm.fallback.Divide(ctx, a, b)
+	}
+	return rs, err
+}
+
+func (fm failoverMiddleware) Modulo(ctx context.Context, a int64, b int64) (rs int64, err error) {
+	rs, err = fm.next.Modulo(ctx, a, b)
+	if unavailable(err) {
+		level.Warn(fm.logger).Log("method", "Modulo", "failover", err)
+		return fm.fallback.Modulo(ctx, a, b)
+	}
+	return rs, err
+}
+
+func (fm failoverMiddleware) SumFloat(ctx context.Context, a float64, b float64) (rs float64, err error) {
+	rs, err = fm.next.SumFloat(ctx, a, b)
+	if unavailable(err) {
+		level.Warn(fm.logger).Log("method", "SumFloat", "failover", err)
+		return fm.fallback.SumFloat(ctx, a, b)
+	}
+	return rs, err
+}
+
+func (fm failoverMiddleware) SubtractFloat(ctx context.Context, a float64, b float64) (rs float64, err error) {
+	rs, err = fm.next.SubtractFloat(ctx, a, b)
+	if unavailable(err) {
+		level.Warn(fm.logger).Log("method", "SubtractFloat", "failover", err)
+		return fm.fallback.SubtractFloat(ctx, a, b)
+	}
+	return rs, err
+}
+
+func (fm failoverMiddleware) MultiplyFloat(ctx context.Context, a float64, b float64) (rs float64, err error) {
+	rs, err = fm.next.MultiplyFloat(ctx, a, b)
+	if unavailable(err) {
+		level.Warn(fm.logger).Log("method", "MultiplyFloat", "failover", err)
+		return fm.fallback.MultiplyFloat(ctx, a, b)
+	}
+	return rs, err
+}
+
+func (fm failoverMiddleware) DivideFloat(ctx context.Context, a float64, b float64) (rs float64, err error) {
+	rs, err = fm.next.DivideFloat(ctx, a, b)
+	if unavailable(err) {
+		level.Warn(fm.logger).Log("method", "DivideFloat", "failover", err)
+		return fm.fallback.DivideFloat(ctx, a, b)
+	}
+	return rs, err
+}
+
+func (fm failoverMiddleware) ModuloFloat(ctx context.Context, a float64, b float64) (rs float64, err error) {
+	rs, err = fm.next.ModuloFloat(ctx, a, b)
+	if unavailable(err) {
+		level.Warn(fm.logger).Log("method", "ModuloFloat", "failover", err)
+		return fm.fallback.ModuloFloat(ctx, a, b)
+	}
+	return rs, err
+}
+
+// unavailable reports whether err means the remote instances could not be
+// reached at all, either directly or because their circuit breakers are open.
+func unavailable(err error) bool {
+	if err == nil {
+		return false
+	}
+	if lberr, ok := err.(lb.RetryError); ok {
+		for _, err := range lberr.RawErrors {
+			if !unavailable(err) {
+				return false
+			}
+		}
+		return len(lberr.RawErrors) > 0 || unavailable(lberr.Final)
+	}
+	if err == lb.ErrNoEndpoints || err == gobreaker.ErrOpenState || err == gobreaker.ErrTooManyRequests {
+		return true
+	}
+	return status.Code(err) == codes.Unavailable
+}
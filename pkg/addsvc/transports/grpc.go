@@ -2,19 +2,14 @@ package transports
 
 import (
 	"context"
-	"time"
 
-	"github.com/go-kit/kit/circuitbreaker"
 	"github.com/go-kit/kit/endpoint"
 	"github.com/go-kit/kit/log"
-	"github.com/go-kit/kit/ratelimit"
 	"github.com/go-kit/kit/tracing/opentracing"
 	"github.com/go-kit/kit/tracing/zipkin"
 	grpctransport "github.com/go-kit/kit/transport/grpc"
 	stdopentracing "github.com/opentracing/opentracing-go"
 	stdzipkin "github.com/openzipkin/zipkin-go"
-	"github.com/sony/gobreaker"
-	"golang.org/x/time/rate"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -22,11 +17,23 @@ import (
 	pb "github.com/cage1016/gokitconsulk8s/pb/addsvc"
 	"github.com/cage1016/gokitconsulk8s/pkg/addsvc/endpoints"
 	"github.com/cage1016/gokitconsulk8s/pkg/addsvc/service"
+	"github.com/cage1016/gokitconsulk8s/pkg/fault"
+	"github.com/cage1016/gokitconsulk8s/pkg/requestid"
+	"github.com/cage1016/gokitconsulk8s/pkg/resilience"
 )
 
 type grpcServer struct {
-	sum    grpctransport.Handler `json:""`
-	concat grpctransport.Handler `json:""`
+	sum           grpctransport.Handler `json:""`
+	concat        grpctransport.Handler `json:""`
+	subtract      grpctransport.Handler `json:""`
+	multiply      grpctransport.Handler `json:""`
+	divide        grpctransport.Handler `json:""`
+	modulo        grpctransport.Handler `json:""`
+	sumfloat      grpctransport.Handler `json:""`
+	subtractfloat grpctransport.Handler `json:""`
+	multiplyfloat grpctransport.Handler `json:""`
+	dividefloat   grpctransport.Handler `json:""`
+	modulofloat   grpctransport.Handler `json:""`
 }
 
 func (s *grpcServer) Sum(ctx context.Context, req *pb.SumRequest) (rep *pb.SumReply, err error) {
@@ -47,6 +54,87 @@ func (s *grpcServer) Concat(ctx context.Context, req *pb.ConcatRequest) (rep *pb
 	return rep, nil
 }
 
+func (s *grpcServer) Subtract(ctx context.Context, req *pb.SubtractRequest) (rep *pb.SubtractReply, err error) {
+	_, rp, err := s.subtract.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, grpcEncodeError(err)
+	}
+	rep = rp.(*pb.SubtractReply)
+	return rep, nil
+}
+
+func (s *grpcServer) Multiply(ctx context.Context, req *pb.MultiplyRequest) (rep *pb.MultiplyReply, err error) {
+	_, rp, err := s.multiply.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, grpcEncodeError(err)
+	}
+	rep = rp.(*pb.MultiplyReply)
+	return rep, nil
+}
+
+func (s *grpcServer) Divide(ctx context.Context, req *pb.DivideRequest) (rep *pb.DivideReply, err error) {
+	_, rp, err := s.divide.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, grpcEncodeError(err)
+	}
+	rep = rp.(*pb.DivideReply)
+	return rep, nil
+}
+
+func (s *grpcServer) Modulo(ctx context.Context, req *pb.ModuloRequest) (rep *pb.ModuloReply, err error) {
+	_, rp, err := s.modulo.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, grpcEncodeError(err)
+	}
+	rep = rp.(*pb.ModuloReply)
+	return rep, nil
+}
+
+func (s *grpcServer) SumFloat(ctx context.Context, req *pb.SumFloatRequest) (rep *pb.SumFloatReply, err error) {
+	_, rp, err := s.sumfloat.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, grpcEncodeError(err)
+	}
+	rep = rp.(*pb.SumFloatReply)
+	return rep, nil
+}
+
+func (s *grpcServer) SubtractFloat(ctx context.Context, req *pb.SubtractFloatRequest) (rep *pb.SubtractFloatReply, err error) {
+	_, rp, err := s.subtractfloat.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, grpcEncodeError(err)
+	}
+	rep = rp.(*pb.SubtractFloatReply)
+	return rep, nil
+}
+
+func (s *grpcServer) MultiplyFloat(ctx context.Context, req *pb.MultiplyFloatRequest) (rep *pb.MultiplyFloatReply, err error) {
+	_, rp, err := s.multiplyfloat.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, grpcEncodeError(err)
+	}
+	rep = rp.(*pb.MultiplyFloatReply)
+	return rep, nil
+}
+
+func (s *grpcServer) DivideFloat(ctx context.Context, req *pb.DivideFloatRequest) (rep *pb.DivideFloatReply, err error) {
+	_, rp, err := s.dividefloat.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, grpcEncodeError(err)
+	}
+	rep = rp.(*pb.DivideFloatReply)
+	return rep, nil
+}
+
+func (s *grpcServer) ModuloFloat(ctx context.Context, req *pb.ModuloFloatRequest) (rep *pb.ModuloFloatReply, err error) {
+	_, rp, err := s.modulofloat.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, grpcEncodeError(err)
+	}
+	rep = rp.(*pb.ModuloFloatReply)
+	return rep, nil
+}
+
 // MakeGRPCServer makes a set of endpoints available as a gRPC server.
 func MakeGRPCServer(endpoints endpoints.Endpoints, otTracer stdopentracing.Tracer, zipkinTracer *stdzipkin.Tracer, logger log.Logger) (req pb.AddsvcServer) { // Zipkin GRPC Server Trace can either be instantiated per gRPC method with a
 	// provided operation name or a global tracing service can be instantiated
@@ -61,6 +149,8 @@ func MakeGRPCServer(endpoints endpoints.Endpoints, otTracer stdopentracing.Trace
 
 	options := []grpctransport.ServerOption{
 		grpctransport.ServerErrorLogger(logger),
+		grpctransport.ServerBefore(requestid.GRPCToContext()),
+		grpctransport.ServerBefore(fault.GRPCToContext()),
 		zipkinServer,
 	}
 
@@ -78,6 +168,69 @@ func MakeGRPCServer(endpoints endpoints.Endpoints, otTracer stdopentracing.Trace
 			encodeGRPCConcatResponse,
 			append(options, grpctransport.ServerBefore(opentracing.GRPCToContext(otTracer, "Concat", logger)))...,
 		),
+
+		subtract: grpctransport.NewServer(
+			endpoints.SubtractEndpoint,
+			decodeGRPCSubtractRequest,
+			encodeGRPCSubtractResponse,
+			append(options, grpctransport.ServerBefore(opentracing.GRPCToContext(otTracer, "Subtract", logger)))...,
+		),
+
+		multiply: grpctransport.NewServer(
+			endpoints.MultiplyEndpoint,
+			decodeGRPCMultiplyRequest,
+			encodeGRPCMultiplyResponse,
+			append(options, grpctransport.ServerBefore(opentracing.GRPCToContext(otTracer, "Multiply", logger)))...,
+		),
+
+		divide: grpctransport.NewServer(
+			endpoints.DivideEndpoint,
+			decodeGRPCDivideRequest,
+			encodeGRPCDivideResponse,
+			append(options, grpctransport.ServerBefore(opentracing.GRPCToContext(otTracer, "Divide", logger)))...,
+		),
+
+		modulo: grpctransport.NewServer(
+			endpoints.ModuloEndpoint,
+			decodeGRPCModuloRequest,
+			encodeGRPCModuloResponse,
+			append(options, grpctransport.ServerBefore(opentracing.GRPCToContext(otTracer, "Modulo", logger)))...,
+		),
+
+		sumfloat: grpctransport.NewServer(
+			endpoints.SumFloatEndpoint,
+			decodeGRPCSumFloatRequest,
+			encodeGRPCSumFloatResponse,
+			append(options, grpctransport.ServerBefore(opentracing.GRPCToContext(otTracer, "SumFloat", logger)))...,
+		),
+
+		subtractfloat: grpctransport.NewServer(
+			endpoints.SubtractFloatEndpoint,
+			decodeGRPCSubtractFloatRequest,
+			encodeGRPCSubtractFloatResponse,
+			append(options, grpctransport.ServerBefore(opentracing.GRPCToContext(otTracer, "SubtractFloat", logger)))...,
+		),
+
+		multiplyfloat: grpctransport.NewServer(
+			endpoints.MultiplyFloatEndpoint,
+			decodeGRPCMultiplyFloatRequest,
+			encodeGRPCMultiplyFloatResponse,
+			append(options, grpctransport.ServerBefore(opentracing.GRPCToContext(otTracer, "MultiplyFloat", logger)))...,
+		),
+
+		dividefloat: grpctransport.NewServer(
+			endpoints.DivideFloatEndpoint,
+			decodeGRPCDivideFloatRequest,
+			encodeGRPCDivideFloatResponse,
+			append(options, grpctransport.ServerBefore(opentracing.GRPCToContext(otTracer, "DivideFloat", logger)))...,
+		),
+
+		modulofloat: grpctransport.NewServer(
+			endpoints.ModuloFloatEndpoint,
+			decodeGRPCModuloFloatRequest,
+			encodeGRPCModuloFloatResponse,
+			append(options, grpctransport.ServerBefore(opentracing.GRPCToContext(otTracer, "ModuloFloat", logger)))...,
+		),
 	}
 }
 
@@ -109,16 +262,139 @@ func encodeGRPCConcatResponse(_ context.Context, grpcReply interface{}) (res int
 	return &pb.ConcatReply{Rs: reply.Rs}, grpcEncodeError(reply.Err)
 }
 
+// decodeGRPCSubtractRequest is a transport/grpc.DecodeRequestFunc that converts a
+// gRPC request to a user-domain request. Primarily useful in a server.
+func decodeGRPCSubtractRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	req := grpcReq.(*pb.SubtractRequest)
+	return endpoints.SubtractRequest{A: req.A, B: req.B}, nil
+}
+
+// encodeGRPCSubtractResponse is a transport/grpc.EncodeResponseFunc that converts a
+// user-domain response to a gRPC reply. Primarily useful in a server.
+func encodeGRPCSubtractResponse(_ context.Context, grpcReply interface{}) (res interface{}, err error) {
+	reply := grpcReply.(endpoints.SubtractResponse)
+	return &pb.SubtractReply{Rs: reply.Rs}, grpcEncodeError(reply.Err)
+}
+
+// decodeGRPCMultiplyRequest is a transport/grpc.DecodeRequestFunc that converts a
+// gRPC request to a user-domain request. Primarily useful in a server.
+func decodeGRPCMultiplyRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	req := grpcReq.(*pb.MultiplyRequest)
+	return endpoints.MultiplyRequest{A: req.A, B: req.B}, nil
+}
+
+// encodeGRPCMultiplyResponse is a transport/grpc.EncodeResponseFunc that converts a
+// user-domain response to a gRPC reply. Primarily useful in a server.
+func encodeGRPCMultiplyResponse(_ context.Context, grpcReply interface{}) (res interface{}, err error) {
+	reply := grpcReply.(endpoints.MultiplyResponse)
+	return &pb.MultiplyReply{Rs: reply.Rs}, grpcEncodeError(reply.Err)
+}
+
+// decodeGRPCDivideRequest is a transport/grpc.DecodeRequestFunc that converts a
+// gRPC request to a user-domain request. Primarily useful in a server.
+func decodeGRPCDivideRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	req := grpcReq.(*pb.DivideRequest)
+	return endpoints.DivideRequest{A: req.A, B: req.B}, nil
+}
+
+// encodeGRPCDivideResponse is a transport/grpc.EncodeResponseFunc that converts a
+// user-domain response to a gRPC reply. Primarily useful in a server.
+func encodeGRPCDivideResponse(_ context.Context, grpcReply interface{}) (res interface{}, err error) {
+	reply := grpcReply.(endpoints.DivideResponse)
+	return &pb.DivideReply{Rs: reply.Rs}, grpcEncodeError(reply.Err)
+}
+
+// decodeGRPCModuloRequest is a transport/grpc.DecodeRequestFunc that converts a
+// gRPC request to a user-domain request. Primarily useful in a server.
+func decodeGRPCModuloRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	req := grpcReq.(*pb.ModuloRequest)
+	return endpoints.ModuloRequest{A: req.A, B: req.B}, nil
+}
+
+// encodeGRPCModuloResponse is a transport/grpc.EncodeResponseFunc that converts a
+// user-domain response to a gRPC reply. Primarily useful in a server.
+func encodeGRPCModuloResponse(_ context.Context, grpcReply interface{}) (res interface{}, err error) {
+	reply := grpcReply.(endpoints.ModuloResponse)
+	return &pb.ModuloReply{Rs: reply.Rs}, grpcEncodeError(reply.Err)
+}
+
+// decodeGRPCSumFloatRequest is a transport/grpc.DecodeRequestFunc that converts a
+// gRPC request to a user-domain request. Primarily useful in a server.
+func decodeGRPCSumFloatRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	req := grpcReq.(*pb.SumFloatRequest)
+	return endpoints.SumFloatRequest{A: endpoints.Float(req.A), B: endpoints.Float(req.B)}, nil
+}
+
+// encodeGRPCSumFloatResponse is a transport/grpc.EncodeResponseFunc that converts a
+// user-domain response to a gRPC reply. Primarily useful in a server.
+func encodeGRPCSumFloatResponse(_ context.Context, grpcReply interface{}) (res interface{}, err error) {
+	reply := grpcReply.(endpoints.SumFloatResponse)
+	return &pb.SumFloatReply{Rs: float64(reply.Rs)}, grpcEncodeError(reply.Err)
+}
+
+// decodeGRPCSubtractFloatRequest is a transport/grpc.DecodeRequestFunc that converts a
+// gRPC request to a user-domain request. Primarily useful in a server.
+func decodeGRPCSubtractFloatRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	req := grpcReq.(*pb.SubtractFloatRequest)
+	return endpoints.SubtractFloatRequest{A: endpoints.Float(req.A), B: endpoints.Float(req.B)}, nil
+}
+
+// encodeGRPCSubtractFloatResponse is a transport/grpc.EncodeResponseFunc that converts a
+// user-domain response to a gRPC reply. Primarily useful in a server.
+func encodeGRPCSubtractFloatResponse(_ context.Context, grpcReply interface{}) (res interface{}, err error) {
+	reply := grpcReply.(endpoints.SubtractFloatResponse)
+	return &pb.SubtractFloatReply{Rs: float64(reply.Rs)}, grpcEncodeError(reply.Err)
+}
+
+// decodeGRPCMultiplyFloatRequest is a transport/grpc.DecodeRequestFunc that converts a
+// gRPC request to a user-domain request. Primarily useful in a server.
+func decodeGRPCMultiplyFloatRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	req := grpcReq.(*pb.MultiplyFloatRequest)
+	return endpoints.MultiplyFloatRequest{A: endpoints.Float(req.A), B: endpoints.Float(req.B)}, nil
+}
+
+// encodeGRPCMultiplyFloatResponse is a transport/grpc.EncodeResponseFunc that converts a
+// user-domain response to a gRPC reply. Primarily useful in a server.
+func encodeGRPCMultiplyFloatResponse(_ context.Context, grpcReply interface{}) (res interface{}, err error) {
+	reply := grpcReply.(endpoints.MultiplyFloatResponse)
+	return &pb.MultiplyFloatReply{Rs: float64(reply.Rs)}, grpcEncodeError(reply.Err)
+}
+
+// decodeGRPCDivideFloatRequest is a transport/grpc.DecodeRequestFunc that converts a
+// gRPC request to a user-domain request. Primarily useful in a server.
+func decodeGRPCDivideFloatRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	req := grpcReq.(*pb.DivideFloatRequest)
+	return endpoints.DivideFloatRequest{A: endpoints.Float(req.A), B: endpoints.Float(req.B)}, nil
+}
+
+// encodeGRPCDivideFloatResponse is a transport/grpc.EncodeResponseFunc that converts a
+// user-domain response to a gRPC reply. Primarily useful in a server.
+func encodeGRPCDivideFloatResponse(_ context.Context, grpcReply interface{}) (res interface{}, err error) {
+	reply := grpcReply.(endpoints.DivideFloatResponse)
+	return &pb.DivideFloatReply{Rs: float64(reply.Rs)}, grpcEncodeError(reply.Err)
+}
+
+// decodeGRPCModuloFloatRequest is a transport/grpc.DecodeRequestFunc that converts a
+// gRPC request to a user-domain request. Primarily useful in a server.
+func decodeGRPCModuloFloatRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	req := grpcReq.(*pb.ModuloFloatRequest)
+	return endpoints.ModuloFloatRequest{A: endpoints.Float(req.A), B: endpoints.Float(req.B)}, nil
+}
+
+// encodeGRPCModuloFloatResponse is a transport/grpc.EncodeResponseFunc that converts a
+// user-domain response to a gRPC reply. Primarily useful in a server.
+func encodeGRPCModuloFloatResponse(_ context.Context, grpcReply interface{}) (res interface{}, err error) {
+	reply := grpcReply.(endpoints.ModuloFloatResponse)
+	return &pb.ModuloFloatReply{Rs: float64(reply.Rs)}, grpcEncodeError(reply.Err)
+}
+
 // NewGRPCClient returns an AddService backed by a gRPC server at the other end
 // of the conn. The caller is responsible for constructing the conn, and
 // eventually closing the underlying transport. We bake-in certain middlewares,
 // implementing the client library pattern.
-func NewGRPCClient(conn *grpc.ClientConn, otTracer stdopentracing.Tracer, zipkinTracer *stdzipkin.Tracer, logger log.Logger) service.AddsvcService { // We construct a single ratelimiter middleware, to limit the total outgoing
-	// QPS from this client to all methods on the remote instance. We also
-	// construct per-endpoint circuitbreaker middlewares to demonstrate how
-	// that's done, although they could easily be combined into a single breaker
-	// for the entire remote instance, too.
-	limiter := ratelimit.NewErroringLimiter(rate.NewLimiter(rate.Every(time.Second), 100))
+func NewGRPCClient(conn *grpc.ClientConn, otTracer stdopentracing.Tracer, zipkinTracer *stdzipkin.Tracer, logger log.Logger, rc resilience.Config) service.AddsvcService { // Every endpoint gets its own rate limiter, circuit breaker and bulkhead,
+	// configured per method by rc. Breakers are named after the remote
+	// instance so that their state can be told apart in logs and metrics.
 
 	// Zipkin GRPC Client Trace can either be instantiated per gRPC method with a
 	// provided operation name or a global tracing client can be instantiated
@@ -131,6 +407,8 @@ func NewGRPCClient(conn *grpc.ClientConn, otTracer stdopentracing.Tracer, zipkin
 
 	// global client middlewares
 	options := []grpctransport.ClientOption{
+		grpctransport.ClientBefore(requestid.ContextToGRPC()),
+		grpctransport.ClientBefore(fault.ContextToGRPC()),
 		zipkinClient,
 	}
 
@@ -148,11 +426,7 @@ func NewGRPCClient(conn *grpc.ClientConn, otTracer stdopentracing.Tracer, zipkin
 			append(options, grpctransport.ClientBefore(opentracing.ContextToGRPC(otTracer, logger)))...,
 		).Endpoint()
 		sumEndpoint = opentracing.TraceClient(otTracer, "Sum")(sumEndpoint)
-		sumEndpoint = limiter(sumEndpoint)
-		sumEndpoint = circuitbreaker.Gobreaker(gobreaker.NewCircuitBreaker(gobreaker.Settings{
-			Name:    "Sum",
-			Timeout: 30 * time.Second,
-		}))(sumEndpoint)
+		sumEndpoint = rc.For("sum").Middleware("addsvc.client.sum@"+conn.Target(), logger)(sumEndpoint)
 	}
 
 	// The Concat endpoint is the same thing, with slightly different
@@ -169,16 +443,156 @@ func NewGRPCClient(conn *grpc.ClientConn, otTracer stdopentracing.Tracer, zipkin
 			append(options, grpctransport.ClientBefore(opentracing.ContextToGRPC(otTracer, logger)))...,
 		).Endpoint()
 		concatEndpoint = opentracing.TraceClient(otTracer, "Concat")(concatEndpoint)
-		concatEndpoint = limiter(concatEndpoint)
-		concatEndpoint = circuitbreaker.Gobreaker(gobreaker.NewCircuitBreaker(gobreaker.Settings{
-			Name:    "Concat",
-			Timeout: 30 * time.Second,
-		}))(concatEndpoint)
+		concatEndpoint = rc.For("concat").Middleware("addsvc.client.concat@"+conn.Target(), logger)(concatEndpoint)
+	}
+
+	var subtractEndpoint endpoint.Endpoint
+	{
+		subtractEndpoint = grpctransport.NewClient(
+			conn,
+			"pb.Addsvc",
+			"Subtract",
+			encodeGRPCSubtractRequest,
+			decodeGRPCSubtractResponse,
+			pb.SubtractReply{},
+			append(options, grpctransport.ClientBefore(opentracing.ContextToGRPC(otTracer, logger)))...,
+		).Endpoint()
+		subtractEndpoint = opentracing.TraceClient(otTracer, "Subtract")(subtractEndpoint)
+		subtractEndpoint = rc.For("subtract").Middleware("addsvc.client.subtract@"+conn.Target(), logger)(subtractEndpoint)
+	}
+
+	var multiplyEndpoint endpoint.Endpoint
+	{
+		multiplyEndpoint = grpctransport.NewClient(
+			conn,
+			"pb.Addsvc",
+			"Multiply",
+			encodeGRPCMultiplyRequest,
+			decodeGRPCMultiplyResponse,
+			pb.MultiplyReply{},
+			append(options, grpctransport.ClientBefore(opentracing.ContextToGRPC(otTracer, logger)))...,
+		).Endpoint()
+		multiplyEndpoint = opentracing.TraceClient(otTracer, "Multiply")(multiplyEndpoint)
+		multiplyEndpoint = rc.For("multiply").Middleware("addsvc.client.multiply@"+conn.Target(), logger)(multiplyEndpoint)
+	}
+
+	var divideEndpoint endpoint.Endpoint
+	{
+		divideEndpoint = grpctransport.NewClient(
+			conn,
+			"pb.Addsvc",
+			"Divide",
+			encodeGRPCDivideRequest,
+			decodeGRPCDivideResponse,
+			pb.DivideReply{},
+			append(options, grpctransport.ClientBefore(opentracing.ContextToGRPC(otTracer, logger)))...,
+		).Endpoint()
+		divideEndpoint = opentracing.TraceClient(otTracer, "Divide")(divideEndpoint)
+		divideEndpoint = rc.For("divide").Middleware("addsvc.client.divide@"+conn.Target(), logger)(divideEndpoint)
+	}
+
+	var moduloEndpoint endpoint.Endpoint
+	{
+		moduloEndpoint = grpctransport.NewClient(
+			conn,
+			"pb.Addsvc",
+			"Modulo",
+			encodeGRPCModuloRequest,
+			decodeGRPCModuloResponse,
+			pb.ModuloReply{},
+			append(options, grpctransport.ClientBefore(opentracing.ContextToGRPC(otTracer, logger)))...,
+		).Endpoint()
+		moduloEndpoint = opentracing.TraceClient(otTracer, "Modulo")(moduloEndpoint)
+		moduloEndpoint = rc.For("modulo").Middleware("addsvc.client.modulo@"+conn.Target(), logger)(moduloEndpoint)
+	}
+
+	var sumfloatEndpoint endpoint.Endpoint
+	{
+		sumfloatEndpoint = grpctransport.NewClient(
+			conn,
+			"pb.Addsvc",
+			"SumFloat",
+			encodeGRPCSumFloatRequest,
+			decodeGRPCSumFloatResponse,
+			pb.SumFloatReply{},
+			append(options, grpctransport.ClientBefore(opentracing.ContextToGRPC(otTracer, logger)))...,
+		).Endpoint()
+		sumfloatEndpoint = opentracing.TraceClient(otTracer, "SumFloat")(sumfloatEndpoint)
+		sumfloatEndpoint = rc.For("sumfloat").Middleware("addsvc.client.sumfloat@"+conn.Target(), logger)(sumfloatEndpoint)
+	}
+
+	var subtractfloatEndpoint endpoint.Endpoint
+	{
+		subtractfloatEndpoint = grpctransport.NewClient(
+			conn,
+			"pb.Addsvc",
+			"SubtractFloat",
+			encodeGRPCSubtractFloatRequest,
+			decodeGRPCSubtractFloatResponse,
+			pb.SubtractFloatReply{},
+			append(options, grpctransport.ClientBefore(opentracing.ContextToGRPC(otTracer, logger)))...,
+		).Endpoint()
+		subtractfloatEndpoint = opentracing.TraceClient(otTracer, "SubtractFloat")(subtractfloatEndpoint)
+		subtractfloatEndpoint = rc.For("subtractfloat").Middleware("addsvc.client.subtractfloat@"+conn.Target(), logger)(subtractfloatEndpoint)
+	}
+
+	var multiplyfloatEndpoint endpoint.Endpoint
+	{
+		multiplyfloatEndpoint = grpctransport.NewClient(
+			conn,
+			"pb.Addsvc",
+			"MultiplyFloat",
+			encodeGRPCMultiplyFloatRequest,
+			decodeGRPCMultiplyFloatResponse,
+			pb.MultiplyFloatReply{},
+			append(options, grpctransport.ClientBefore(opentracing.ContextToGRPC(otTracer, logger)))...,
+		).Endpoint()
+		multiplyfloatEndpoint = opentracing.TraceClient(otTracer, "MultiplyFloat")(multiplyfloatEndpoint)
+		multiplyfloatEndpoint = rc.For("multiplyfloat").Middleware("addsvc.client.multiplyfloat@"+conn.Target(), logger)(multiplyfloatEndpoint)
+	}
+
+	var dividefloatEndpoint endpoint.Endpoint
+	{
+		dividefloatEndpoint = grpctransport.NewClient(
+			conn,
+			"pb.Addsvc",
+			"DivideFloat",
+			encodeGRPCDivideFloatRequest,
+			decodeGRPCDivideFloatResponse,
+			pb.DivideFloatReply{},
+			append(options, grpctransport.ClientBefore(opentracing.ContextToGRPC(otTracer, logger)))...,
+		).Endpoint()
+		dividefloatEndpoint = opentracing.TraceClient(otTracer, "DivideFloat")(dividefloatEndpoint)
+		dividefloatEndpoint = rc.For("dividefloat").Middleware("addsvc.client.dividefloat@"+conn.Target(), logger)(dividefloatEndpoint)
+	}
+
+	var modulofloatEndpoint endpoint.Endpoint
+	{
+		modulofloatEndpoint = grpctransport.NewClient(
+			conn,
+			"pb.Addsvc",
+			"ModuloFloat",
+			encodeGRPCModuloFloatRequest,
+			decodeGRPCModuloFloatResponse,
+			pb.ModuloFloatReply{},
+			append(options, grpctransport.ClientBefore(opentracing.ContextToGRPC(otTracer, logger)))...,
+		).Endpoint()
+		modulofloatEndpoint = opentracing.TraceClient(otTracer, "ModuloFloat")(modulofloatEndpoint)
+		modulofloatEndpoint = rc.For("modulofloat").Middleware("addsvc.client.modulofloat@"+conn.Target(), logger)(modulofloatEndpoint)
 	}
 
 	return endpoints.Endpoints{
-		SumEndpoint:    sumEndpoint,
-		ConcatEndpoint: concatEndpoint,
+		SumEndpoint:           sumEndpoint,
+		ConcatEndpoint:        concatEndpoint,
+		SubtractEndpoint:      subtractEndpoint,
+		MultiplyEndpoint:      multiplyEndpoint,
+		DivideEndpoint:        divideEndpoint,
+		ModuloEndpoint:        moduloEndpoint,
+		SumFloatEndpoint:      sumfloatEndpoint,
+		SubtractFloatEndpoint: subtractfloatEndpoint,
+		MultiplyFloatEndpoint: multiplyfloatEndpoint,
+		DivideFloatEndpoint:   dividefloatEndpoint,
+		ModuloFloatEndpoint:   modulofloatEndpoint,
 	}
 }
 
@@ -210,12 +624,138 @@ func decodeGRPCConcatResponse(_ context.Context, grpcReply interface{}) (interfa
 	return endpoints.ConcatResponse{Rs: reply.Rs}, nil
 }
 
+// encodeGRPCSubtractRequest is a transport/grpc.EncodeRequestFunc that converts a
+// user-domain Subtract request to a gRPC Subtract request. Primarily useful in a client.
+func encodeGRPCSubtractRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(endpoints.SubtractRequest)
+	return &pb.SubtractRequest{A: req.A, B: req.B}, nil
+}
+
+// decodeGRPCSubtractResponse is a transport/grpc.DecodeResponseFunc that converts a
+// gRPC Subtract reply to a user-domain Subtract response. Primarily useful in a client.
+func decodeGRPCSubtractResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	reply := grpcReply.(*pb.SubtractReply)
+	return endpoints.SubtractResponse{Rs: reply.Rs}, nil
+}
+
+// encodeGRPCMultiplyRequest is a transport/grpc.EncodeRequestFunc that converts a
+// user-domain Multiply request to a gRPC Multiply request. Primarily useful in a client.
+func encodeGRPCMultiplyRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(endpoints.MultiplyRequest)
+	return &pb.MultiplyRequest{A: req.A, B: req.B}, nil
+}
+
+// decodeGRPCMultiplyResponse is a transport/grpc.DecodeResponseFunc that converts a
+// gRPC Multiply reply to a user-domain Multiply response. Primarily useful in a client.
+func decodeGRPCMultiplyResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	reply := grpcReply.(*pb.MultiplyReply)
+	return endpoints.MultiplyResponse{Rs: reply.Rs}, nil
+}
+
+// encodeGRPCDivideRequest is a transport/grpc.EncodeRequestFunc that converts a
+// user-domain Divide request to a gRPC Divide request. Primarily useful in a client.
+func encodeGRPCDivideRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(endpoints.DivideRequest)
+	return &pb.DivideRequest{A: req.A, B: req.B}, nil
+}
+
+// decodeGRPCDivideResponse is a transport/grpc.DecodeResponseFunc that converts a
+// gRPC Divide reply to a user-domain Divide response. Primarily useful in a client.
+func decodeGRPCDivideResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	reply := grpcReply.(*pb.DivideReply)
+	return endpoints.DivideResponse{Rs: reply.Rs}, nil
+}
+
+// encodeGRPCModuloRequest is a transport/grpc.EncodeRequestFunc that converts a
+// user-domain Modulo request to a gRPC Modulo request. Primarily useful in a client.
+func encodeGRPCModuloRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(endpoints.ModuloRequest)
+	return &pb.ModuloRequest{A: req.A, B: req.B}, nil
+}
+
+// decodeGRPCModuloResponse is a transport/grpc.DecodeResponseFunc that converts a
+// gRPC Modulo reply to a user-domain Modulo response. Primarily useful in a client.
+func decodeGRPCModuloResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	reply := grpcReply.(*pb.ModuloReply)
+	return endpoints.ModuloResponse{Rs: reply.Rs}, nil
+}
+
+// encodeGRPCSumFloatRequest is a transport/grpc.EncodeRequestFunc that converts a
+// user-domain SumFloat request to a gRPC SumFloat request. Primarily useful in a client.
+func encodeGRPCSumFloatRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(endpoints.SumFloatRequest)
+	return &pb.SumFloatRequest{A: float64(req.A), B: float64(req.B)}, nil
+}
+
+// decodeGRPCSumFloatResponse is a transport/grpc.DecodeResponseFunc that converts a
+// gRPC SumFloat reply to a user-domain SumFloat response. Primarily useful in a client.
+func decodeGRPCSumFloatResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	reply := grpcReply.(*pb.SumFloatReply)
+	return endpoints.SumFloatResponse{Rs: endpoints.Float(reply.Rs)}, nil
+}
+
+// encodeGRPCSubtractFloatRequest is a transport/grpc.EncodeRequestFunc that converts a
+// user-domain SubtractFloat request to a gRPC SubtractFloat request. Primarily useful in a client.
+func encodeGRPCSubtractFloatRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(endpoints.SubtractFloatRequest)
+	return &pb.SubtractFloatRequest{A: float64(req.A), B: float64(req.B)}, nil
+}
+
+// decodeGRPCSubtractFloatResponse is a transport/grpc.DecodeResponseFunc that converts a
+// gRPC SubtractFloat reply to a user-domain SubtractFloat response. Primarily useful in a client.
+func decodeGRPCSubtractFloatResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	reply := grpcReply.(*pb.SubtractFloatReply)
+	return endpoints.SubtractFloatResponse{Rs: endpoints.Float(reply.Rs)}, nil
+}
+
+// encodeGRPCMultiplyFloatRequest is a transport/grpc.EncodeRequestFunc that converts a
+// user-domain MultiplyFloat request to a gRPC MultiplyFloat request. Primarily useful in a client.
+func encodeGRPCMultiplyFloatRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(endpoints.MultiplyFloatRequest)
+	return &pb.MultiplyFloatRequest{A: float64(req.A), B: float64(req.B)}, nil
+}
+
+// decodeGRPCMultiplyFloatResponse is a transport/grpc.DecodeResponseFunc that converts a
+// gRPC MultiplyFloat reply to a user-domain MultiplyFloat response. Primarily useful in a client.
+func decodeGRPCMultiplyFloatResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	reply := grpcReply.(*pb.MultiplyFloatReply)
+	return endpoints.MultiplyFloatResponse{Rs: endpoints.Float(reply.Rs)}, nil
+}
+
+// encodeGRPCDivideFloatRequest is a transport/grpc.EncodeRequestFunc that converts a
+// user-domain DivideFloat request to a gRPC DivideFloat request. Primarily useful in a client.
+func encodeGRPCDivideFloatRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(endpoints.DivideFloatRequest)
+	return &pb.DivideFloatRequest{A: float64(req.A), B: float64(req.B)}, nil
+}
+
+// decodeGRPCDivideFloatResponse is a transport/grpc.DecodeResponseFunc that converts a
+// gRPC DivideFloat reply to a user-domain DivideFloat response. Primarily useful in a client.
+func decodeGRPCDivideFloatResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	reply := grpcReply.(*pb.DivideFloatReply)
+	return endpoints.DivideFloatResponse{Rs: endpoints.Float(reply.Rs)}, nil
+}
+
+// encodeGRPCModuloFloatRequest is a transport/grpc.EncodeRequestFunc that converts a
+// user-domain ModuloFloat request to a gRPC ModuloFloat request. Primarily useful in a client.
+func encodeGRPCModuloFloatRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(endpoints.ModuloFloatRequest)
+	return &pb.ModuloFloatRequest{A: float64(req.A), B: float64(req.B)}, nil
+}
+
+// decodeGRPCModuloFloatResponse is a transport/grpc.DecodeResponseFunc that converts a
+// gRPC ModuloFloat reply to a user-domain ModuloFloat response. Primarily useful in a client.
+func decodeGRPCModuloFloatResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	reply := grpcReply.(*pb.ModuloFloatReply)
+	return endpoints.ModuloFloatResponse{Rs: endpoints.Float(reply.Rs)}, nil
+}
+
 func grpcEncodeError(err error) error {
 	if err == nil {
 		return nil
 	}
 
-	st, ok := status.FromError(err)
+	st, ok := status.FromError(resilience.Status(err))
 	if ok {
 		return status.Error(st.Code(), st.Message())
 	}
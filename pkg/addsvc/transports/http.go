@@ -4,47 +4,54 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
-	"errors"
-	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
 	"strings"
-	"time"
 
-	"github.com/go-kit/kit/circuitbreaker"
 	"github.com/go-kit/kit/endpoint"
 	"github.com/go-kit/kit/log"
-	"github.com/go-kit/kit/ratelimit"
 	"github.com/go-kit/kit/sd/lb"
 	"github.com/go-kit/kit/tracing/opentracing"
 	"github.com/go-kit/kit/tracing/zipkin"
 	httptransport "github.com/go-kit/kit/transport/http"
 	stdopentracing "github.com/opentracing/opentracing-go"
 	stdzipkin "github.com/openzipkin/zipkin-go"
-	"github.com/sony/gobreaker"
-	"golang.org/x/time/rate"
 	"google.golang.org/grpc/status"
 
+	pb "github.com/cage1016/gokitconsulk8s/pb/addsvc"
 	"github.com/cage1016/gokitconsulk8s/pkg/addsvc/endpoints"
 	"github.com/cage1016/gokitconsulk8s/pkg/addsvc/service"
+	"github.com/cage1016/gokitconsulk8s/pkg/fault"
+	"github.com/cage1016/gokitconsulk8s/pkg/openapi"
+	"github.com/cage1016/gokitconsulk8s/pkg/requestid"
+	"github.com/cage1016/gokitconsulk8s/pkg/resilience"
+	"github.com/cage1016/gokitconsulk8s/pkg/transport/httpstatus"
+	"github.com/cage1016/gokitconsulk8s/pkg/transport/negotiation"
 )
 
 type errorWrapper struct {
 	Error string `json:"error"`
 }
 
+// JSONErrorDecoder decodes the error of a failed response as a gRPC status
+// whose code is derived from the response status. A full bulkhead is decoded
+// as resilience.ErrBulkheadFull.
 func JSONErrorDecoder(r *http.Response) error {
+	code := httpstatus.Code(r.StatusCode)
 	contentType := r.Header.Get("Content-Type")
 	if !strings.Contains(contentType, "application/json") {
-		return fmt.Errorf("expected JSON formatted error, got Content-Type %s", contentType)
+		return status.Errorf(code, "expected JSON formatted error, got Content-Type %s", contentType)
 	}
 	var w errorWrapper
 	if err := json.NewDecoder(r.Body).Decode(&w); err != nil {
-		return err
+		return status.Error(code, err.Error())
+	}
+	if r.StatusCode == http.StatusServiceUnavailable && w.Error == status.Convert(resilience.ErrBulkheadFull).Message() {
+		return resilience.ErrBulkheadFull
 	}
-	return errors.New(w.Error)
+	return status.Error(code, w.Error)
 }
 
 // NewHTTPHandler returns a handler that makes a set of endpoints available on
@@ -59,46 +66,450 @@ func NewHTTPHandler(endpoints endpoints.Endpoints, otTracer stdopentracing.Trace
 	options := []httptransport.ServerOption{
 		httptransport.ServerErrorEncoder(httpEncodeError),
 		httptransport.ServerErrorLogger(logger),
+		httptransport.ServerBefore(requestid.HTTPToContext()),
+		httptransport.ServerBefore(fault.HTTPToContext()),
 		zipkinServer,
 	}
 
 	m := http.NewServeMux()
-	m.Handle("/sum", httptransport.NewServer(
+	m.Handle("/sum", negotiation.Handler(httptransport.NewServer(
 		endpoints.SumEndpoint,
 		decodeHTTPSumRequest,
-		httptransport.EncodeJSONResponse,
+		encodeHTTPSumResponse,
 		append(options, httptransport.ServerBefore(opentracing.HTTPToContext(otTracer, "Sum", logger)))...,
-	))
-	m.Handle("/concat", httptransport.NewServer(
+	), httpEncodeError))
+	m.Handle("/concat", negotiation.Handler(httptransport.NewServer(
 		endpoints.ConcatEndpoint,
 		decodeHTTPConcatRequest,
-		httptransport.EncodeJSONResponse,
+		encodeHTTPConcatResponse,
 		append(options, httptransport.ServerBefore(opentracing.HTTPToContext(otTracer, "Concat", logger)))...,
-	))
+	), httpEncodeError))
+	m.Handle("/subtract", negotiation.Handler(httptransport.NewServer(
+		endpoints.SubtractEndpoint,
+		decodeHTTPSubtractRequest,
+		encodeHTTPSubtractResponse,
+		append(options, httptransport.ServerBefore(opentracing.HTTPToContext(otTracer, "Subtract", logger)))...,
+	), httpEncodeError))
+	m.Handle("/multiply", negotiation.Handler(httptransport.NewServer(
+		endpoints.MultiplyEndpoint,
+		decodeHTTPMultiplyRequest,
+		encodeHTTPMultiplyResponse,
+		append(options, httptransport.ServerBefore(opentracing.HTTPToContext(otTracer, "Multiply", logger)))...,
+	), httpEncodeError))
+	m.Handle("/divide", negotiation.Handler(httptransport.NewServer(
+		endpoints.DivideEndpoint,
+		decodeHTTPDivideRequest,
+		encodeHTTPDivideResponse,
+		append(options, httptransport.ServerBefore(opentracing.HTTPToContext(otTracer, "Divide", logger)))...,
+	), httpEncodeError))
+	m.Handle("/modulo", negotiation.Handler(httptransport.NewServer(
+		endpoints.ModuloEndpoint,
+		decodeHTTPModuloRequest,
+		encodeHTTPModuloResponse,
+		append(options, httptransport.ServerBefore(opentracing.HTTPToContext(otTracer, "Modulo", logger)))...,
+	), httpEncodeError))
+	m.Handle("/sumfloat", negotiation.Handler(httptransport.NewServer(
+		endpoints.SumFloatEndpoint,
+		decodeHTTPSumFloatRequest,
+		encodeHTTPSumFloatResponse,
+		append(options, httptransport.ServerBefore(opentracing.HTTPToContext(otTracer, "SumFloat", logger)))...,
+	), httpEncodeError))
+	m.Handle("/subtractfloat", negotiation.Handler(httptransport.NewServer(
+		endpoints.SubtractFloatEndpoint,
+		decodeHTTPSubtractFloatRequest,
+		encodeHTTPSubtractFloatResponse,
+		append(options, httptransport.ServerBefore(opentracing.HTTPToContext(otTracer, "SubtractFloat", logger)))...,
+	), httpEncodeError))
+	m.Handle("/multiplyfloat", negotiation.Handler(httptransport.NewServer(
+		endpoints.MultiplyFloatEndpoint,
+		decodeHTTPMultiplyFloatRequest,
+		encodeHTTPMultiplyFloatResponse,
+		append(options, httptransport.ServerBefore(opentracing.HTTPToContext(otTracer, "MultiplyFloat", logger)))...,
+	), httpEncodeError))
+	m.Handle("/dividefloat", negotiation.Handler(httptransport.NewServer(
+		endpoints.DivideFloatEndpoint,
+		decodeHTTPDivideFloatRequest,
+		encodeHTTPDivideFloatResponse,
+		append(options, httptransport.ServerBefore(opentracing.HTTPToContext(otTracer, "DivideFloat", logger)))...,
+	), httpEncodeError))
+	m.Handle("/modulofloat", negotiation.Handler(httptransport.NewServer(
+		endpoints.ModuloFloatEndpoint,
+		decodeHTTPModuloFloatRequest,
+		encodeHTTPModuloFloatResponse,
+		append(options, httptransport.ServerBefore(opentracing.HTTPToContext(otTracer, "ModuloFloat", logger)))...,
+	), httpEncodeError))
+
+	m.Handle(openapi.Path, OpenAPI())
 	return m
 }
 
+// httpOperations describes the routes of NewHTTPHandler in its OpenAPI
+// document.
+var httpOperations = []openapi.Operation{
+	{
+		Path:          "/sum",
+		ID:            "Sum",
+		Summary:       "Sums two integers.",
+		Request:       endpoints.SumRequest{},
+		Response:      endpoints.SumResponse{},
+		ProtoRequest:  &pb.SumRequest{},
+		ProtoResponse: &pb.SumReply{},
+	},
+	{
+		Path:          "/concat",
+		ID:            "Concat",
+		Summary:       "Concatenates two strings.",
+		Request:       endpoints.ConcatRequest{},
+		Response:      endpoints.ConcatResponse{},
+		ProtoRequest:  &pb.ConcatRequest{},
+		ProtoResponse: &pb.ConcatReply{},
+	},
+	{
+		Path:          "/subtract",
+		ID:            "Subtract",
+		Summary:       "Subtracts two integers, b from a.",
+		Request:       endpoints.SubtractRequest{},
+		Response:      endpoints.SubtractResponse{},
+		ProtoRequest:  &pb.SubtractRequest{},
+		ProtoResponse: &pb.SubtractReply{},
+	},
+	{
+		Path:          "/multiply",
+		ID:            "Multiply",
+		Summary:       "Multiplies two integers.",
+		Request:       endpoints.MultiplyRequest{},
+		Response:      endpoints.MultiplyResponse{},
+		ProtoRequest:  &pb.MultiplyRequest{},
+		ProtoResponse: &pb.MultiplyReply{},
+	},
+	{
+		Path:          "/divide",
+		ID:            "Divide",
+		Summary:       "Divides two integers, a by b, truncating towards zero.",
+		Request:       endpoints.DivideRequest{},
+		Response:      endpoints.DivideResponse{},
+		ProtoRequest:  &pb.DivideRequest{},
+		ProtoResponse: &pb.DivideReply{},
+	},
+	{
+		Path:          "/modulo",
+		ID:            "Modulo",
+		Summary:       "Takes the remainder of the division of two integers, a by b, which has the sign of a.",
+		Request:       endpoints.ModuloRequest{},
+		Response:      endpoints.ModuloResponse{},
+		ProtoRequest:  &pb.ModuloRequest{},
+		ProtoResponse: &pb.ModuloReply{},
+	},
+	{
+		Path:          "/sumfloat",
+		ID:            "SumFloat",
+		Summary:       "Sums two floats.",
+		Request:       endpoints.SumFloatRequest{},
+		Response:      endpoints.SumFloatResponse{},
+		ProtoRequest:  &pb.SumFloatRequest{},
+		ProtoResponse: &pb.SumFloatReply{},
+	},
+	{
+		Path:          "/subtractfloat",
+		ID:            "SubtractFloat",
+		Summary:       "Subtracts two floats, b from a.",
+		Request:       endpoints.SubtractFloatRequest{},
+		Response:      endpoints.SubtractFloatResponse{},
+		ProtoRequest:  &pb.SubtractFloatRequest{},
+		ProtoResponse: &pb.SubtractFloatReply{},
+	},
+	{
+		Path:          "/multiplyfloat",
+		ID:            "MultiplyFloat",
+		Summary:       "Multiplies two floats.",
+		Request:       endpoints.MultiplyFloatRequest{},
+		Response:      endpoints.MultiplyFloatResponse{},
+		ProtoRequest:  &pb.MultiplyFloatRequest{},
+		ProtoResponse: &pb.MultiplyFloatReply{},
+	},
+	{
+		Path:          "/dividefloat",
+		ID:            "DivideFloat",
+		Summary:       "Divides two floats, a by b.",
+		Request:       endpoints.DivideFloatRequest{},
+		Response:      endpoints.DivideFloatResponse{},
+		ProtoRequest:  &pb.DivideFloatRequest{},
+		ProtoResponse: &pb.DivideFloatReply{},
+	},
+	{
+		Path:          "/modulofloat",
+		ID:            "ModuloFloat",
+		Summary:       "Takes the remainder of the division of two floats, a by b, which has the sign of a.",
+		Request:       endpoints.ModuloFloatRequest{},
+		Response:      endpoints.ModuloFloatResponse{},
+		ProtoRequest:  &pb.ModuloFloatRequest{},
+		ProtoResponse: &pb.ModuloFloatReply{},
+	},
+}
+
+// OpenAPI returns the OpenAPI document of the HTTP API of addsvc.
+func OpenAPI() *openapi.Document {
+	return openapi.New("addsvc", "v1", httpOperations)
+}
+
 // decodeHTTPSumRequest is a transport/http.DecodeRequestFunc that decodes a
-// JSON-encoded request from the HTTP request body. Primarily useful in a server.
-func decodeHTTPSumRequest(_ context.Context, r *http.Request) (interface{}, error) {
+// Sum request from the query string or the JSON, protobuf or form body of
+// the HTTP request. Primarily useful in a server.
+func decodeHTTPSumRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	if negotiation.IsProtobuf(r) {
+		var msg pb.SumRequest
+		if err := negotiation.DecodeProtobuf(r, &msg); err != nil {
+			return nil, err
+		}
+		return decodeGRPCSumRequest(ctx, &msg)
+	}
 	var req endpoints.SumRequest
-	err := json.NewDecoder(r.Body).Decode(&req)
+	err := negotiation.DecodeBody(r, &req)
 	return req, err
 }
 
+// encodeHTTPSumResponse is a transport/http.EncodeResponseFunc that encodes
+// a Sum response as JSON or protobuf, as negotiated with the client.
+// Primarily useful in a server.
+func encodeHTTPSumResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	return negotiation.EncodeResponse(ctx, w, response, encodeGRPCSumResponse)
+}
+
 // decodeHTTPConcatRequest is a transport/http.DecodeRequestFunc that decodes a
-// JSON-encoded request from the HTTP request body. Primarily useful in a server.
-func decodeHTTPConcatRequest(_ context.Context, r *http.Request) (interface{}, error) {
+// Concat request from the query string or the JSON, protobuf or form body of
+// the HTTP request. Primarily useful in a server.
+func decodeHTTPConcatRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	if negotiation.IsProtobuf(r) {
+		var msg pb.ConcatRequest
+		if err := negotiation.DecodeProtobuf(r, &msg); err != nil {
+			return nil, err
+		}
+		return decodeGRPCConcatRequest(ctx, &msg)
+	}
 	var req endpoints.ConcatRequest
-	err := json.NewDecoder(r.Body).Decode(&req)
+	err := negotiation.DecodeBody(r, &req)
+	return req, err
+}
+
+// encodeHTTPConcatResponse is a transport/http.EncodeResponseFunc that encodes
+// a Concat response as JSON or protobuf, as negotiated with the client.
+// Primarily useful in a server.
+func encodeHTTPConcatResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	return negotiation.EncodeResponse(ctx, w, response, encodeGRPCConcatResponse)
+}
+
+// decodeHTTPSubtractRequest is a transport/http.DecodeRequestFunc that decodes a
+// Subtract request from the query string or the JSON, protobuf or form body of
+// the HTTP request. Primarily useful in a server.
+func decodeHTTPSubtractRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	if negotiation.IsProtobuf(r) {
+		var msg pb.SubtractRequest
+		if err := negotiation.DecodeProtobuf(r, &msg); err != nil {
+			return nil, err
+		}
+		return decodeGRPCSubtractRequest(ctx, &msg)
+	}
+	var req endpoints.SubtractRequest
+	err := negotiation.DecodeBody(r, &req)
+	return req, err
+}
+
+// encodeHTTPSubtractResponse is a transport/http.EncodeResponseFunc that encodes
+// a Subtract response as JSON or protobuf, as negotiated with the client.
+// Primarily useful in a server.
+func encodeHTTPSubtractResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	return negotiation.EncodeResponse(ctx, w, response, encodeGRPCSubtractResponse)
+}
+
+// decodeHTTPMultiplyRequest is a transport/http.DecodeRequestFunc that decodes a
+// Multiply request from the query string or the JSON, protobuf or form body of
+// the HTTP request. Primarily useful in a server.
+func decodeHTTPMultiplyRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	if negotiation.IsProtobuf(r) {
+		var msg pb.MultiplyRequest
+		if err := negotiation.DecodeProtobuf(r, &msg); err != nil {
+			return nil, err
+		}
+		return decodeGRPCMultiplyRequest(ctx, &msg)
+	}
+	var req endpoints.MultiplyRequest
+	err := negotiation.DecodeBody(r, &req)
+	return req, err
+}
+
+// encodeHTTPMultiplyResponse is a transport/http.EncodeResponseFunc that encodes
+// a Multiply response as JSON or protobuf, as negotiated with the client.
+// Primarily useful in a server.
+func encodeHTTPMultiplyResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	return negotiation.EncodeResponse(ctx, w, response, encodeGRPCMultiplyResponse)
+}
+
+// decodeHTTPDivideRequest is a transport/http.DecodeRequestFunc that decodes a
+// Divide request from the query string or the JSON, protobuf or form body of
+// the HTTP request. Primarily useful in a server.
+func decodeHTTPDivideRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	if negotiation.IsProtobuf(r) {
+		var msg pb.DivideRequest
+		if err := negotiation.DecodeProtobuf(r, &msg); err != nil {
+			return nil, err
+		}
+		return decodeGRPCDivideRequest(ctx, &msg)
+	}
+	var req endpoints.DivideRequest
+	err := negotiation.DecodeBody(r, &req)
+	return req, err
+}
+
+// encodeHTTPDivideResponse is a transport/http.EncodeResponseFunc that encodes
+// a Divide response as JSON or protobuf, as negotiated with the client.
+// Primarily useful in a server.
+func encodeHTTPDivideResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	return negotiation.EncodeResponse(ctx, w, response, encodeGRPCDivideResponse)
+}
+
+// decodeHTTPModuloRequest is a transport/http.DecodeRequestFunc that decodes a
+// Modulo request from the query string or the JSON, protobuf or form body of
+// the HTTP request. Primarily useful in a server.
+func decodeHTTPModuloRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	if negotiation.IsProtobuf(r) {
+		var msg pb.ModuloRequest
+		if err := negotiation.DecodeProtobuf(r, &msg); err != nil {
+			return nil, err
+		}
+		return decodeGRPCModuloRequest(ctx, &msg)
+	}
+	var req endpoints.ModuloRequest
+	err := negotiation.DecodeBody(r, &req)
+	return req, err
+}
+
+// encodeHTTPModuloResponse is a transport/http.EncodeResponseFunc that encodes
+// a Modulo response as JSON or protobuf, as negotiated with the client.
+// Primarily useful in a server.
+func encodeHTTPModuloResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	return negotiation.EncodeResponse(ctx, w, response, encodeGRPCModuloResponse)
+}
+
+// decodeHTTPSumFloatRequest is a transport/http.DecodeRequestFunc that decodes a
+// SumFloat request from the query string or the JSON, protobuf or form body of
+// the HTTP request. Primarily useful in a server.
+func decodeHTTPSumFloatRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	if negotiation.IsProtobuf(r) {
+		var msg pb.SumFloatRequest
+		if err := negotiation.DecodeProtobuf(r, &msg); err != nil {
+			return nil, err
+		}
+		return decodeGRPCSumFloatRequest(ctx, &msg)
+	}
+	var req endpoints.SumFloatRequest
+	err := negotiation.DecodeBody(r, &req)
 	return req, err
 }
 
+// encodeHTTPSumFloatResponse is a transport/http.EncodeResponseFunc that encodes
+// a SumFloat response as JSON or protobuf, as negotiated with the client.
+// Primarily useful in a server.
+func encodeHTTPSumFloatResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	return negotiation.EncodeResponse(ctx, w, response, encodeGRPCSumFloatResponse)
+}
+
+// decodeHTTPSubtractFloatRequest is a transport/http.DecodeRequestFunc that decodes a
+// SubtractFloat request from the query string or the JSON, protobuf or form body of
+// the HTTP request. Primarily useful in a server.
+func decodeHTTPSubtractFloatRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	if negotiation.IsProtobuf(r) {
+		var msg pb.SubtractFloatRequest
+		if err := negotiation.DecodeProtobuf(r, &msg); err != nil {
+			return nil, err
+		}
+		return decodeGRPCSubtractFloatRequest(ctx, &msg)
+	}
+	var req endpoints.SubtractFloatRequest
+	err := negotiation.DecodeBody(r, &req)
+	return req, err
+}
+
+// encodeHTTPSubtractFloatResponse is a transport/http.EncodeResponseFunc that encodes
+// a SubtractFloat response as JSON or protobuf, as negotiated with the client.
+// Primarily useful in a server.
+func encodeHTTPSubtractFloatResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	return negotiation.EncodeResponse(ctx, w, response, encodeGRPCSubtractFloatResponse)
+}
+
+// decodeHTTPMultiplyFloatRequest is a transport/http.DecodeRequestFunc that decodes a
+// MultiplyFloat request from the query string or the JSON, protobuf or form body of
+// the HTTP request. Primarily useful in a server.
+func decodeHTTPMultiplyFloatRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	if negotiation.IsProtobuf(r) {
+		var msg pb.MultiplyFloatRequest
+		if err := negotiation.DecodeProtobuf(r, &msg); err != nil {
+			return nil, err
+		}
+		return decodeGRPCMultiplyFloatRequest(ctx, &msg)
+	}
+	var req endpoints.MultiplyFloatRequest
+	err := negotiation.DecodeBody(r, &req)
+	return req, err
+}
+
+// encodeHTTPMultiplyFloatResponse is a transport/http.EncodeResponseFunc that encodes
+// a MultiplyFloat response as JSON or protobuf, as negotiated with the client.
+// Primarily useful in a server.
+func encodeHTTPMultiplyFloatResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	return negotiation.EncodeResponse(ctx, w, response, encodeGRPCMultiplyFloatResponse)
+}
+
+// decodeHTTPDivideFloatRequest is a transport/http.DecodeRequestFunc that decodes a
+// DivideFloat request from the query string or the JSON, protobuf or form body of
+// the HTTP request. Primarily useful in a server.
+func decodeHTTPDivideFloatRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	if negotiation.IsProtobuf(r) {
+		var msg pb.DivideFloatRequest
+		if err := negotiation.DecodeProtobuf(r, &msg); err != nil {
+			return nil, err
+		}
+		return decodeGRPCDivideFloatRequest(ctx, &msg)
+	}
+	var req endpoints.DivideFloatRequest
+	err := negotiation.DecodeBody(r, &req)
+	return req, err
+}
+
+// encodeHTTPDivideFloatResponse is a transport/http.EncodeResponseFunc that encodes
+// a DivideFloat response as JSON or protobuf, as negotiated with the client.
+// Primarily useful in a server.
+func encodeHTTPDivideFloatResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	return negotiation.EncodeResponse(ctx, w, response, encodeGRPCDivideFloatResponse)
+}
+
+// decodeHTTPModuloFloatRequest is a transport/http.DecodeRequestFunc that decodes a
+// ModuloFloat request from the query string or the JSON, protobuf or form body of
+// the HTTP request. Primarily useful in a server.
+func decodeHTTPModuloFloatRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	if negotiation.IsProtobuf(r) {
+		var msg pb.ModuloFloatRequest
+		if err := negotiation.DecodeProtobuf(r, &msg); err != nil {
+			return nil, err
+		}
+		return decodeGRPCModuloFloatRequest(ctx, &msg)
+	}
+	var req endpoints.ModuloFloatRequest
+	err := negotiation.DecodeBody(r, &req)
+	return req, err
+}
+
+// encodeHTTPModuloFloatResponse is a transport/http.EncodeResponseFunc that encodes
+// a ModuloFloat response as JSON or protobuf, as negotiated with the client.
+// Primarily useful in a server.
+func encodeHTTPModuloFloatResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	return negotiation.EncodeResponse(ctx, w, response, encodeGRPCModuloFloatResponse)
+}
+
 // NewHTTPClient returns an AddService backed by an HTTP server living at the
 // remote instance. We expect instance to come from a service discovery system,
 // so likely of the form "host:port". We bake-in certain middlewares,
 // implementing the client library pattern.
-func NewHTTPClient(instance string, otTracer stdopentracing.Tracer, zipkinTracer *stdzipkin.Tracer, logger log.Logger) (service.AddsvcService, error) { // Quickly sanitize the instance string.
+func NewHTTPClient(instance string, otTracer stdopentracing.Tracer, zipkinTracer *stdzipkin.Tracer, logger log.Logger, rc resilience.Config) (service.AddsvcService, error) { // Quickly sanitize the instance string.
 	if !strings.HasPrefix(instance, "http") {
 		instance = "http://" + instance
 	}
@@ -107,12 +518,9 @@ func NewHTTPClient(instance string, otTracer stdopentracing.Tracer, zipkinTracer
 		return nil, err
 	}
 
-	// We construct a single ratelimiter middleware, to limit the total outgoing
-	// QPS from this client to all methods on the remote instance. We also
-	// construct per-endpoint circuitbreaker middlewares to demonstrate how
-	// that's done, although they could easily be combined into a single breaker
-	// for the entire remote instance, too.
-	limiter := ratelimit.NewErroringLimiter(rate.NewLimiter(rate.Every(time.Second), 100))
+	// Every endpoint gets its own rate limiter, circuit breaker and bulkhead,
+	// configured per method by rc. Breakers are named after the remote
+	// instance so that their state can be told apart in logs and metrics.
 
 	// Zipkin HTTP Client Trace can either be instantiated per endpoint with a
 	// provided operation name or a global tracing client can be instantiated
@@ -122,6 +530,8 @@ func NewHTTPClient(instance string, otTracer stdopentracing.Tracer, zipkinTracer
 
 	// global client middlewares
 	options := []httptransport.ClientOption{
+		httptransport.ClientBefore(requestid.ContextToHTTP()),
+		httptransport.ClientBefore(fault.ContextToHTTP()),
 		zipkinClient,
 	}
 
@@ -138,17 +548,13 @@ func NewHTTPClient(instance string, otTracer stdopentracing.Tracer, zipkinTracer
 		sumEndpoint = httptransport.NewClient(
 			"POST",
 			copyURL(u, "/sum"),
-			encodeHTTPSumRequest,
+			encodeHTTPRequest,
 			decodeHTTPSumResponse,
 			append(options, httptransport.ClientBefore(opentracing.ContextToHTTP(otTracer, logger)))...,
 		).Endpoint()
 		sumEndpoint = opentracing.TraceClient(otTracer, "Sum")(sumEndpoint)
 		sumEndpoint = zipkin.TraceEndpoint(zipkinTracer, "Sum")(sumEndpoint)
-		sumEndpoint = limiter(sumEndpoint)
-		sumEndpoint = circuitbreaker.Gobreaker(gobreaker.NewCircuitBreaker(gobreaker.Settings{
-			Name:    "Sum",
-			Timeout: 30 * time.Second,
-		}))(sumEndpoint)
+		sumEndpoint = rc.For("sum").Middleware("addsvc.client.sum@"+u.Host, logger)(sumEndpoint)
 		e.SumEndpoint = sumEndpoint
 	}
 
@@ -159,40 +565,173 @@ func NewHTTPClient(instance string, otTracer stdopentracing.Tracer, zipkinTracer
 		concatEndpoint = httptransport.NewClient(
 			"POST",
 			copyURL(u, "/concat"),
-			encodeHTTPConcatRequest,
+			encodeHTTPRequest,
 			decodeHTTPConcatResponse,
 			append(options, httptransport.ClientBefore(opentracing.ContextToHTTP(otTracer, logger)))...,
 		).Endpoint()
 		concatEndpoint = opentracing.TraceClient(otTracer, "Concat")(concatEndpoint)
 		concatEndpoint = zipkin.TraceEndpoint(zipkinTracer, "Concat")(concatEndpoint)
-		concatEndpoint = limiter(concatEndpoint)
-		concatEndpoint = circuitbreaker.Gobreaker(gobreaker.NewCircuitBreaker(gobreaker.Settings{
-			Name:    "Concat",
-			Timeout: 30 * time.Second,
-		}))(concatEndpoint)
+		concatEndpoint = rc.For("concat").Middleware("addsvc.client.concat@"+u.Host, logger)(concatEndpoint)
 		e.ConcatEndpoint = concatEndpoint
 	}
 
+	var subtractEndpoint endpoint.Endpoint
+	{
+		subtractEndpoint = httptransport.NewClient(
+			"POST",
+			copyURL(u, "/subtract"),
+			encodeHTTPRequest,
+			decodeHTTPSubtractResponse,
+			append(options, httptransport.ClientBefore(opentracing.ContextToHTTP(otTracer, logger)))...,
+		).Endpoint()
+		subtractEndpoint = opentracing.TraceClient(otTracer, "Subtract")(subtractEndpoint)
+		subtractEndpoint = zipkin.TraceEndpoint(zipkinTracer, "Subtract")(subtractEndpoint)
+		subtractEndpoint = rc.For("subtract").Middleware("addsvc.client.subtract@"+u.Host, logger)(subtractEndpoint)
+		e.SubtractEndpoint = subtractEndpoint
+	}
+
+	var multiplyEndpoint endpoint.Endpoint
+	{
+		multiplyEndpoint = httptransport.NewClient(
+			"POST",
+			copyURL(u, "/multiply"),
+			encodeHTTPRequest,
+			decodeHTTPMultiplyResponse,
+			append(options, httptransport.ClientBefore(opentracing.ContextToHTTP(otTracer, logger)))...,
+		).Endpoint()
+		multiplyEndpoint = opentracing.TraceClient(otTracer, "Multiply")(multiplyEndpoint)
+		multiplyEndpoint = zipkin.TraceEndpoint(zipkinTracer, "Multiply")(multiplyEndpoint)
+		multiplyEndpoint = rc.For("multiply").Middleware("addsvc.client.multiply@"+u.Host, logger)(multiplyEndpoint)
+		e.MultiplyEndpoint = multiplyEndpoint
+	}
+
+	var divideEndpoint endpoint.Endpoint
+	{
+		divideEndpoint = httptransport.NewClient(
+			"POST",
+			copyURL(u, "/divide"),
+			encodeHTTPRequest,
+			decodeHTTPDivideResponse,
+			append(options, httptransport.ClientBefore(opentracing.ContextToHTTP(otTracer, logger)))...,
+		).Endpoint()
+		divideEndpoint = opentracing.TraceClient(otTracer, "Divide")(divideEndpoint)
+		divideEndpoint = zipkin.TraceEndpoint(zipkinTracer, "Divide")(divideEndpoint)
+		divideEndpoint = rc.For("divide").Middleware("addsvc.client.divide@"+u.Host, logger)(divideEndpoint)
+		e.DivideEndpoint = divideEndpoint
+	}
+
+	var moduloEndpoint endpoint.Endpoint
+	{
+		moduloEndpoint = httptransport.NewClient(
+			"POST",
+			copyURL(u, "/modulo"),
+			encodeHTTPRequest,
+			decodeHTTPModuloResponse,
+			append(options, httptransport.ClientBefore(opentracing.ContextToHTTP(otTracer, logger)))...,
+		).Endpoint()
+		moduloEndpoint = opentracing.TraceClient(otTracer, "Modulo")(moduloEndpoint)
+		moduloEndpoint = zipkin.TraceEndpoint(zipkinTracer, "Modulo")(moduloEndpoint)
+		moduloEndpoint = rc.For("modulo").Middleware("addsvc.client.modulo@"+u.Host, logger)(moduloEndpoint)
+		e.ModuloEndpoint = moduloEndpoint
+	}
+
+	var sumfloatEndpoint endpoint.Endpoint
+	{
+		sumfloatEndpoint = httptransport.NewClient(
+			"POST",
+			copyURL(u, "/sumfloat"),
+			encodeHTTPRequest,
+			decodeHTTPSumFloatResponse,
+			append(options, httptransport.ClientBefore(opentracing.ContextToHTTP(otTracer, logger)))...,
+		).Endpoint()
+		sumfloatEndpoint = opentracing.TraceClient(otTracer, "SumFloat")(sumfloatEndpoint)
+		sumfloatEndpoint = zipkin.TraceEndpoint(zipkinTracer, "SumFloat")(sumfloatEndpoint)
+		sumfloatEndpoint = rc.For("sumfloat").Middleware("addsvc.client.sumfloat@"+u.Host, logger)(sumfloatEndpoint)
+		e.SumFloatEndpoint = sumfloatEndpoint
+	}
+
+	var subtractfloatEndpoint endpoint.Endpoint
+	{
+		subtractfloatEndpoint = httptransport.NewClient(
+			"POST",
+			copyURL(u, "/subtractfloat"),
+			encodeHTTPRequest,
+			decodeHTTPSubtractFloatResponse,
+			append(options, httptransport.ClientBefore(opentracing.ContextToHTTP(otTracer, logger)))...,
+		).Endpoint()
+		subtractfloatEndpoint = opentracing.TraceClient(otTracer, "SubtractFloat")(subtractfloatEndpoint)
+		subtractfloatEndpoint = zipkin.TraceEndpoint(zipkinTracer, "SubtractFloat")(subtractfloatEndpoint)
+		subtractfloatEndpoint = rc.For("subtractfloat").Middleware("addsvc.client.subtractfloat@"+u.Host, logger)(subtractfloatEndpoint)
+		e.SubtractFloatEndpoint = subtractfloatEndpoint
+	}
+
+	var multiplyfloatEndpoint endpoint.Endpoint
+	{
+		multiplyfloatEndpoint = httptransport.NewClient(
+			"POST",
+			copyURL(u, "/multiplyfloat"),
+			encodeHTTPRequest,
+			decodeHTTPMultiplyFloatResponse,
+			append(options, httptransport.ClientBefore(opentracing.ContextToHTTP(otTracer, logger)))...,
+		).Endpoint()
+		multiplyfloatEndpoint = opentracing.TraceClient(otTracer, "MultiplyFloat")(multiplyfloatEndpoint)
+		multiplyfloatEndpoint = zipkin.TraceEndpoint(zipkinTracer, "MultiplyFloat")(multiplyfloatEndpoint)
+		multiplyfloatEndpoint = rc.For("multiplyfloat").Middleware("addsvc.client.multiplyfloat@"+u.Host, logger)(multiplyfloatEndpoint)
+		e.MultiplyFloatEndpoint = multiplyfloatEndpoint
+	}
+
+	var dividefloatEndpoint endpoint.Endpoint
+	{
+		dividefloatEndpoint = httptransport.NewClient(
+			"POST",
+			copyURL(u, "/dividefloat"),
+			encodeHTTPRequest,
+			decodeHTTPDivideFloatResponse,
+			append(options, httptransport.ClientBefore(opentracing.ContextToHTTP(otTracer, logger)))...,
+		).Endpoint()
+		dividefloatEndpoint = opentracing.TraceClient(otTracer, "DivideFloat")(dividefloatEndpoint)
+		dividefloatEndpoint = zipkin.TraceEndpoint(zipkinTracer, "DivideFloat")(dividefloatEndpoint)
+		dividefloatEndpoint = rc.For("dividefloat").Middleware("addsvc.client.dividefloat@"+u.Host, logger)(dividefloatEndpoint)
+		e.DivideFloatEndpoint = dividefloatEndpoint
+	}
+
+	var modulofloatEndpoint endpoint.Endpoint
+	{
+		modulofloatEndpoint = httptransport.NewClient(
+			"POST",
+			copyURL(u, "/modulofloat"),
+			encodeHTTPRequest,
+			decodeHTTPModuloFloatResponse,
+			append(options, httptransport.ClientBefore(opentracing.ContextToHTTP(otTracer, logger)))...,
+		).Endpoint()
+		modulofloatEndpoint = opentracing.TraceClient(otTracer, "ModuloFloat")(modulofloatEndpoint)
+		modulofloatEndpoint = zipkin.TraceEndpoint(zipkinTracer, "ModuloFloat")(modulofloatEndpoint)
+		modulofloatEndpoint = rc.For("modulofloat").Middleware("addsvc.client.modulofloat@"+u.Host, logger)(modulofloatEndpoint)
+		e.ModuloFloatEndpoint = modulofloatEndpoint
+	}
+
 	// Returning the endpoint.Set as a service.Service relies on the
 	// endpoint.Set implementing the Service methods. That's just a simple bit
 	// of glue code.
 	return e, nil
 }
 
-//
+// copyURL returns base with path appended to its own path, so that a client
+// can reach the service behind a path prefix such as the router's.
 func copyURL(base *url.URL, path string) *url.URL {
 	next := *base
-	next.Path = path
+	next.Path = strings.TrimSuffix(base.Path, "/") + path
 	return &next
 }
 
-// encodeHTTPSumRequest is a transport/http.EncodeRequestFunc that
+// encodeHTTPRequest is a transport/http.EncodeRequestFunc that
 // JSON-encodes any request to the request body. Primarily useful in a client.
-func encodeHTTPSumRequest(_ context.Context, r *http.Request, request interface{}) (err error) {
+func encodeHTTPRequest(_ context.Context, r *http.Request, request interface{}) (err error) {
 	var buf bytes.Buffer
 	if err := json.NewEncoder(&buf).Encode(request); err != nil {
 		return err
 	}
+	r.Header.Set("Content-Type", negotiation.JSONContentType)
 	r.Body = ioutil.NopCloser(&buf)
 	return nil
 }
@@ -210,17 +749,6 @@ func decodeHTTPSumResponse(_ context.Context, r *http.Response) (interface{}, er
 	return resp, err
 }
 
-// encodeHTTPConcatRequest is a transport/http.EncodeRequestFunc that
-// JSON-encodes any request to the request body. Primarily useful in a client.
-func encodeHTTPConcatRequest(_ context.Context, r *http.Request, request interface{}) (err error) {
-	var buf bytes.Buffer
-	if err := json.NewEncoder(&buf).Encode(request); err != nil {
-		return err
-	}
-	r.Body = ioutil.NopCloser(&buf)
-	return nil
-}
-
 // decodeHTTPConcatResponse is a transport/http.DecodeResponseFunc that decodes a
 // JSON-encoded sum response from the HTTP response body. If the response has a
 // non-200 status code, we will interpret that as an error and attempt to decode
@@ -234,17 +762,140 @@ func decodeHTTPConcatResponse(_ context.Context, r *http.Response) (interface{},
 	return resp, err
 }
 
+// decodeHTTPSubtractResponse is a transport/http.DecodeResponseFunc that decodes a
+// JSON-encoded subtract response from the HTTP response body. If the response has a
+// non-200 status code, we will interpret that as an error and attempt to decode
+// the specific error message from the response body. Primarily useful in a client.
+func decodeHTTPSubtractResponse(_ context.Context, r *http.Response) (interface{}, error) {
+	if r.StatusCode != http.StatusOK {
+		return nil, JSONErrorDecoder(r)
+	}
+	var resp endpoints.SubtractResponse
+	err := json.NewDecoder(r.Body).Decode(&resp)
+	return resp, err
+}
+
+// decodeHTTPMultiplyResponse is a transport/http.DecodeResponseFunc that decodes a
+// JSON-encoded multiply response from the HTTP response body. If the response has a
+// non-200 status code, we will interpret that as an error and attempt to decode
+// the specific error message from the response body. Primarily useful in a client.
+func decodeHTTPMultiplyResponse(_ context.Context, r *http.Response) (interface{}, error) {
+	if r.StatusCode != http.StatusOK {
+		return nil, JSONErrorDecoder(r)
+	}
+	var resp endpoints.MultiplyResponse
+	err := json.NewDecoder(r.Body).Decode(&resp)
+	return resp, err
+}
+
+// decodeHTTPDivideResponse is a transport/http.DecodeResponseFunc that decodes a
+// JSON-encoded divide response from the HTTP response body. If the response has a
+// non-200 status code, we will interpret that as an error and attempt to decode
+// the specific error message from the response body. Primarily useful in a client.
+func decodeHTTPDivideResponse(_ context.Context, r *http.Response) (interface{}, error) {
+	if r.StatusCode != http.StatusOK {
+		return nil, JSONErrorDecoder(r)
+	}
+	var resp endpoints.DivideResponse
+	err := json.NewDecoder(r.Body).Decode(&resp)
+	return resp, err
+}
+
+// decodeHTTPModuloResponse is a transport/http.DecodeResponseFunc that decodes a
+// JSON-encoded modulo response from the HTTP response body. If the response has a
+// non-200 status code, we will interpret that as an error and attempt to decode
+// the specific error message from the response body. Primarily useful in a client.
+func decodeHTTPModuloResponse(_ context.Context, r *http.Response) (interface{}, error) {
+	if r.StatusCode != http.StatusOK {
+		return nil, JSONErrorDecoder(r)
+	}
+	var resp endpoints.ModuloResponse
+	err := json.NewDecoder(r.Body).Decode(&resp)
+	return resp, err
+}
+
+// decodeHTTPSumFloatResponse is a transport/http.DecodeResponseFunc that decodes a
+// JSON-encoded sumfloat response from the HTTP response body. If the response has a
+// non-200 status code, we will interpret that as an error and attempt to decode
+// the specific error message from the response body. Primarily useful in a client.
+func decodeHTTPSumFloatResponse(_ context.Context, r *http.Response) (interface{}, error) {
+	if r.StatusCode != http.StatusOK {
+		return nil, JSONErrorDecoder(r)
+	}
+	var resp endpoints.SumFloatResponse
+	err := json.NewDecoder(r.Body).Decode(&resp)
+	return resp, err
+}
+
+// decodeHTTPSubtractFloatResponse is a transport/http.DecodeResponseFunc that decodes a
+// JSON-encoded subtractfloat response from the HTTP response body. If the response has a
+// non-200 status code, we will interpret that as an error and attempt to decode
+// the specific error message from the response body. Primarily useful in a client.
+func decodeHTTPSubtractFloatResponse(_ context.Context, r *http.Response) (interface{}, error) {
+	if r.StatusCode != http.StatusOK {
+		return nil, JSONErrorDecoder(r)
+	}
+	var resp endpoints.SubtractFloatResponse
+	err := json.NewDecoder(r.Body).Decode(&resp)
+	return resp, err
+}
+
+// decodeHTTPMultiplyFloatResponse is a transport/http.DecodeResponseFunc that decodes a
+// JSON-encoded multiplyfloat response from the HTTP response body. If the response has a
+// non-200 status code, we will interpret that as an error and attempt to decode
+// the specific error message from the response body. Primarily useful in a client.
+func decodeHTTPMultiplyFloatResponse(_ context.Context, r *http.Response) (interface{}, error) {
+	if r.StatusCode != http.StatusOK {
+		return nil, JSONErrorDecoder(r)
+	}
+	var resp endpoints.MultiplyFloatResponse
+	err := json.NewDecoder(r.Body).Decode(&resp)
+	return resp, err
+}
+
+// decodeHTTPDivideFloatResponse is a transport/http.DecodeResponseFunc that decodes a
+// JSON-encoded dividefloat response from the HTTP response body. If the response has a
+// non-200 status code, we will interpret that as an error and attempt to decode
+// the specific error message from the response body. Primarily useful in a client.
+func decodeHTTPDivideFloatResponse(_ context.Context, r *http.Response) (interface{}, error) {
+	if r.StatusCode != http.StatusOK {
+		return nil, JSONErrorDecoder(r)
+	}
+	var resp endpoints.DivideFloatResponse
+	err := json.NewDecoder(r.Body).Decode(&resp)
+	return resp, err
+}
+
+// decodeHTTPModuloFloatResponse is a transport/http.DecodeResponseFunc that decodes a
+// JSON-encoded modulofloat response from the HTTP response body. If the response has a
+// non-200 status code, we will interpret that as an error and attempt to decode
+// the specific error message from the response body. Primarily useful in a client.
+func decodeHTTPModuloFloatResponse(_ context.Context, r *http.Response) (interface{}, error) {
+	if r.StatusCode != http.StatusOK {
+		return nil, JSONErrorDecoder(r)
+	}
+	var resp endpoints.ModuloFloatResponse
+	err := json.NewDecoder(r.Body).Decode(&resp)
+	return resp, err
+}
+
 func httpEncodeError(_ context.Context, err error, w http.ResponseWriter) {
 	w.Header().Set("Content-Type", "application/json")
 
-	if lberr, ok := err.(lb.RetryError); ok {
+	if herr, ok := err.(negotiation.Error); ok {
+		w.WriteHeader(herr.Status)
+		json.NewEncoder(w).Encode(errorWrapper{Error: herr.Msg})
+	} else if resilience.BulkheadFull(err) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(errorWrapper{Error: status.Convert(resilience.ErrBulkheadFull).Message()})
+	} else if lberr, ok := err.(lb.RetryError); ok {
 		st, _ := status.FromError(lberr.Final)
-		w.WriteHeader(HTTPStatusFromCode(st.Code()))
+		w.WriteHeader(httpstatus.FromCode(st.Code()))
 		json.NewEncoder(w).Encode(errorWrapper{Error: st.Message()})
 	} else {
-		st, ok := status.FromError(err)
+		st, ok := status.FromError(resilience.Status(err))
 		if ok {
-			w.WriteHeader(HTTPStatusFromCode(st.Code()))
+			w.WriteHeader(httpstatus.FromCode(st.Code()))
 			json.NewEncoder(w).Encode(errorWrapper{Error: st.Message()})
 		} else {
 			switch err {
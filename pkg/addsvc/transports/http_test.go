@@ -0,0 +1,64 @@
+package transports
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	stdopentracing "github.com/opentracing/opentracing-go"
+	stdzipkin "github.com/openzipkin/zipkin-go"
+
+	"github.com/cage1016/gokitconsulk8s/pkg/addsvc/endpoints"
+	"github.com/cage1016/gokitconsulk8s/pkg/addsvc/service"
+	"github.com/cage1016/gokitconsulk8s/pkg/debuglog"
+	"github.com/cage1016/gokitconsulk8s/pkg/fault"
+	"github.com/cage1016/gokitconsulk8s/pkg/openapi/openapitest"
+	"github.com/cage1016/gokitconsulk8s/pkg/resilience"
+)
+
+func TestHTTPHandlerMatchesOpenAPI(t *testing.T) {
+	logger := log.NewNopLogger()
+	zipkinTracer, err := stdzipkin.NewTracer(nil, stdzipkin.WithNoopTracer(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fi, err := fault.NewInjector(fault.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracer := stdopentracing.NoopTracer{}
+	eps := endpoints.New(service.New(logger), logger, tracer, zipkinTracer, resilience.Config{}, debuglog.NewRegistry(debuglog.Config{}), fi)
+	handler := NewHTTPHandler(eps, tracer, zipkinTracer, logger)
+
+	get, post := http.MethodGet, http.MethodPost
+	q := func(a, b string) url.Values { return url.Values{"a": {a}, "b": {b}} }
+	openapitest.Run(t, handler, OpenAPI(), []openapitest.Case{
+		{Name: "sum query", Method: get, Path: "/sum", Query: q("1", "2"), Want: `{"rs":3,"err":null}`},
+		{Name: "sum", Method: post, Path: "/sum", Body: `{"a":1,"b":2}`, Want: `{"rs":3,"err":null}`},
+		{Name: "sum overflow", Method: post, Path: "/sum", Body: `{"a":9223372036854775807,"b":1}`, Status: http.StatusBadRequest},
+		{Name: "concat query", Method: get, Path: "/concat", Query: q("3", "4"), Want: `{"rs":"34","err":null}`},
+		{Name: "concat", Method: post, Path: "/concat", Body: `{"a":"x","b":"y"}`, Want: `{"rs":"xy","err":null}`},
+		{Name: "subtract query", Method: get, Path: "/subtract", Query: q("1", "2"), Want: `{"rs":-1,"err":null}`},
+		{Name: "subtract", Method: post, Path: "/subtract", Body: `{"a":5,"b":3}`, Want: `{"rs":2,"err":null}`},
+		{Name: "multiply query", Method: get, Path: "/multiply", Query: q("-3", "4"), Want: `{"rs":-12,"err":null}`},
+		{Name: "multiply", Method: post, Path: "/multiply", Body: `{"a":6,"b":7}`, Want: `{"rs":42,"err":null}`},
+		{Name: "divide query", Method: get, Path: "/divide", Query: q("-7", "2"), Want: `{"rs":-3,"err":null}`},
+		{Name: "divide", Method: post, Path: "/divide", Body: `{"a":7,"b":2}`, Want: `{"rs":3,"err":null}`},
+		{Name: "divide by zero", Method: post, Path: "/divide", Body: `{"a":7,"b":0}`, Status: http.StatusBadRequest, Want: `{"error":"division by zero"}`},
+		{Name: "modulo query", Method: get, Path: "/modulo", Query: q("-7", "3"), Want: `{"rs":-1,"err":null}`},
+		{Name: "modulo", Method: post, Path: "/modulo", Body: `{"a":7,"b":3}`, Want: `{"rs":1,"err":null}`},
+		{Name: "sumfloat query", Method: get, Path: "/sumfloat", Query: q("1.5", "Infinity"), Want: `{"rs":"Infinity","err":null}`},
+		{Name: "sumfloat", Method: post, Path: "/sumfloat", Body: `{"a":1.5,"b":"2.5"}`, Want: `{"rs":4,"err":null}`},
+		{Name: "sumfloat nan", Method: post, Path: "/sumfloat", Body: `{"a":"Infinity","b":"-Infinity"}`, Want: `{"rs":"NaN","err":null}`},
+		{Name: "subtractfloat query", Method: get, Path: "/subtractfloat", Query: q("1", "0.5"), Want: `{"rs":0.5,"err":null}`},
+		{Name: "subtractfloat", Method: post, Path: "/subtractfloat", Body: `{"a":"NaN","b":1}`, Want: `{"rs":"NaN","err":null}`},
+		{Name: "multiplyfloat query", Method: get, Path: "/multiplyfloat", Query: q("2", "0.25"), Want: `{"rs":0.5,"err":null}`},
+		{Name: "multiplyfloat", Method: post, Path: "/multiplyfloat", Body: `{"a":"-Infinity","b":2}`, Want: `{"rs":"-Infinity","err":null}`},
+		{Name: "multiplyfloat overflow", Method: post, Path: "/multiplyfloat", Body: `{"a":1e308,"b":10}`, Status: http.StatusBadRequest},
+		{Name: "dividefloat query", Method: get, Path: "/dividefloat", Query: q("1", "4"), Want: `{"rs":0.25,"err":null}`},
+		{Name: "dividefloat", Method: post, Path: "/dividefloat", Body: `{"a":1,"b":0}`, Status: http.StatusBadRequest},
+		{Name: "modulofloat query", Method: get, Path: "/modulofloat", Query: q("-7.5", "2"), Want: `{"rs":-1.5,"err":null}`},
+		{Name: "modulofloat", Method: post, Path: "/modulofloat", Body: `{"a":7.5,"b":2}`, Want: `{"rs":1.5,"err":null}`},
+	})
+}
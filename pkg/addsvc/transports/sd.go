@@ -0,0 +1,58 @@
+package transports
+
+import (
+	"io"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/sd"
+	"github.com/go-kit/kit/sd/lb"
+	stdopentracing "github.com/opentracing/opentracing-go"
+	stdzipkin "github.com/openzipkin/zipkin-go"
+	"google.golang.org/grpc"
+
+	"github.com/cage1016/gokitconsulk8s/pkg/addsvc/endpoints"
+	"github.com/cage1016/gokitconsulk8s/pkg/addsvc/service"
+	"github.com/cage1016/gokitconsulk8s/pkg/resilience"
+)
+
+// NewLoadBalancedGRPCClient returns an AddsvcService that spreads calls over
+// every addsvc instance published by instancer. Each instance gets its own
+// gRPC client, and so its own circuit breakers; failed calls are retried on
+// other instances up to retryMax times within retryTimeout, unless they are
+// caller errors such as division by zero. Instances are dialed with options.
+func NewLoadBalancedGRPCClient(instancer sd.Instancer, balancer func(sd.Endpointer) lb.Balancer, retryMax int, retryTimeout time.Duration, otTracer stdopentracing.Tracer, zipkinTracer *stdzipkin.Tracer, logger log.Logger, rc resilience.Config, options ...grpc.DialOption) service.AddsvcService {
+	retry := func(pick func(endpoints.Endpoints) endpoint.Endpoint) endpoint.Endpoint {
+		endpointer := sd.NewEndpointer(instancer, grpcFactory(pick, otTracer, zipkinTracer, logger, rc, options), logger)
+		return lb.RetryWithCallback(retryTimeout, balancer(endpointer), func(n int, err error) (bool, error) {
+			return n < retryMax && !resilience.CallerError(err), nil
+		})
+	}
+	return endpoints.Endpoints{
+		SumEndpoint:           retry(func(eps endpoints.Endpoints) endpoint.Endpoint { return eps.SumEndpoint }),
+		ConcatEndpoint:        retry(func(eps endpoints.Endpoints) endpoint.Endpoint { return eps.ConcatEndpoint }),
+		SubtractEndpoint:      retry(func(eps endpoints.Endpoints) endpoint.Endpoint { return eps.SubtractEndpoint }),
+		MultiplyEndpoint:      retry(func(eps endpoints.Endpoints) endpoint.Endpoint { return eps.MultiplyEndpoint }),
+		DivideEndpoint:        retry(func(eps endpoints.Endpoints) endpoint.Endpoint { return eps.DivideEndpoint }),
+		ModuloEndpoint:        retry(func(eps endpoints.Endpoints) endpoint.Endpoint { return eps.ModuloEndpoint }),
+		SumFloatEndpoint:      retry(func(eps endpoints.Endpoints) endpoint.Endpoint { return eps.SumFloatEndpoint }),
+		SubtractFloatEndpoint: retry(func(eps endpoints.Endpoints) endpoint.Endpoint { return eps.SubtractFloatEndpoint }),
+		MultiplyFloatEndpoint: retry(func(eps endpoints.Endpoints) endpoint.Endpoint { return eps.MultiplyFloatEndpoint }),
+		DivideFloatEndpoint:   retry(func(eps endpoints.Endpoints) endpoint.Endpoint { return eps.DivideFloatEndpoint }),
+		ModuloFloatEndpoint:   retry(func(eps endpoints.Endpoints) endpoint.Endpoint { return eps.ModuloFloatEndpoint }),
+	}
+}
+
+// grpcFactory returns an sd.Factory that dials an addsvc instance and picks a
+// single method's client endpoint out of NewGRPCClient.
+func grpcFactory(pick func(endpoints.Endpoints) endpoint.Endpoint, otTracer stdopentracing.Tracer, zipkinTracer *stdzipkin.Tracer, logger log.Logger, rc resilience.Config, options []grpc.DialOption) sd.Factory {
+	return func(instance string) (endpoint.Endpoint, io.Closer, error) {
+		conn, err := grpc.Dial(instance, append([]grpc.DialOption{grpc.WithInsecure()}, options...)...)
+		if err != nil {
+			return nil, nil, err
+		}
+		svc := NewGRPCClient(conn, otTracer, zipkinTracer, log.With(logger, "instance", instance), rc)
+		return pick(svc.(endpoints.Endpoints)), conn, nil
+	}
+}
@@ -0,0 +1,77 @@
+// Package admin serves the diagnostics of a service on a listener of its own,
+// kept apart from the API: profiles, expvars, build information and the log
+// level, which can be changed at runtime.
+package admin
+
+import (
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"strings"
+)
+
+// GitSHA and BuildTags describe the build. The makefile sets them with
+// -ldflags "-X github.com/cage1016/gokitconsulk8s/pkg/admin.GitSHA=...".
+var (
+	GitSHA    = "unknown"
+	BuildTags = ""
+)
+
+// BuildInfo is the reply of the /version endpoint.
+type BuildInfo struct {
+	Service   string   `json:"service"`
+	GitSHA    string   `json:"git_sha"`
+	BuildTags []string `json:"build_tags"`
+	GoVersion string   `json:"go_version"`
+}
+
+// Build returns the build information of service.
+func Build(service string) BuildInfo {
+	tags := strings.FieldsFunc(BuildTags, func(r rune) bool { return r == ',' || r == ' ' })
+	if tags == nil {
+		tags = []string{}
+	}
+	return BuildInfo{
+		Service:   service,
+		GitSHA:    GitSHA,
+		BuildTags: tags,
+		GoVersion: runtime.Version(),
+	}
+}
+
+// NewHandler returns the admin handler of service, whose log level is set by
+// levels. It serves:
+//
+//	/debug/pprof/  the profiles of net/http/pprof
+//	/debug/vars    the variables of expvar
+//	/version       the BuildInfo of the binary
+//	/log/level     the log level, changed with a PUT of {"level":"debug"}
+//
+// More handlers, such as the one of debuglog, may be added to the returned
+// mux.
+func NewHandler(service string, levels *LevelFilter) *http.ServeMux {
+	m := http.NewServeMux()
+	m.HandleFunc("/debug/pprof/", pprof.Index)
+	m.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	m.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	m.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	m.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	m.Handle("/debug/vars", expvar.Handler())
+
+	info := Build(service)
+	m.HandleFunc("/version", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		json.NewEncoder(w).Encode(info)
+	})
+	m.Handle("/log/level", levels)
+	return m
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(struct {
+		Error string `json:"error"`
+	}{err.Error()})
+}
@@ -0,0 +1,104 @@
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+// levels lists the log levels, least severe first.
+var levels = []struct {
+	name  string
+	value level.Value
+}{
+	{"debug", level.DebugValue()},
+	{"info", level.InfoValue()},
+	{"warn", level.WarnValue()},
+	{"error", level.ErrorValue()},
+}
+
+// LevelFilter is a log.Logger dropping the lines below a level that can be
+// changed at runtime. Lines without a level are always logged.
+//
+// Put it below the loggers adding a caller, so that log.DefaultCaller still
+// reports the caller of Log.
+type LevelFilter struct {
+	next log.Logger
+	min  int32
+}
+
+// NewLevelFilter returns a LevelFilter logging to next the lines at or above
+// lvl: debug, info, warn or error.
+func NewLevelFilter(next log.Logger, lvl string) (*LevelFilter, error) {
+	f := &LevelFilter{next: next}
+	if err := f.SetLevel(lvl); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Log implements log.Logger.
+func (f *LevelFilter) Log(keyvals ...interface{}) error {
+	min := int(atomic.LoadInt32(&f.min))
+	for i := 1; i < len(keyvals); i += 2 {
+		v, ok := keyvals[i].(level.Value)
+		if !ok {
+			continue
+		}
+		for rank, l := range levels {
+			if l.value == v && rank < min {
+				return nil
+			}
+		}
+		break
+	}
+	return f.next.Log(keyvals...)
+}
+
+// Level returns the least severe level logged.
+func (f *LevelFilter) Level() string {
+	return levels[atomic.LoadInt32(&f.min)].name
+}
+
+// SetLevel sets the least severe level logged.
+func (f *LevelFilter) SetLevel(lvl string) error {
+	for rank, l := range levels {
+		if l.name == lvl {
+			atomic.StoreInt32(&f.min, int32(rank))
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown log level %q, want debug, info, warn or error", lvl)
+}
+
+// ServeHTTP replies to GET with the level, as {"level":"info"}, and to PUT
+// with such an object by setting the level.
+func (f *LevelFilter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var req struct {
+			Level string `json:"level"`
+		}
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<10)).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		if err := f.SetLevel(req.Level); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	default:
+		w.Header().Set("Allow", http.MethodGet+", "+http.MethodPut)
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+	json.NewEncoder(w).Encode(struct {
+		Level string `json:"level"`
+	}{f.Level()})
+}
@@ -0,0 +1,80 @@
+package admin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+func TestLevelFilterLog(t *testing.T) {
+	for _, c := range []struct {
+		min    string
+		logged []string
+	}{
+		{"debug", []string{"debug", "info", "warn", "error", "none"}},
+		{"info", []string{"info", "warn", "error", "none"}},
+		{"warn", []string{"warn", "error", "none"}},
+		{"error", []string{"error", "none"}},
+	} {
+		var lines []string
+		next := log.LoggerFunc(func(keyvals ...interface{}) error {
+			lines = append(lines, keyvals[len(keyvals)-1].(string))
+			return nil
+		})
+		f, err := NewLevelFilter(next, c.min)
+		if err != nil {
+			t.Fatal(err)
+		}
+		logger := log.With(f, "caller", "x")
+		level.Debug(logger).Log("msg", "debug")
+		level.Info(logger).Log("msg", "info")
+		level.Warn(logger).Log("msg", "warn")
+		level.Error(logger).Log("msg", "error")
+		logger.Log("msg", "none")
+		if got, want := strings.Join(lines, ","), strings.Join(c.logged, ","); got != want {
+			t.Errorf("at %s: logged %s, want %s", c.min, got, want)
+		}
+	}
+}
+
+func TestNewLevelFilterUnknownLevel(t *testing.T) {
+	if _, err := NewLevelFilter(log.NewNopLogger(), "INFO"); err == nil {
+		t.Error("NewLevelFilter accepted INFO")
+	}
+}
+
+func TestLevelFilterServeHTTP(t *testing.T) {
+	f, err := NewLevelFilter(log.NewNopLogger(), "info")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		method string
+		body   string
+		status int
+		level  string
+	}{
+		{http.MethodGet, "", http.StatusOK, "info"},
+		{http.MethodPut, `{"level":"debug"}`, http.StatusOK, "debug"},
+		{http.MethodPut, `{"level":"trace"}`, http.StatusBadRequest, "debug"},
+		{http.MethodPut, `{"level":`, http.StatusBadRequest, "debug"},
+		{http.MethodPut, `{"level":"error"}`, http.StatusOK, "error"},
+		{http.MethodPost, `{"level":"warn"}`, http.StatusMethodNotAllowed, "error"},
+	} {
+		w := httptest.NewRecorder()
+		f.ServeHTTP(w, httptest.NewRequest(c.method, "/log/level", strings.NewReader(c.body)))
+		if w.Code != c.status {
+			t.Errorf("%s %s: status %d, want %d", c.method, c.body, w.Code, c.status)
+		}
+		if w.Code == http.StatusOK && strings.TrimSpace(w.Body.String()) != `{"level":"`+c.level+`"}` {
+			t.Errorf("%s %s: reply %s", c.method, c.body, w.Body)
+		}
+		if f.Level() != c.level {
+			t.Errorf("%s %s: level %s, want %s", c.method, c.body, f.Level(), c.level)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+// Package compression compresses the responses of HTTP handlers and the
+// messages of gRPC calls with gzip or zstd.
+//
+// Importing it registers both compressors with gRPC, so that gRPC servers
+// accept calls compressed with either and answer them with the compressor of
+// the call. Clients pick the compressor of their calls with DialOption.
+package compression
+
+import (
+	"compress/gzip"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// The encodings, and gRPC compressors, supported.
+const (
+	Gzip = "gzip"
+	Zstd = "zstd"
+)
+
+// Negotiate returns the first of encodings, in order of preference, that the
+// Accept-Encoding header accept allows, or an empty string when it allows
+// none. The weights of accept only tell allowed encodings, with a weight above
+// 0, from the others.
+func Negotiate(accept string, encodings []string) string {
+	weights := map[string]float64{}
+	for _, e := range strings.Split(accept, ",") {
+		name, q := e, 1.
+		if i := strings.Index(e, ";"); i >= 0 {
+			name = e[:i]
+			if p := strings.TrimSpace(e[i+1:]); strings.HasPrefix(p, "q=") {
+				if f, err := strconv.ParseFloat(p[2:], 64); err == nil {
+					q = f
+				}
+			}
+		}
+		if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+			weights[name] = q
+		}
+	}
+	for _, e := range encodings {
+		q, ok := weights[e]
+		if !ok {
+			q, ok = weights["*"]
+		}
+		if ok && q > 0 {
+			return e
+		}
+	}
+	return ""
+}
+
+// encoder is implemented by the writers of gzip and zstd streams.
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// encoders pools the encoders of each encoding, which are costly to allocate.
+var encoders = map[string]*sync.Pool{
+	Gzip: {New: func() interface{} {
+		return gzip.NewWriter(nil)
+	}},
+	Zstd: {New: func() interface{} {
+		e, _ := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+		return e
+	}},
+}
+
+// newEncoder returns a pooled encoder of encoding writing to w. Closing it
+// returns it to the pool.
+func newEncoder(encoding string, w io.Writer) encoder {
+	pool := encoders[encoding]
+	e := pool.Get().(encoder)
+	e.Reset(w)
+	return &pooledEncoder{encoder: e, pool: pool}
+}
+
+type pooledEncoder struct {
+	encoder
+	pool *sync.Pool
+}
+
+func (e *pooledEncoder) Close() error {
+	err := e.encoder.Close()
+	e.encoder.Reset(nil)
+	e.pool.Put(e.encoder)
+	return err
+}
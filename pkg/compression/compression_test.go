@@ -0,0 +1,33 @@
+package compression
+
+import "testing"
+
+func TestNegotiate(t *testing.T) {
+	both := []string{Zstd, Gzip}
+	for _, c := range []struct {
+		accept    string
+		encodings []string
+		want      string
+	}{
+		{"", both, ""},
+		{"gzip", both, Gzip},
+		{"gzip, zstd", both, Zstd},
+		{"zstd, gzip", []string{Gzip, Zstd}, Gzip},
+		{"GZIP", both, Gzip},
+		{"gzip;q=0.1, zstd;q=0.9", []string{Gzip, Zstd}, Gzip},
+		{"gzip;q=0, zstd", []string{Gzip, Zstd}, Zstd},
+		{"gzip; q=0", both, ""},
+		{"gzip;q=bad", both, Gzip},
+		{"*", both, Zstd},
+		{"*;q=0, gzip", both, Gzip},
+		{"zstd;q=0, *", both, Gzip},
+		{"br, deflate", both, ""},
+		{"identity", both, ""},
+		{"gzip, zstd", nil, ""},
+		{" , gzip ,", both, Gzip},
+	} {
+		if got := Negotiate(c.accept, c.encodings); got != c.want {
+			t.Errorf("Negotiate(%q, %v) = %q, want %q", c.accept, c.encodings, got, c.want)
+		}
+	}
+}
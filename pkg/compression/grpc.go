@@ -0,0 +1,73 @@
+package compression
+
+import (
+	"io"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding"
+	// Registers the gzip compressor.
+	_ "google.golang.org/grpc/encoding/gzip"
+)
+
+func init() {
+	encoding.RegisterCompressor(zstdCompressor{})
+}
+
+// DialOption returns the dial option making a gRPC client compress its calls
+// with the compressor name, gzip or zstd, which servers then answer with. An
+// empty name leaves calls uncompressed.
+func DialOption(name string) grpc.DialOption {
+	if name == "" {
+		return grpc.EmptyDialOption{}
+	}
+	return grpc.WithDefaultCallOptions(grpc.UseCompressor(name))
+}
+
+// maxDecodedBytes bounds the memory a zstd frame may ask for, so that small
+// messages cannot exhaust the memory of the process.
+const maxDecodedBytes = 64 << 20
+
+var decoders = sync.Pool{New: func() interface{} {
+	d, _ := zstd.NewReader(nil, zstd.WithDecoderConcurrency(1), zstd.WithDecoderMaxMemory(maxDecodedBytes))
+	return d
+}}
+
+// zstdCompressor is the gRPC compressor of zstd.
+type zstdCompressor struct{}
+
+func (zstdCompressor) Compress(w io.Writer) (io.WriteCloser, error) {
+	return newEncoder(Zstd, w), nil
+}
+
+func (zstdCompressor) Decompress(r io.Reader) (io.Reader, error) {
+	d := decoders.Get().(*zstd.Decoder)
+	if err := d.Reset(r); err != nil {
+		decoders.Put(d)
+		return nil, err
+	}
+	return &zstdReader{d: d}, nil
+}
+
+func (zstdCompressor) Name() string {
+	return Zstd
+}
+
+// zstdReader returns its decoder to the pool once the message is read.
+type zstdReader struct {
+	d *zstd.Decoder
+}
+
+func (r *zstdReader) Read(p []byte) (int, error) {
+	if r.d == nil {
+		return 0, io.EOF
+	}
+	n, err := r.d.Read(p)
+	if err == io.EOF {
+		r.d.Reset(nil)
+		decoders.Put(r.d)
+		r.d = nil
+	}
+	return n, err
+}
@@ -0,0 +1,153 @@
+package compression
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Config configures Handler.
+type Config struct {
+	// Encodings lists the encodings responses may be compressed with, gzip
+	// and zstd, in order of preference. Empty disables compression.
+	Encodings []string
+	// MinSize is the size below which responses are sent as they are.
+	MinSize int
+}
+
+// Handler returns next compressing its responses with the first of
+// cfg.Encodings the Accept-Encoding header of the request allows. Responses
+// are held back until cfg.MinSize bytes are written, or the handler flushes
+// or returns, to tell whether they are worth compressing. Responses already
+// encoded, empty or of compressed media such as images are sent as they are.
+// It returns next as it is when cfg has no encoding.
+func Handler(next http.Handler, cfg Config) http.Handler {
+	if len(cfg.Encodings) == 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+		encoding := Negotiate(strings.Join(r.Header["Accept-Encoding"], ","), cfg.Encodings)
+		if encoding == "" || r.Method == http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+		cw := &compressWriter{ResponseWriter: w, encoding: encoding, minSize: cfg.MinSize}
+		defer cw.close()
+		next.ServeHTTP(cw, r)
+	})
+}
+
+// compressWriter buffers the start of a response until it knows whether to
+// compress it.
+type compressWriter struct {
+	http.ResponseWriter
+	encoding string
+	minSize  int
+	// code is the status of the response, 0 until the handler sets it.
+	code    int
+	buf     []byte
+	started bool
+	// e compresses the body once started, nil when it is sent as it is.
+	e encoder
+}
+
+func (cw *compressWriter) WriteHeader(code int) {
+	if cw.code == 0 {
+		cw.code = code
+	}
+}
+
+func (cw *compressWriter) Write(p []byte) (int, error) {
+	if !cw.started {
+		if cw.code == 0 {
+			cw.code = http.StatusOK
+		}
+		if len(cw.buf)+len(p) < cw.minSize {
+			cw.buf = append(cw.buf, p...)
+			return len(p), nil
+		}
+		if err := cw.start(true); err != nil {
+			return 0, err
+		}
+	}
+	if cw.e != nil {
+		return cw.e.Write(p)
+	}
+	return cw.ResponseWriter.Write(p)
+}
+
+// Flush sends what was written so far, which is compressed only when the
+// response reached the minimum size before.
+func (cw *compressWriter) Flush() {
+	if !cw.started {
+		if cw.code == 0 {
+			cw.code = http.StatusOK
+		}
+		cw.start(false)
+	}
+	if cw.e != nil {
+		cw.e.Flush()
+	}
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// start sends the headers of the response and its buffered body, compressed
+// when compress is set and the response may be.
+func (cw *compressWriter) start(compress bool) error {
+	cw.started = true
+	if compress && cw.compressible() {
+		h := cw.Header()
+		if h.Get("Content-Type") == "" {
+			// Sniff the content type before the body is compressed.
+			h.Set("Content-Type", http.DetectContentType(cw.buf))
+		}
+		h.Set("Content-Encoding", cw.encoding)
+		h.Del("Content-Length")
+		cw.e = newEncoder(cw.encoding, cw.ResponseWriter)
+	}
+	cw.ResponseWriter.WriteHeader(cw.code)
+	if len(cw.buf) == 0 {
+		return nil
+	}
+	buf := cw.buf
+	cw.buf = nil
+	var err error
+	if cw.e != nil {
+		_, err = cw.e.Write(buf)
+	} else {
+		_, err = cw.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+// compressible reports whether the response is worth compressing.
+func (cw *compressWriter) compressible() bool {
+	h := cw.Header()
+	if h.Get("Content-Encoding") != "" || cw.code < http.StatusOK || cw.code == http.StatusNoContent || cw.code == http.StatusNotModified {
+		return false
+	}
+	mt := strings.ToLower(strings.TrimSpace(strings.Split(h.Get("Content-Type"), ";")[0]))
+	switch {
+	case mt == "image/svg+xml":
+		return true
+	case strings.HasPrefix(mt, "image/"), strings.HasPrefix(mt, "audio/"), strings.HasPrefix(mt, "video/"):
+		return false
+	}
+	switch mt {
+	case "application/gzip", "application/zip", "application/zstd":
+		return false
+	}
+	return true
+}
+
+// close sends the rest of the response once the handler returned.
+func (cw *compressWriter) close() {
+	if !cw.started && cw.code != 0 {
+		cw.start(false)
+	}
+	if cw.e != nil {
+		cw.e.Close()
+	}
+}
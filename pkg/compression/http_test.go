@@ -0,0 +1,113 @@
+package compression
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestHandler(t *testing.T) {
+	text := strings.Repeat("a", 100)
+	for _, c := range []struct {
+		name        string
+		accept      string
+		method      string
+		contentType string
+		encoding    string
+		code        int
+		writes      []string
+		flush       bool
+		want        string
+	}{
+		{name: "at the threshold", accept: "gzip", writes: []string{text}, want: Gzip},
+		{name: "below the threshold", accept: "gzip", writes: []string{text[:99]}},
+		{name: "over several writes", accept: "gzip, zstd", writes: []string{text[:50], text[:50]}, want: Zstd},
+		{name: "not accepted", accept: "br", writes: []string{text}},
+		{name: "head", accept: "gzip", method: http.MethodHead, writes: []string{text}},
+		{name: "flushed early", accept: "gzip", writes: []string{text[:10], text}, flush: true},
+		{name: "image", accept: "gzip", contentType: "image/png", writes: []string{text}},
+		{name: "svg", accept: "gzip", contentType: "image/svg+xml", writes: []string{text}, want: Gzip},
+		{name: "already encoded", accept: "gzip", encoding: "br", writes: []string{text}},
+		{name: "no content", accept: "gzip", code: http.StatusNoContent},
+		{name: "error", accept: "gzip", code: http.StatusBadRequest, writes: []string{text}, want: Gzip},
+	} {
+		handler := Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if c.contentType != "" {
+				w.Header().Set("Content-Type", c.contentType)
+			}
+			if c.encoding != "" {
+				w.Header().Set("Content-Encoding", c.encoding)
+			}
+			if c.code != 0 {
+				w.WriteHeader(c.code)
+			}
+			for i, s := range c.writes {
+				w.Write([]byte(s))
+				if c.flush && i == 0 {
+					w.(http.Flusher).Flush()
+				}
+			}
+		}), Config{Encodings: []string{Zstd, Gzip}, MinSize: 100})
+
+		method := c.method
+		if method == "" {
+			method = http.MethodGet
+		}
+		r := httptest.NewRequest(method, "/", nil)
+		r.Header.Set("Accept-Encoding", c.accept)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		wantCode := c.code
+		if wantCode == 0 {
+			wantCode = http.StatusOK
+		}
+		if w.Code != wantCode {
+			t.Errorf("%s: status %d, want %d", c.name, w.Code, wantCode)
+		}
+		if got := w.Header().Get("Vary"); got != "Accept-Encoding" {
+			t.Errorf("%s: Vary %q", c.name, got)
+		}
+		got := w.Header().Get("Content-Encoding")
+		if got == c.encoding {
+			got = ""
+		}
+		if got != c.want {
+			t.Errorf("%s: Content-Encoding %q, want %q", c.name, got, c.want)
+			continue
+		}
+		body, err := decode(c.want, w.Body.Bytes())
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		if want := strings.Join(c.writes, ""); string(body) != want {
+			t.Errorf("%s: body %q, want %q", c.name, body, want)
+		}
+	}
+}
+
+func decode(encoding string, b []byte) ([]byte, error) {
+	switch encoding {
+	case Gzip:
+		r, err := gzip.NewReader(bytes.NewReader(b))
+		if err != nil {
+			return nil, err
+		}
+		return ioutil.ReadAll(r)
+	case Zstd:
+		d, err := zstd.NewReader(bytes.NewReader(b))
+		if err != nil {
+			return nil, err
+		}
+		defer d.Close()
+		return ioutil.ReadAll(d)
+	}
+	return b, nil
+}
@@ -0,0 +1,56 @@
+package config
+
+// AddsvcPrefix prefixes the environment variables of addsvc.
+const AddsvcPrefix = "QS_ADDSVC"
+
+// Addsvc is the configuration of addsvc.
+type Addsvc struct {
+	Service  `yaml:",inline"`
+	DebugLog DebugLog `yaml:"debug_log"`
+	Fault    Fault    `yaml:"fault"`
+}
+
+// LoadAddsvc returns the configuration of addsvc, read from its defaults, its
+// configuration file, the environment and args.
+func LoadAddsvc(args []string) (cfg Addsvc, err error) {
+	cfg = defaultAddsvc()
+	err = load("addsvc", AddsvcPrefix, &cfg, &cfg.PrintConfig, args, cfg.bind)
+	if err != nil {
+		return cfg, err
+	}
+	var v validator
+	cfg.check(&v)
+	return cfg, v.err()
+}
+
+func defaultAddsvc() Addsvc {
+	return Addsvc{
+		Service: Service{
+			NameSpace:   "gokitconsulk8s",
+			ServiceName: "addsvc",
+			LogLevel:    "info",
+			ServiceHost: "localhost",
+			HTTPPort:    "8180",
+			GRPCPort:    "8181",
+			Compression: defaultCompression(),
+			Resilience:  defaultServerResilience(),
+		},
+		DebugLog: defaultDebugLog(),
+	}
+}
+
+func (c *Addsvc) bind(l *loader) {
+	c.Service.bind(l, AddsvcPrefix)
+	c.DebugLog.bind(l, AddsvcPrefix)
+	c.Fault.bind(l, AddsvcPrefix)
+}
+
+func (c Addsvc) check(v *validator) {
+	c.Service.check(v, false)
+	c.DebugLog.check(v)
+}
+
+func (c Addsvc) redacted() interface{} {
+	c.Service.redact()
+	return c
+}
@@ -0,0 +1,85 @@
+package config
+
+import "net"
+
+// AllPrefix prefixes the environment variables read only by serve all.
+const AllPrefix = "QS"
+
+// All is the configuration of every service run together in one process, as
+// done by serve all. Its configuration file holds one section per service,
+// each service still reads its own QS_* environment variables and flags are
+// namespaced by service, e.g. -addsvc.http-port.
+type All struct {
+	Addsvc Addsvc `yaml:"addsvc"`
+	Foosvc Foosvc `yaml:"foosvc"`
+	Router Router `yaml:"router"`
+
+	// PrintConfig is set by the -print-config flag.
+	PrintConfig bool `yaml:"-"`
+}
+
+// LoadAll returns the configuration of every service, read from their
+// defaults, the configuration file, the environment and args. foosvc calls
+// addsvc in-process by default and health checks it by its service name, and
+// the router serves on 8080 and 8081 and proxies gRPC calls to the gRPC ports
+// of addsvc and foosvc.
+func LoadAll(args []string) (cfg All, err error) {
+	cfg = All{
+		Addsvc: defaultAddsvc(),
+		Foosvc: defaultFoosvc(),
+		Router: defaultRouter(),
+	}
+	cfg.Foosvc.Addsvc.Transport = TransportLocal
+	cfg.Foosvc.Addsvc.ServiceName = ""
+	cfg.Router.HTTPPort = "8080"
+	cfg.Router.GRPCPort = "8081"
+
+	err = load("all", AllPrefix, &cfg, &cfg.PrintConfig, args, func(l *loader) {
+		l.flagPrefix = "addsvc."
+		cfg.Addsvc.bind(l)
+		l.flagPrefix = "foosvc."
+		cfg.Foosvc.bind(l)
+		l.flagPrefix = "router."
+		cfg.Router.bind(l)
+	})
+	if err != nil {
+		return cfg, err
+	}
+	if cfg.Foosvc.Addsvc.ServiceName == "" {
+		cfg.Foosvc.Addsvc.ServiceName = cfg.Addsvc.ServiceName
+	}
+	if cfg.Router.AddsvcURL == "" {
+		cfg.Router.AddsvcURL = net.JoinHostPort("localhost", cfg.Addsvc.GRPCPort)
+	}
+	if cfg.Router.FoosvcURL == "" {
+		cfg.Router.FoosvcURL = net.JoinHostPort("localhost", cfg.Foosvc.GRPCPort)
+	}
+
+	var v validator
+	v.scope = "addsvc."
+	cfg.Addsvc.check(&v)
+	v.scope = "foosvc."
+	cfg.Foosvc.check(&v)
+	v.scope = "router."
+	cfg.Router.check(&v)
+	v.scope = ""
+	v.distinctPorts(map[string]string{
+		"addsvc.http_port":  cfg.Addsvc.HTTPPort,
+		"addsvc.grpc_port":  cfg.Addsvc.GRPCPort,
+		"addsvc.admin_port": cfg.Addsvc.AdminPort,
+		"foosvc.http_port":  cfg.Foosvc.HTTPPort,
+		"foosvc.grpc_port":  cfg.Foosvc.GRPCPort,
+		"foosvc.admin_port": cfg.Foosvc.AdminPort,
+		"router.http_port":  cfg.Router.HTTPPort,
+		"router.grpc_port":  cfg.Router.GRPCPort,
+		"router.admin_port": cfg.Router.AdminPort,
+	})
+	return cfg, v.err()
+}
+
+func (c All) redacted() interface{} {
+	c.Addsvc = c.Addsvc.redacted().(Addsvc)
+	c.Foosvc = c.Foosvc.redacted().(Foosvc)
+	c.Router = c.Router.redacted().(Router)
+	return c
+}
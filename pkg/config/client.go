@@ -0,0 +1,31 @@
+package config
+
+// ClientPrefix prefixes the environment variables of the client command.
+const ClientPrefix = "QS_CLIENT"
+
+// Client is the configuration of the client command, which takes its other
+// settings as flags.
+type Client struct {
+	// Resilience guards the calls made.
+	Resilience Resilience `yaml:"resilience"`
+}
+
+// LoadClient returns the configuration of the client command, read from its
+// defaults, the configuration file named by QS_CLIENT_CONFIG_FILE and
+// the environment. Calls are paced by the caller, so only the circuit breaker
+// and bulkhead of the client apply by default.
+func LoadClient() (cfg Client, err error) {
+	cfg = Client{Resilience: defaultClientResilience()}
+	cfg.Resilience.Default.RateLimit = 0
+
+	var print bool
+	err = load("client", ClientPrefix, &cfg, &print, nil, func(l *loader) {
+		cfg.Resilience.bind(l, ClientPrefix, "")
+	})
+	if err != nil {
+		return cfg, err
+	}
+	var v validator
+	cfg.Resilience.check(&v, "resilience")
+	return cfg, v.err()
+}
@@ -0,0 +1,426 @@
+// Package config loads the configuration of the gokitconsulk8s services.
+//
+// Every setting starts from its default and is then overridden, in order, by
+// the YAML file named by the -config flag or the <PREFIX>_CONFIG_FILE
+// environment variable, by its QS_* environment variable and finally by its
+// command line flag.
+package config
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/cage1016/gokitconsulk8s/pkg/compression"
+)
+
+// Service holds the settings shared by every service.
+type Service struct {
+	NameSpace   string `yaml:"namespace"`
+	ServiceName string `yaml:"service_name"`
+	LogLevel    string `yaml:"log_level"`
+	ServiceHost string `yaml:"service_host"`
+	HTTPPort    string `yaml:"http_port"`
+	GRPCPort    string `yaml:"grpc_port"`
+	// AdminPort is the port of the admin server, which serves profiles,
+	// expvars, build information and the log level. Empty disables it.
+	AdminPort   string      `yaml:"admin_port"`
+	ZipkinV2URL string      `yaml:"zipkin_v2_url"`
+	Compression Compression `yaml:"compression"`
+	// Resilience guards the methods the service serves, or for the router
+	// the calls it forwards.
+	Resilience Resilience `yaml:"resilience"`
+
+	// PrintConfig asks the service to print its effective configuration and
+	// exit. It is only set by the -print-config flag.
+	PrintConfig bool `yaml:"-"`
+}
+
+func (s *Service) bind(l *loader, prefix string) {
+	l.String(&s.NameSpace, prefix+"_NAMESPACE", "namespace", "namespace of the service")
+	l.String(&s.ServiceName, prefix+"_SERVICE_NAME", "service-name", "name of the service in traces, logs and health checks")
+	l.String(&s.LogLevel, prefix+"_LOG_LEVEL", "log-level", "log level: debug, info, warn or error")
+	l.String(&s.ServiceHost, prefix+"_SERVICE_HOST", "service-host", "host the service is reachable at")
+	l.String(&s.HTTPPort, prefix+"_HTTP_PORT", "http-port", "HTTP listen port")
+	l.String(&s.GRPCPort, prefix+"_GRPC_PORT", "grpc-port", "gRPC listen port")
+	l.String(&s.AdminPort, prefix+"_ADMIN_PORT", "admin-port", "admin HTTP listen port, the admin server is off when empty")
+	l.String(&s.ZipkinV2URL, "QS_ZIPKIN_V2_URL", "zipkin-v2-url", "Zipkin v2 span collector URL, tracing is off when empty")
+	s.Compression.bind(l, prefix)
+	s.Resilience.bind(l, prefix, "")
+}
+
+// check validates the settings of s. Ports may only be left empty, which
+// disables the listener, when optionalPorts is set.
+func (s Service) check(v *validator, optionalPorts bool) {
+	v.required("service_name", s.ServiceName)
+	v.oneOf("log_level", s.LogLevel, "debug", "info", "warn", "error")
+	if !optionalPorts {
+		v.required("http_port", s.HTTPPort)
+		v.required("grpc_port", s.GRPCPort)
+	}
+	v.port("http_port", s.HTTPPort)
+	v.port("grpc_port", s.GRPCPort)
+	v.port("admin_port", s.AdminPort)
+	v.distinctPorts(map[string]string{
+		"http_port":  s.HTTPPort,
+		"grpc_port":  s.GRPCPort,
+		"admin_port": s.AdminPort,
+	})
+	v.url("zipkin_v2_url", s.ZipkinV2URL)
+	s.Compression.check(v)
+	s.Resilience.check(v, "resilience")
+}
+
+// Compression configures the compression of the HTTP responses of a service
+// and of the gRPC calls it makes. gRPC servers accept calls compressed with
+// gzip or zstd and answer them compressed alike.
+type Compression struct {
+	// HTTP lists the encodings of HTTP responses, gzip and zstd, in order of
+	// preference. Empty disables the compression of HTTP responses.
+	HTTP List `yaml:"http"`
+	// MinSize is the size below which HTTP responses are not compressed.
+	MinSize int `yaml:"min_size"`
+	// GRPC is the compressor of the gRPC calls the service makes, gzip or
+	// zstd, empty for none.
+	GRPC string `yaml:"grpc"`
+}
+
+func defaultCompression() Compression {
+	return Compression{HTTP: List{compression.Gzip}, MinSize: 1024}
+}
+
+func (c *Compression) bind(l *loader, prefix string) {
+	l.List(&c.HTTP, prefix+"_COMPRESSION_HTTP", "compression-http", "comma separated encodings of HTTP responses in order of preference: gzip, zstd, empty for none")
+	l.Int(&c.MinSize, prefix+"_COMPRESSION_MIN_SIZE", "compression-min-size", "size below which HTTP responses are not compressed")
+	l.String(&c.GRPC, prefix+"_COMPRESSION_GRPC", "compression-grpc", "compressor of the gRPC calls made: gzip or zstd, empty for none")
+}
+
+func (c Compression) check(v *validator) {
+	for _, e := range c.HTTP {
+		v.oneOf("compression.http", e, compression.Gzip, compression.Zstd)
+	}
+	v.atLeast("compression.min_size", float64(c.MinSize), 0)
+	if c.GRPC != "" {
+		v.oneOf("compression.grpc", c.GRPC, compression.Gzip, compression.Zstd)
+	}
+}
+
+// DebugLog configures the logging of request and response payloads, which
+// can also be switched per method at runtime.
+type DebugLog struct {
+	// Methods lists the methods whose payloads are logged from the start.
+	Methods List `yaml:"methods"`
+	// MaxBytes truncates the payloads logged, 0 logs them whole.
+	MaxBytes int `yaml:"max_bytes"`
+	// Redact lists the payload fields whose values are not logged. It
+	// replaces the default list, which covers the usual names of
+	// credentials.
+	Redact List `yaml:"redact"`
+}
+
+func defaultDebugLog() DebugLog {
+	return DebugLog{
+		MaxBytes: 4096,
+		Redact:   List{"password", "passwd", "secret", "token", "access_token", "refresh_token", "api_key", "apikey", "authorization", "credentials"},
+	}
+}
+
+func (d *DebugLog) bind(l *loader, prefix string) {
+	l.List(&d.Methods, prefix+"_DEBUG_LOG_METHODS", "debug-log-methods", "comma separated methods whose payloads are logged from the start")
+	l.Int(&d.MaxBytes, prefix+"_DEBUG_LOG_MAX_BYTES", "debug-log-max-bytes", "size the logged payloads are truncated to, 0 logs them whole")
+	l.List(&d.Redact, prefix+"_DEBUG_LOG_REDACT", "debug-log-redact", "comma separated payload fields whose values are not logged")
+}
+
+func (d DebugLog) check(v *validator) {
+	v.atLeast("debug_log.max_bytes", float64(d.MaxBytes), 0)
+}
+
+// Fault configures the injection of delays and errors into the calls of a
+// service, see package fault for the syntax of Rules.
+type Fault struct {
+	// Enabled turns fault injection on, it is off by default.
+	Enabled bool `yaml:"enabled"`
+	// Headers lets calls ask for faults in their X-Fault header.
+	Headers bool `yaml:"headers"`
+	// Rules are the faults injected into every call.
+	Rules string `yaml:"rules"`
+}
+
+func (f *Fault) bind(l *loader, prefix string) {
+	l.Bool(&f.Enabled, prefix+"_FAULT_ENABLED", "fault-enabled", "inject faults into the calls of the service")
+	l.Bool(&f.Headers, prefix+"_FAULT_HEADERS", "fault-headers", "inject the faults calls ask for in their X-Fault header")
+	l.String(&f.Rules, prefix+"_FAULT_RULES", "fault-rules", "faults injected into every call: method=delay:<duration>[:<percent>],abort:<code>[:<percent>][;...]")
+}
+
+func (s *Service) redact() {
+	s.ZipkinV2URL = redactURL(s.ZipkinV2URL)
+}
+
+// redacter is implemented by the configuration of every service.
+type redacter interface {
+	redacted() interface{}
+}
+
+// Print writes cfg to w as YAML, with the credentials of its URLs redacted.
+func Print(w io.Writer, cfg redacter) error {
+	b, err := yaml.Marshal(cfg.redacted())
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+func redactURL(s string) string {
+	u, err := url.Parse(s)
+	if err != nil || u.User == nil {
+		return s
+	}
+	u.User = url.User("xxxxx")
+	return u.String()
+}
+
+// load fills cfg, which must already hold the defaults, from the configuration
+// file, the environment and args, in that order. bind registers the
+// environment variables and flags of every setting of cfg; print is set by the
+// -print-config flag.
+func load(name, prefix string, cfg interface{}, print *bool, args []string, bind func(*loader)) error {
+	l := &loader{fs: flag.NewFlagSet(name, flag.ContinueOnError)}
+	bind(l)
+	l.fs.BoolVar(print, "print-config", false, "print the effective configuration and exit")
+
+	file := os.Getenv(prefix + "_CONFIG_FILE")
+	l.fs.StringVar(&file, "config", file, "YAML configuration file, also read from "+prefix+"_CONFIG_FILE")
+	if err := l.fs.Parse(args); err != nil {
+		return err
+	}
+
+	if file != "" {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		if err := yaml.UnmarshalStrict(b, cfg); err != nil {
+			return fmt.Errorf("%s: %v", file, err)
+		}
+	}
+	for _, v := range l.vars {
+		if s := os.Getenv(v.env); s != "" {
+			if err := v.value.Set(s); err != nil {
+				return fmt.Errorf("%s: %v", v.env, err)
+			}
+		}
+	}
+	for _, set := range l.flags {
+		if err := set(); err != nil {
+			return err
+		}
+	}
+	for _, f := range l.finish {
+		if err := f(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// value is a setting that can be parsed from its string form.
+type value interface {
+	Set(string) error
+	String() string
+}
+
+type variable struct {
+	env   string
+	value value
+}
+
+// loader binds settings to their environment variable and flag. Flags are
+// parsed first, to find the configuration file, but only applied once the
+// file and the environment have been read.
+type loader struct {
+	fs    *flag.FlagSet
+	vars  []variable
+	flags []func() error
+	// finish runs once every setting is loaded, to derive settings from
+	// others.
+	finish []func() error
+
+	// flagPrefix namespaces the flags of a service when several services are
+	// loaded together.
+	flagPrefix string
+}
+
+func (l *loader) bind(v value, env, name, usage string) {
+	name = l.flagPrefix + name
+	l.vars = append(l.vars, variable{env: env, value: v})
+	l.fs.Var(&deferredFlag{l: l, name: name, value: v}, name, usage+" ("+env+")")
+}
+
+// then runs f once every setting is loaded.
+func (l *loader) then(f func() error) {
+	l.finish = append(l.finish, f)
+}
+
+func (l *loader) String(p *string, env, flag, usage string) {
+	l.bind((*stringValue)(p), env, flag, usage)
+}
+
+func (l *loader) Int(p *int, env, flag, usage string) {
+	l.bind((*intValue)(p), env, flag, usage)
+}
+
+func (l *loader) Bool(p *bool, env, flag, usage string) {
+	l.bind((*boolValue)(p), env, flag, usage)
+}
+
+func (l *loader) Float(p *float64, env, flag, usage string) {
+	l.bind((*floatValue)(p), env, flag, usage)
+}
+
+func (l *loader) Duration(p *Duration, env, flag, usage string) {
+	l.bind(p, env, flag, usage)
+}
+
+func (l *loader) List(p *List, env, flag, usage string) {
+	l.bind(p, env, flag, usage)
+}
+
+func (l *loader) Versions(p *Versions, env, flag, usage string) {
+	l.bind(p, env, flag, usage)
+}
+
+func (l *loader) Routes(p *Routes, env, flag, usage string) {
+	l.bind(p, env, flag, usage)
+}
+
+// deferredFlag records the flags given on the command line so that they are
+// applied after the configuration file and the environment.
+type deferredFlag struct {
+	l     *loader
+	name  string
+	value value
+}
+
+func (f *deferredFlag) Set(s string) error {
+	f.l.flags = append(f.l.flags, func() error {
+		if err := f.value.Set(s); err != nil {
+			return fmt.Errorf("-%s: %v", f.name, err)
+		}
+		return nil
+	})
+	return nil
+}
+
+// IsBoolFlag lets boolean flags be given without a value.
+func (f *deferredFlag) IsBoolFlag() bool {
+	_, ok := f.value.(*boolValue)
+	return ok
+}
+
+func (f *deferredFlag) String() string {
+	if f == nil || f.value == nil {
+		return ""
+	}
+	return f.value.String()
+}
+
+type stringValue string
+
+func (v *stringValue) Set(s string) error { *v = stringValue(s); return nil }
+func (v *stringValue) String() string     { return string(*v) }
+
+type intValue int
+
+func (v *intValue) Set(s string) error {
+	n, err := strconv.Atoi(s)
+	*v = intValue(n)
+	return err
+}
+
+func (v *intValue) String() string { return strconv.Itoa(int(*v)) }
+
+type boolValue bool
+
+func (v *boolValue) Set(s string) error {
+	b, err := strconv.ParseBool(s)
+	*v = boolValue(b)
+	return err
+}
+
+func (v *boolValue) String() string { return strconv.FormatBool(bool(*v)) }
+
+type floatValue float64
+
+func (v *floatValue) Set(s string) error {
+	f, err := strconv.ParseFloat(s, 64)
+	*v = floatValue(f)
+	return err
+}
+
+func (v *floatValue) String() string { return strconv.FormatFloat(float64(*v), 'g', -1, 64) }
+
+// List is a list of strings, written as a comma separated string in the
+// environment and on the command line.
+type List []string
+
+// Set implements flag.Value.
+func (l *List) Set(s string) error {
+	*l = nil
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			*l = append(*l, v)
+		}
+	}
+	return nil
+}
+
+func (l List) String() string {
+	return strings.Join(l, ",")
+}
+
+// Duration is a time.Duration written as "500ms" or "30s". A plain number is
+// read as milliseconds, as the QS_* environment variables always have been.
+type Duration time.Duration
+
+// Duration returns d as a time.Duration.
+func (d Duration) Duration() time.Duration {
+	return time.Duration(d)
+}
+
+// Set implements flag.Value.
+func (d *Duration) Set(s string) error {
+	if ms, err := strconv.ParseInt(s, 10, 64); err == nil {
+		*d = Duration(time.Duration(ms) * time.Millisecond)
+		return nil
+	}
+	v, err := time.ParseDuration(s)
+	*d = Duration(v)
+	return err
+}
+
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (d *Duration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	return d.Set(s)
+}
+
+// MarshalYAML implements yaml.Marshaler.
+func (d Duration) MarshalYAML() (interface{}, error) {
+	return d.String(), nil
+}
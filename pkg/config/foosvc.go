@@ -0,0 +1,138 @@
+package config
+
+import (
+	"time"
+
+	"github.com/cage1016/gokitconsulk8s/pkg/discovery"
+)
+
+const (
+	// FoosvcPrefix prefixes the environment variables of foosvc.
+	FoosvcPrefix = "QS_FOOSVC"
+	// AddsvcClientPrefix prefixes the resilience settings foosvc applies to
+	// its addsvc client.
+	AddsvcClientPrefix = "QS_ADDSVC_CLIENT"
+)
+
+// Transports foosvc can use to reach addsvc.
+const (
+	TransportGRPC  = "grpc"
+	TransportHTTP  = "http"
+	TransportLocal = "local"
+)
+
+// Foosvc is the configuration of foosvc.
+type Foosvc struct {
+	Service      `yaml:",inline"`
+	RetryMax     int          `yaml:"retry_max"`
+	RetryTimeout Duration     `yaml:"retry_timeout"`
+	Addsvc       AddsvcClient `yaml:"addsvc"`
+	DebugLog     DebugLog     `yaml:"debug_log"`
+	Fault        Fault        `yaml:"fault"`
+}
+
+// AddsvcClient configures how foosvc reaches addsvc.
+type AddsvcClient struct {
+	// URL is the gRPC target of addsvc, see discovery.NewInstancer.
+	URL string `yaml:"url"`
+	// HTTPURL is the HTTP address of addsvc, used by the http transport or
+	// as the failover of the grpc transport.
+	HTTPURL   string `yaml:"http_url"`
+	Transport string `yaml:"transport"`
+	Balancer  string `yaml:"balancer"`
+	// ServiceName is the name addsvc registers with the gRPC health
+	// service, which the outlier detection checks.
+	ServiceName string `yaml:"service_name"`
+	// HealthInterval is how often addsvc instances are health checked, 0
+	// disables outlier detection.
+	HealthInterval Duration `yaml:"health_interval"`
+	EjectionTime   Duration `yaml:"ejection_time"`
+	// Resilience guards the calls to addsvc.
+	Resilience Resilience `yaml:"resilience"`
+}
+
+// LoadFoosvc returns the configuration of foosvc, read from its defaults, its
+// configuration file, the environment and args.
+func LoadFoosvc(args []string) (cfg Foosvc, err error) {
+	cfg = defaultFoosvc()
+	err = load("foosvc", FoosvcPrefix, &cfg, &cfg.PrintConfig, args, cfg.bind)
+	if err != nil {
+		return cfg, err
+	}
+	var v validator
+	cfg.check(&v)
+	return cfg, v.err()
+}
+
+func defaultFoosvc() Foosvc {
+	return Foosvc{
+		Service: Service{
+			NameSpace:   "gokitconsulk8s",
+			ServiceName: "foosvc",
+			LogLevel:    "info",
+			ServiceHost: "localhost",
+			HTTPPort:    "7180",
+			GRPCPort:    "7181",
+			Compression: defaultCompression(),
+			Resilience:  defaultServerResilience(),
+		},
+		RetryMax:     3,
+		RetryTimeout: Duration(500 * time.Millisecond),
+		Addsvc: AddsvcClient{
+			Transport:      TransportGRPC,
+			Balancer:       discovery.RoundRobin,
+			ServiceName:    "addsvc",
+			HealthInterval: Duration(10 * time.Second),
+			EjectionTime:   Duration(30 * time.Second),
+			Resilience:     defaultClientResilience(),
+		},
+		DebugLog: defaultDebugLog(),
+	}
+}
+
+func (c *Foosvc) bind(l *loader) {
+	c.Service.bind(l, FoosvcPrefix)
+	l.Int(&c.RetryMax, FoosvcPrefix+"_RETRY_MAX", "retry-max", "attempts per addsvc call")
+	l.Duration(&c.RetryTimeout, FoosvcPrefix+"_RETRY_TIMEOUT", "retry-timeout", "time budget of an addsvc call, retries included")
+	l.String(&c.Addsvc.URL, "QS_ADDSVC_URL", "addsvc-url", "addsvc gRPC target: host:port[,host:port...], dns://name or consul://name")
+	l.String(&c.Addsvc.HTTPURL, "QS_ADDSVC_HTTP_URL", "addsvc-http-url", "addsvc HTTP address")
+	l.String(&c.Addsvc.Transport, "QS_ADDSVC_TRANSPORT", "addsvc-transport", "transport to addsvc: grpc, http or local")
+	l.String(&c.Addsvc.Balancer, "QS_ADDSVC_BALANCER", "addsvc-balancer", "balancer over addsvc instances: round_robin or random")
+	l.String(&c.Addsvc.ServiceName, "QS_ADDSVC_SERVICE_NAME", "addsvc-service-name", "addsvc service name checked by the gRPC health checks")
+	l.Duration(&c.Addsvc.HealthInterval, "QS_ADDSVC_HEALTH_INTERVAL", "addsvc-health-interval", "addsvc health check interval, 0 disables outlier detection")
+	l.Duration(&c.Addsvc.EjectionTime, "QS_ADDSVC_EJECTION_TIME", "addsvc-ejection-time", "how long an unhealthy addsvc instance is ejected")
+	c.Addsvc.Resilience.bind(l, AddsvcClientPrefix, "addsvc-client-")
+	c.DebugLog.bind(l, FoosvcPrefix)
+	c.Fault.bind(l, FoosvcPrefix)
+}
+
+func (c Foosvc) check(v *validator) {
+	c.Service.check(v, false)
+	c.DebugLog.check(v)
+	v.atLeast("retry_max", float64(c.RetryMax), 1)
+	v.atLeast("retry_timeout", float64(c.RetryTimeout), 0)
+	v.oneOf("addsvc.transport", c.Addsvc.Transport, TransportGRPC, TransportHTTP, TransportLocal)
+	switch c.Addsvc.Transport {
+	case TransportGRPC:
+		v.required("addsvc.url", c.Addsvc.URL)
+		if _, err := discovery.NewBalancer(c.Addsvc.Balancer); err != nil {
+			v.errorf("addsvc.balancer", "%v", err)
+		}
+		if c.Addsvc.HealthInterval > 0 {
+			v.required("addsvc.service_name", c.Addsvc.ServiceName)
+		}
+	case TransportHTTP:
+		v.required("addsvc.http_url", c.Addsvc.HTTPURL)
+	}
+	v.target("addsvc.url", c.Addsvc.URL)
+	v.url("addsvc.http_url", c.Addsvc.HTTPURL)
+	v.atLeast("addsvc.health_interval", float64(c.Addsvc.HealthInterval), 0)
+	v.atLeast("addsvc.ejection_time", float64(c.Addsvc.EjectionTime), 0)
+	c.Addsvc.Resilience.check(v, "addsvc.resilience")
+}
+
+func (c Foosvc) redacted() interface{} {
+	c.Service.redact()
+	c.Addsvc.HTTPURL = redactURL(c.Addsvc.HTTPURL)
+	return c
+}
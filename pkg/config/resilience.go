@@ -0,0 +1,167 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Resilience configures the rate limiter, bulkhead and circuit breaker of
+// every method of a service or client, see package resilience. Methods not
+// listed in Methods use Default.
+type Resilience struct {
+	Default ResilienceSettings `yaml:"default"`
+	// Methods holds the settings of the methods listed in the configuration
+	// file, which are those of Default, environment and flags included,
+	// with the overrides of the method on top.
+	Methods map[string]ResilienceSettings `yaml:"methods"`
+
+	// overrides holds the YAML settings of every method in Methods.
+	overrides map[string]*deferred
+}
+
+// ResilienceSettings configures the resilience of a single method.
+type ResilienceSettings struct {
+	// RateLimit is the sustained number of requests per second, 0 disables
+	// rate limiting.
+	RateLimit float64 `yaml:"rate_limit"`
+	// RateBurst is the number of requests allowed above RateLimit at once.
+	RateBurst int `yaml:"rate_burst"`
+	// MaxConcurrency caps the number of requests in flight, 0 disables the
+	// bulkhead.
+	MaxConcurrency int `yaml:"max_concurrency"`
+	// MaxQueue is the number of requests allowed to wait for a free slot once
+	// MaxConcurrency is reached.
+	MaxQueue int `yaml:"max_queue"`
+	// QueueTimeout bounds how long a queued request waits, 0 waits until the
+	// request is canceled.
+	QueueTimeout Duration `yaml:"queue_timeout"`
+	Breaker      Breaker  `yaml:"breaker"`
+}
+
+// Breaker configures a circuit breaker.
+type Breaker struct {
+	// MaxRequests is the number of requests let through while half-open.
+	MaxRequests int `yaml:"max_requests"`
+	// Interval is the period of the closed state after which counts are
+	// cleared, 0 never clears them.
+	Interval Duration `yaml:"interval"`
+	// Timeout is how long the breaker stays open before turning half-open.
+	Timeout Duration `yaml:"timeout"`
+	// ConsecutiveFailures opens the breaker after that many failures in a
+	// row. The breaker is disabled when neither it nor FailureRatio is set.
+	ConsecutiveFailures int `yaml:"consecutive_failures"`
+	// FailureRatio, when set, opens the breaker once at least MinRequests
+	// were seen in the current interval and this ratio of them failed.
+	FailureRatio float64 `yaml:"failure_ratio"`
+	MinRequests  int     `yaml:"min_requests"`
+}
+
+func defaultServerResilience() Resilience {
+	return Resilience{Default: ResilienceSettings{
+		RateLimit: 1,
+		RateBurst: 100,
+		Breaker: Breaker{
+			MaxRequests:         1,
+			Timeout:             Duration(60 * time.Second),
+			ConsecutiveFailures: 6,
+		},
+	}}
+}
+
+func defaultClientResilience() Resilience {
+	r := defaultServerResilience()
+	r.Default.Breaker.Timeout = Duration(30 * time.Second)
+	return r
+}
+
+// UnmarshalYAML decodes every entry of methods on top of the default
+// settings, so that a method only lists the settings it overrides.
+func (r *Resilience) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	raw := struct {
+		Default *ResilienceSettings  `yaml:"default"`
+		Methods map[string]*deferred `yaml:"methods"`
+	}{Default: &r.Default}
+	if err := unmarshal(&raw); err != nil {
+		return err
+	}
+	r.overrides = raw.Methods
+	return r.resolve()
+}
+
+// resolve sets Methods to the overrides of every method on top of Default.
+func (r *Resilience) resolve() error {
+	if len(r.overrides) == 0 {
+		return nil
+	}
+	r.Methods = make(map[string]ResilienceSettings, len(r.overrides))
+	for method, d := range r.overrides {
+		s := r.Default
+		if d != nil {
+			if err := d.unmarshal(&s); err != nil {
+				return fmt.Errorf("method %s: %v", method, err)
+			}
+		}
+		r.Methods[method] = s
+	}
+	return nil
+}
+
+// deferred holds a YAML value until it is decoded on top of another.
+type deferred struct {
+	unmarshal func(interface{}) error
+}
+
+func (d *deferred) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	d.unmarshal = unmarshal
+	return nil
+}
+
+// bind binds the default settings to the <prefix>_RATE_*, <prefix>_MAX_*,
+// <prefix>_QUEUE_TIMEOUT and <prefix>_BREAKER_* environment variables and to
+// flags prefixed with flagPrefix. They reach the methods of the configuration
+// file too, unless a method overrides them.
+func (r *Resilience) bind(l *loader, prefix, flagPrefix string) {
+	l.then(r.resolve)
+	d := &r.Default
+	l.Float(&d.RateLimit, prefix+"_RATE_LIMIT", flagPrefix+"rate-limit", "requests per second allowed per method, 0 disables rate limiting")
+	l.Int(&d.RateBurst, prefix+"_RATE_BURST", flagPrefix+"rate-burst", "requests allowed at once above the rate limit")
+	l.Int(&d.MaxConcurrency, prefix+"_MAX_CONCURRENCY", flagPrefix+"max-concurrency", "requests in flight allowed per method, 0 disables the bulkhead")
+	l.Int(&d.MaxQueue, prefix+"_MAX_QUEUE", flagPrefix+"max-queue", "requests waiting for a slot once max concurrency is reached")
+	l.Duration(&d.QueueTimeout, prefix+"_QUEUE_TIMEOUT", flagPrefix+"queue-timeout", "how long requests wait for a slot, 0 until they are canceled")
+	l.Int(&d.Breaker.MaxRequests, prefix+"_BREAKER_MAX_REQUESTS", flagPrefix+"breaker-max-requests", "requests let through a half-open circuit breaker")
+	l.Duration(&d.Breaker.Interval, prefix+"_BREAKER_INTERVAL", flagPrefix+"breaker-interval", "period after which a closed circuit breaker clears its counts, 0 never")
+	l.Duration(&d.Breaker.Timeout, prefix+"_BREAKER_TIMEOUT", flagPrefix+"breaker-timeout", "how long a circuit breaker stays open")
+	l.Int(&d.Breaker.ConsecutiveFailures, prefix+"_BREAKER_CONSECUTIVE_FAILURES", flagPrefix+"breaker-consecutive-failures", "failures in a row opening a circuit breaker, 0 without a failure ratio disables it")
+	l.Float(&d.Breaker.FailureRatio, prefix+"_BREAKER_FAILURE_RATIO", flagPrefix+"breaker-failure-ratio", "ratio of failures opening a circuit breaker once min requests were seen, 0 counts consecutive failures")
+	l.Int(&d.Breaker.MinRequests, prefix+"_BREAKER_MIN_REQUESTS", flagPrefix+"breaker-min-requests", "requests seen before the failure ratio applies")
+}
+
+// check validates the settings of every method, reported under key.
+func (r Resilience) check(v *validator, key string) {
+	r.Default.check(v, key+".default")
+	methods := make([]string, 0, len(r.Methods))
+	for method := range r.Methods {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	for _, method := range methods {
+		r.Methods[method].check(v, key+".methods."+method)
+	}
+}
+
+func (s ResilienceSettings) check(v *validator, key string) {
+	v.atLeast(key+".rate_limit", s.RateLimit, 0)
+	if s.RateLimit > 0 {
+		v.atLeast(key+".rate_burst", float64(s.RateBurst), 1)
+	}
+	v.atLeast(key+".max_concurrency", float64(s.MaxConcurrency), 0)
+	v.atLeast(key+".max_queue", float64(s.MaxQueue), 0)
+	v.atLeast(key+".queue_timeout", float64(s.QueueTimeout), 0)
+	v.atLeast(key+".breaker.max_requests", float64(s.Breaker.MaxRequests), 0)
+	v.atLeast(key+".breaker.interval", float64(s.Breaker.Interval), 0)
+	v.atLeast(key+".breaker.timeout", float64(s.Breaker.Timeout), 0)
+	v.atLeast(key+".breaker.consecutive_failures", float64(s.Breaker.ConsecutiveFailures), 0)
+	v.between(key+".breaker.failure_ratio", s.Breaker.FailureRatio, 0, 1)
+	v.atLeast(key+".breaker.min_requests", float64(s.Breaker.MinRequests), 0)
+}
@@ -0,0 +1,449 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RouterPrefix prefixes the environment variables of the router.
+const RouterPrefix = "QS_ROUTER"
+
+// Router is the configuration of the router.
+type Router struct {
+	Service `yaml:",inline"`
+	// RetryMax and RetryTimeout bound the attempts, spread over the
+	// instances of a service, and the time of the calls of the HTTP routes.
+	// A RetryTimeout of 0 disables retries. The gRPC proxy forwards calls
+	// once.
+	RetryMax     int      `yaml:"retry_max"`
+	RetryTimeout Duration `yaml:"retry_timeout"`
+	// AddsvcURL and FoosvcURL are the gRPC targets of the services, see
+	// discovery.NewInstancer.
+	AddsvcURL string    `yaml:"addsvc_url"`
+	FoosvcURL string    `yaml:"foosvc_url"`
+	Hedge     Hedge     `yaml:"hedge"`
+	AccessLog AccessLog `yaml:"access_log"`
+	Split     Split     `yaml:"split"`
+	// Routes send the gRPC calls they match to an upstream of their own,
+	// ahead of Split. The first route matching a call wins.
+	Routes Routes `yaml:"routes"`
+	Shadow Shadow `yaml:"shadow"`
+	// GRPCWeb serves gRPC-Web calls on the HTTP port, forwarding them like
+	// the calls of the gRPC port.
+	GRPCWeb   bool      `yaml:"grpc_web"`
+	CORS      CORS      `yaml:"cors"`
+	WebSocket WebSocket `yaml:"websocket"`
+	// FaultHeaders passes the X-Fault header and x-fault metadata of calls on
+	// to the services. The router drops them otherwise, so that its callers
+	// cannot ask the services for faults.
+	FaultHeaders bool `yaml:"fault_headers"`
+}
+
+// Hedge configures the hedging of the calls the router forwards.
+type Hedge struct {
+	// Percentile of recent latencies after which a call is hedged, 0
+	// disables hedging.
+	Percentile float64  `yaml:"percentile"`
+	MinDelay   Duration `yaml:"min_delay"`
+	MaxDelay   Duration `yaml:"max_delay"`
+	// MaxRatio caps hedged calls to this fraction of all calls.
+	MaxRatio float64 `yaml:"max_ratio"`
+}
+
+// AccessLog configures the access log of the router.
+type AccessLog struct {
+	// SampleRate is the fraction of successful calls logged. Failed calls
+	// are always logged.
+	SampleRate float64 `yaml:"sample_rate"`
+	// Headers logs the HTTP headers and gRPC metadata of the calls.
+	Headers bool `yaml:"headers"`
+	// Redact lists the headers and metadata whose values are not logged.
+	Redact List `yaml:"redact"`
+	// TrustedProxies lists the IP addresses and CIDR blocks of the proxies
+	// whose X-Forwarded-For header names the client of a call. The header
+	// is ignored on calls from other peers.
+	TrustedProxies List `yaml:"trusted_proxies"`
+}
+
+// Split configures the splitting of the calls to addsvc and foosvc between
+// their stable version, at addsvc_url and foosvc_url, and other versions such
+// as canaries.
+type Split struct {
+	// Header names the header, or gRPC metadata, that pins a call to the
+	// version it names.
+	Header string `yaml:"header"`
+	// HashHeader names the header, or gRPC metadata, whose value is hashed to
+	// pick the version of a call, so that calls with the same value stick to
+	// the same version. Calls without it are split at random.
+	HashHeader string   `yaml:"hash_header"`
+	Addsvc     Versions `yaml:"addsvc"`
+	Foosvc     Versions `yaml:"foosvc"`
+}
+
+// StableVersion names the version of a service at its usual URL.
+const StableVersion = "stable"
+
+// Version is a version of a service that calls are split to.
+type Version struct {
+	Name string `yaml:"name"`
+	// URL is the gRPC target of the version, see discovery.NewInstancer.
+	URL string `yaml:"url"`
+	// Weight is the percentage of calls sent to the version. The stable
+	// version gets the calls left.
+	Weight int `yaml:"weight"`
+}
+
+// Versions is a list of versions, written as name:weight@url and separated by
+// semicolons in the environment and on the command line, e.g.
+// canary:10@localhost:9181.
+type Versions []Version
+
+// Set implements flag.Value.
+func (vs *Versions) Set(s string) error {
+	*vs = nil
+	for _, e := range strings.Split(s, ";") {
+		if e = strings.TrimSpace(e); e == "" {
+			continue
+		}
+		i := strings.Index(e, "@")
+		j := strings.Index(e, ":")
+		if i < 0 || j < 0 || j > i {
+			return fmt.Errorf("%q is not name:weight@url", e)
+		}
+		weight, err := strconv.Atoi(e[j+1 : i])
+		if err != nil {
+			return fmt.Errorf("%q: %v", e, err)
+		}
+		*vs = append(*vs, Version{Name: e[:j], URL: e[i+1:], Weight: weight})
+	}
+	return nil
+}
+
+func (vs Versions) String() string {
+	s := make([]string, len(vs))
+	for i, v := range vs {
+		s[i] = fmt.Sprintf("%s:%d@%s", v.Name, v.Weight, v.URL)
+	}
+	return strings.Join(s, ";")
+}
+
+func (vs Versions) check(v *validator, key string) {
+	names := map[string]bool{StableVersion: true}
+	total := 0
+	for _, version := range vs {
+		if version.Name == "" || names[version.Name] {
+			v.errorf(key, "version name %q is empty or used twice", version.Name)
+		}
+		names[version.Name] = true
+		v.required(key+"."+version.Name+".url", version.URL)
+		v.target(key+"."+version.Name+".url", version.URL)
+		v.between(key+"."+version.Name+".weight", float64(version.Weight), 0, 100)
+		total += version.Weight
+	}
+	if total > 100 {
+		v.errorf(key, "weights add up to %d%%, more than 100%%", total)
+	}
+}
+
+// CORS configures the cross-origin calls browsers may make to the HTTP routes
+// and, with grpc_web, to the gRPC proxy.
+type CORS struct {
+	// AllowedOrigins lists the origins allowed, such as https://example.com.
+	// * allows any origin, and a single * within an origin any string, e.g.
+	// https://*.example.com. Empty disables CORS. * may not be combined
+	// with AllowCredentials, which would let any site call the router with
+	// the credentials of its visitors.
+	AllowedOrigins List `yaml:"allowed_origins"`
+	AllowedMethods List `yaml:"allowed_methods"`
+	AllowedHeaders List `yaml:"allowed_headers"`
+	// ExposedHeaders lists the response headers scripts may read.
+	ExposedHeaders   List `yaml:"exposed_headers"`
+	AllowCredentials bool `yaml:"allow_credentials"`
+	// MaxAge is how long browsers may cache the answer to a preflight
+	// request.
+	MaxAge Duration `yaml:"max_age"`
+}
+
+// WebSocket configures the WebSocket gateway of the router, which bridges the
+// JSON messages of browsers to the gRPC calls of the proxy.
+type WebSocket struct {
+	Enabled bool `yaml:"enabled"`
+	// MaxCalls caps the calls in progress on a socket.
+	MaxCalls int `yaml:"max_calls"`
+	// MaxMessageBytes caps the size of the messages read from a socket.
+	MaxMessageBytes int `yaml:"max_message_bytes"`
+}
+
+// Shadow configures the mirroring of calls to shadow upstreams, such as a new
+// version of a service, whose responses are compared with those of the
+// service and then discarded.
+type Shadow struct {
+	Addsvc Mirror `yaml:"addsvc"`
+	Foosvc Mirror `yaml:"foosvc"`
+	// Timeout bounds every shadow call.
+	Timeout Duration `yaml:"timeout"`
+	// MaxInFlight caps the shadow calls in progress, calls beyond it are not
+	// mirrored.
+	MaxInFlight int `yaml:"max_in_flight"`
+}
+
+// Mirror configures the mirroring of the calls to a service.
+type Mirror struct {
+	// URL is the gRPC target of the shadow, see discovery.NewInstancer.
+	// Empty disables mirroring.
+	URL string `yaml:"url"`
+	// Percent is the percentage of calls mirrored.
+	Percent float64 `yaml:"percent"`
+}
+
+// Route sends the gRPC calls matching all of its conditions to Upstream.
+type Route struct {
+	Name string `yaml:"name"`
+	// Method is a path.Match pattern of the full method of the calls, e.g.
+	// /pb.Addsvc/* or /pb.Addsvc/Sum.
+	Method string `yaml:"method"`
+	// Metadata maps metadata keys to the value calls must have, * for any.
+	Metadata map[string]string `yaml:"metadata"`
+	// Callers lists the IP addresses or CIDR blocks calls must come from,
+	// matched against the peer address of the calls only.
+	Callers List `yaml:"callers"`
+	// Upstream is the gRPC target of the calls matched, see
+	// discovery.NewInstancer.
+	Upstream string `yaml:"upstream"`
+}
+
+// Routes is a list of routes, separated by semicolons in the environment and
+// on the command line, each written as name@upstream?conditions with the
+// conditions in query string form: method, caller, repeated for every caller,
+// and metadata keys, e.g. acme@localhost:9181?method=/pb.Addsvc/*&x-tenant=acme.
+type Routes []Route
+
+// Set implements flag.Value.
+func (rs *Routes) Set(s string) error {
+	*rs = nil
+	for _, e := range strings.Split(s, ";") {
+		if e = strings.TrimSpace(e); e == "" {
+			continue
+		}
+		i := strings.Index(e, "@")
+		if i < 0 {
+			return fmt.Errorf("%q is not name@upstream?conditions", e)
+		}
+		r := Route{Name: e[:i], Upstream: e[i+1:]}
+		if j := strings.Index(r.Upstream, "?"); j >= 0 {
+			conditions, err := url.ParseQuery(r.Upstream[j+1:])
+			if err != nil {
+				return fmt.Errorf("%q: %v", e, err)
+			}
+			r.Upstream = r.Upstream[:j]
+			for key, values := range conditions {
+				switch key {
+				case "method":
+					r.Method = values[0]
+				case "caller":
+					r.Callers = values
+				default:
+					if r.Metadata == nil {
+						r.Metadata = map[string]string{}
+					}
+					r.Metadata[key] = values[0]
+				}
+			}
+		}
+		*rs = append(*rs, r)
+	}
+	return nil
+}
+
+func (rs Routes) String() string {
+	s := make([]string, len(rs))
+	for i, r := range rs {
+		conditions := url.Values{}
+		if r.Method != "" {
+			conditions.Set("method", r.Method)
+		}
+		for _, c := range r.Callers {
+			conditions.Add("caller", c)
+		}
+		for k, v := range r.Metadata {
+			conditions.Set(k, v)
+		}
+		s[i] = r.Name + "@" + r.Upstream
+		if len(conditions) > 0 {
+			s[i] += "?" + conditions.Encode()
+		}
+	}
+	return strings.Join(s, ";")
+}
+
+func (rs Routes) check(v *validator) {
+	names := map[string]bool{}
+	for _, r := range rs {
+		key := "routes." + r.Name
+		if r.Name == "" || names[r.Name] {
+			v.errorf("routes", "route name %q is empty or used twice", r.Name)
+		}
+		names[r.Name] = true
+		v.required(key+".upstream", r.Upstream)
+		v.target(key+".upstream", r.Upstream)
+		if _, err := path.Match(r.Method, ""); err != nil {
+			v.errorf(key+".method", "%q: %v", r.Method, err)
+		}
+		for _, c := range r.Callers {
+			if _, _, err := net.ParseCIDR(c); err != nil && net.ParseIP(c) == nil {
+				v.errorf(key+".callers", "%q is not an IP address or a CIDR block", c)
+			}
+		}
+	}
+}
+
+// LoadRouter returns the configuration of the router, read from its defaults,
+// its configuration file, the environment and args.
+func LoadRouter(args []string) (cfg Router, err error) {
+	cfg = defaultRouter()
+	err = load("router", RouterPrefix, &cfg, &cfg.PrintConfig, args, cfg.bind)
+	if err != nil {
+		return cfg, err
+	}
+	var v validator
+	cfg.check(&v)
+	return cfg, v.err()
+}
+
+func defaultRouter() Router {
+	return Router{
+		Service: Service{
+			ServiceName: "router",
+			LogLevel:    "info",
+			Compression: defaultCompression(),
+			Resilience:  defaultClientResilience(),
+		},
+		RetryMax:     3,
+		RetryTimeout: Duration(500 * time.Millisecond),
+		Hedge: Hedge{
+			MinDelay: Duration(5 * time.Millisecond),
+			MaxDelay: Duration(200 * time.Millisecond),
+			MaxRatio: 0.1,
+		},
+		AccessLog: AccessLog{
+			SampleRate: 1,
+			Redact:     List{"authorization", "cookie", "proxy-authorization", "x-api-key"},
+		},
+		Split: Split{Header: "x-version"},
+		Shadow: Shadow{
+			Addsvc:      Mirror{Percent: 100},
+			Foosvc:      Mirror{Percent: 100},
+			Timeout:     Duration(time.Second),
+			MaxInFlight: 100,
+		},
+		CORS: CORS{
+			AllowedMethods: List{"GET", "HEAD", "POST"},
+			AllowedHeaders: List{"Content-Type", "X-Request-ID", "X-Version"},
+			ExposedHeaders: List{"X-Request-ID"},
+			MaxAge:         Duration(10 * time.Minute),
+		},
+		WebSocket: WebSocket{
+			MaxCalls:        100,
+			MaxMessageBytes: 1 << 20,
+		},
+	}
+}
+
+func (c *Router) bind(l *loader) {
+	c.Service.bind(l, RouterPrefix)
+	l.Int(&c.RetryMax, RouterPrefix+"_RETRY_MAX", "retry-max", "attempts per call of the HTTP routes")
+	l.Duration(&c.RetryTimeout, RouterPrefix+"_RETRY_TIMEOUT", "retry-timeout", "time budget of a call of the HTTP routes, retries included, 0 disables retries")
+	l.String(&c.AddsvcURL, "QS_ADDSVC_URL", "addsvc-url", "addsvc gRPC target: host:port[,host:port...], dns://name or consul://name")
+	l.String(&c.FoosvcURL, "QS_FOOSVC_URL", "foosvc-url", "foosvc gRPC target: host:port[,host:port...], dns://name or consul://name")
+	l.Float(&c.Hedge.Percentile, RouterPrefix+"_HEDGE_PERCENTILE", "hedge-percentile", "latency percentile after which calls are hedged, 0 disables hedging")
+	l.Duration(&c.Hedge.MinDelay, RouterPrefix+"_HEDGE_MIN_DELAY", "hedge-min-delay", "lower bound of the hedge delay")
+	l.Duration(&c.Hedge.MaxDelay, RouterPrefix+"_HEDGE_MAX_DELAY", "hedge-max-delay", "upper bound of the hedge delay")
+	l.Float(&c.Hedge.MaxRatio, RouterPrefix+"_HEDGE_MAX_RATIO", "hedge-max-ratio", "maximum fraction of calls hedged")
+	l.Float(&c.AccessLog.SampleRate, RouterPrefix+"_ACCESS_LOG_SAMPLE_RATE", "access-log-sample-rate", "fraction of successful calls written to the access log")
+	l.Bool(&c.AccessLog.Headers, RouterPrefix+"_ACCESS_LOG_HEADERS", "access-log-headers", "write the headers and metadata of calls to the access log")
+	l.List(&c.AccessLog.Redact, RouterPrefix+"_ACCESS_LOG_REDACT", "access-log-redact", "comma separated headers and metadata whose values are redacted in the access log")
+	l.List(&c.AccessLog.TrustedProxies, RouterPrefix+"_ACCESS_LOG_TRUSTED_PROXIES", "access-log-trusted-proxies", "comma separated IP addresses and CIDR blocks of the proxies whose X-Forwarded-For header the access log trusts")
+	l.String(&c.Split.Header, RouterPrefix+"_SPLIT_HEADER", "split-header", "header or metadata pinning a call to the version it names")
+	l.String(&c.Split.HashHeader, RouterPrefix+"_SPLIT_HASH_HEADER", "split-hash-header", "header or metadata hashed to pick the version of a call, calls without it are split at random")
+	l.Versions(&c.Split.Addsvc, RouterPrefix+"_SPLIT_ADDSVC", "split-addsvc", "addsvc versions besides the stable one: name:weight@url[;name:weight@url...], weights in percent")
+	l.Versions(&c.Split.Foosvc, RouterPrefix+"_SPLIT_FOOSVC", "split-foosvc", "foosvc versions besides the stable one: name:weight@url[;name:weight@url...], weights in percent")
+	l.Routes(&c.Routes, RouterPrefix+"_ROUTES", "routes", "gRPC routes ahead of the split: name@upstream?method=pattern&caller=cidr&key=value[;...]")
+	l.String(&c.Shadow.Addsvc.URL, RouterPrefix+"_SHADOW_ADDSVC_URL", "shadow-addsvc-url", "gRPC target the calls to addsvc are mirrored to, empty for none")
+	l.Float(&c.Shadow.Addsvc.Percent, RouterPrefix+"_SHADOW_ADDSVC_PERCENT", "shadow-addsvc-percent", "percentage of the calls to addsvc mirrored")
+	l.String(&c.Shadow.Foosvc.URL, RouterPrefix+"_SHADOW_FOOSVC_URL", "shadow-foosvc-url", "gRPC target the calls to foosvc are mirrored to, empty for none")
+	l.Float(&c.Shadow.Foosvc.Percent, RouterPrefix+"_SHADOW_FOOSVC_PERCENT", "shadow-foosvc-percent", "percentage of the calls to foosvc mirrored")
+	l.Duration(&c.Shadow.Timeout, RouterPrefix+"_SHADOW_TIMEOUT", "shadow-timeout", "time budget of a mirrored call")
+	l.Int(&c.Shadow.MaxInFlight, RouterPrefix+"_SHADOW_MAX_IN_FLIGHT", "shadow-max-in-flight", "mirrored calls in progress beyond which calls are not mirrored")
+	l.Bool(&c.GRPCWeb, RouterPrefix+"_GRPC_WEB", "grpc-web", "serve gRPC-Web calls, binary and text, on the HTTP port")
+	l.List(&c.CORS.AllowedOrigins, RouterPrefix+"_CORS_ALLOWED_ORIGINS", "cors-allowed-origins", "comma separated origins allowed to call the router, * for any, empty disables CORS")
+	l.List(&c.CORS.AllowedMethods, RouterPrefix+"_CORS_ALLOWED_METHODS", "cors-allowed-methods", "comma separated methods allowed in cross-origin calls")
+	l.List(&c.CORS.AllowedHeaders, RouterPrefix+"_CORS_ALLOWED_HEADERS", "cors-allowed-headers", "comma separated headers allowed in cross-origin calls")
+	l.List(&c.CORS.ExposedHeaders, RouterPrefix+"_CORS_EXPOSED_HEADERS", "cors-exposed-headers", "comma separated response headers exposed to cross-origin callers")
+	l.Bool(&c.CORS.AllowCredentials, RouterPrefix+"_CORS_ALLOW_CREDENTIALS", "cors-allow-credentials", "allow cross-origin calls with credentials")
+	l.Duration(&c.CORS.MaxAge, RouterPrefix+"_CORS_MAX_AGE", "cors-max-age", "how long browsers may cache preflight answers")
+	l.Bool(&c.WebSocket.Enabled, RouterPrefix+"_WEBSOCKET_ENABLED", "websocket-enabled", "serve the WebSocket gateway to the gRPC calls on the HTTP port")
+	l.Int(&c.WebSocket.MaxCalls, RouterPrefix+"_WEBSOCKET_MAX_CALLS", "websocket-max-calls", "calls in progress allowed on a WebSocket")
+	l.Int(&c.WebSocket.MaxMessageBytes, RouterPrefix+"_WEBSOCKET_MAX_MESSAGE_BYTES", "websocket-max-message-bytes", "size limit of the messages read from a WebSocket")
+	l.Bool(&c.FaultHeaders, RouterPrefix+"_FAULT_HEADERS", "fault-headers", "pass the X-Fault header of calls on to the services")
+}
+
+func (c Router) check(v *validator) {
+	c.Service.check(v, true)
+	if c.HTTPPort == "" && c.GRPCPort == "" {
+		v.errorf("http_port and grpc_port", "at least one is required")
+	}
+	v.atLeast("retry_max", float64(c.RetryMax), 1)
+	v.atLeast("retry_timeout", float64(c.RetryTimeout), 0)
+	v.required("addsvc_url", c.AddsvcURL)
+	v.required("foosvc_url", c.FoosvcURL)
+	v.target("addsvc_url", c.AddsvcURL)
+	v.target("foosvc_url", c.FoosvcURL)
+	v.between("hedge.percentile", c.Hedge.Percentile, 0, 1)
+	v.between("hedge.max_ratio", c.Hedge.MaxRatio, 0, 1)
+	v.atLeast("hedge.min_delay", float64(c.Hedge.MinDelay), 0)
+	if c.Hedge.MaxDelay < c.Hedge.MinDelay {
+		v.errorf("hedge.max_delay", "%s is less than hedge.min_delay %s", c.Hedge.MaxDelay, c.Hedge.MinDelay)
+	}
+	v.between("access_log.sample_rate", c.AccessLog.SampleRate, 0, 1)
+	for _, p := range c.AccessLog.TrustedProxies {
+		if _, _, err := net.ParseCIDR(p); err != nil && net.ParseIP(p) == nil {
+			v.errorf("access_log.trusted_proxies", "%q is not an IP address or a CIDR block", p)
+		}
+	}
+	c.Split.Addsvc.check(v, "split.addsvc")
+	c.Split.Foosvc.check(v, "split.foosvc")
+	c.Routes.check(v)
+	v.target("shadow.addsvc.url", c.Shadow.Addsvc.URL)
+	v.between("shadow.addsvc.percent", c.Shadow.Addsvc.Percent, 0, 100)
+	v.target("shadow.foosvc.url", c.Shadow.Foosvc.URL)
+	v.between("shadow.foosvc.percent", c.Shadow.Foosvc.Percent, 0, 100)
+	v.atLeast("shadow.timeout", float64(c.Shadow.Timeout), 1)
+	v.atLeast("shadow.max_in_flight", float64(c.Shadow.MaxInFlight), 1)
+	if c.GRPCWeb && c.HTTPPort == "" {
+		v.errorf("grpc_web", "requires http_port")
+	}
+	for _, o := range c.CORS.AllowedOrigins {
+		if o != "*" && (strings.Count(o, "*") > 1 || !strings.Contains(o, "://")) {
+			v.errorf("cors.allowed_origins", "%q is not *, scheme://host[:port] or a pattern with a single *", o)
+		}
+		if o == "*" && c.CORS.AllowCredentials {
+			v.errorf("cors.allowed_origins", "* is not allowed with allow_credentials")
+		}
+	}
+	v.atLeast("cors.max_age", float64(c.CORS.MaxAge), 0)
+	if c.WebSocket.Enabled && c.HTTPPort == "" {
+		v.errorf("websocket.enabled", "requires http_port")
+	}
+	v.atLeast("websocket.max_calls", float64(c.WebSocket.MaxCalls), 1)
+	v.atLeast("websocket.max_message_bytes", float64(c.WebSocket.MaxMessageBytes), 1)
+}
+
+func (c Router) redacted() interface{} {
+	c.Service.redact()
+	return c
+}
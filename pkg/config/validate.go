@@ -0,0 +1,132 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// validator collects every problem found in a configuration, so that they
+// can all be reported at once.
+type validator struct {
+	problems []string
+	// scope prefixes the keys of the problems found, e.g. "addsvc.".
+	scope string
+}
+
+func (v *validator) errorf(key, format string, a ...interface{}) {
+	v.problems = append(v.problems, v.scope+key+": "+fmt.Sprintf(format, a...))
+}
+
+func (v *validator) err() error {
+	if len(v.problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("invalid configuration: %s", strings.Join(v.problems, "; "))
+}
+
+func (v *validator) required(key, s string) {
+	if s == "" {
+		v.errorf(key, "is required")
+	}
+}
+
+func (v *validator) oneOf(key, s string, allowed ...string) {
+	for _, a := range allowed {
+		if s == a {
+			return
+		}
+	}
+	v.errorf(key, "%q is not one of %s", s, strings.Join(allowed, ", "))
+}
+
+// port checks that s, when set, is a TCP port number.
+func (v *validator) port(key, s string) {
+	if s == "" {
+		return
+	}
+	if n, err := strconv.Atoi(s); err != nil || n < 1 || n > 65535 {
+		v.errorf(key, "%q is not a port between 1 and 65535", s)
+	}
+}
+
+// url checks that s, when set, is an absolute http or https URL. As with the
+// HTTP clients, the scheme may be left out.
+func (v *validator) url(key, s string) {
+	if s == "" {
+		return
+	}
+	raw := s
+	if !strings.Contains(raw, "://") {
+		raw = "http://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		v.errorf(key, "%v", err)
+		return
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		v.errorf(key, "%q is not an http or https URL", redactURL(s))
+	}
+}
+
+// target checks that s, when set, is a gRPC target as understood by
+// discovery.NewInstancer: host:port[,host:port...], dns://name or
+// consul://name.
+func (v *validator) target(key, s string) {
+	if s == "" {
+		return
+	}
+	for _, scheme := range []string{"dns://", "consul://"} {
+		if strings.HasPrefix(s, scheme) {
+			if strings.TrimPrefix(s, scheme) == "" {
+				v.errorf(key, "missing name in %q", s)
+			}
+			return
+		}
+	}
+	for _, instance := range strings.Split(s, ",") {
+		_, port, err := net.SplitHostPort(strings.TrimSpace(instance))
+		if err != nil {
+			v.errorf(key, "%v", err)
+			continue
+		}
+		v.port(key, port)
+	}
+}
+
+// distinctPorts checks that no two of ports, keyed by setting, are the same.
+func (v *validator) distinctPorts(ports map[string]string) {
+	keys := make([]string, 0, len(ports))
+	for key := range ports {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	seen := map[string]string{}
+	for _, key := range keys {
+		port := ports[key]
+		if port == "" {
+			continue
+		}
+		if other, ok := seen[port]; ok {
+			v.errorf(other+" and "+key, "both use port %s", port)
+			continue
+		}
+		seen[port] = key
+	}
+}
+
+func (v *validator) atLeast(key string, n, min float64) {
+	if n < min {
+		v.errorf(key, "%g is less than %g", n, min)
+	}
+}
+
+func (v *validator) between(key string, n, min, max float64) {
+	if n < min || n > max {
+		v.errorf(key, "%g is not between %g and %g", n, min, max)
+	}
+}
@@ -0,0 +1,197 @@
+// Package debuglog logs the full request and response payloads of endpoints,
+// for debugging. Logging is switched on and off per method at runtime through
+// the HTTP handler of a Registry.
+package debuglog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+
+	"github.com/cage1016/gokitconsulk8s/pkg/requestid"
+)
+
+// Path is where services serve the handler of their Registry, on their admin
+// server only since it exposes the payloads of every caller.
+const Path = "/debug/payloads"
+
+// redacted replaces the values of redacted fields.
+const redacted = "xxxxx"
+
+// Config configures the payloads logged by a Registry.
+type Config struct {
+	// Methods lists the methods logged from the start.
+	Methods []string
+	// MaxBytes truncates the payloads logged to that many bytes, 0 logs them
+	// whole.
+	MaxBytes int
+	// Redact lists the JSON fields, at any depth, whose values are not
+	// logged.
+	Redact []string
+}
+
+// Registry holds whether the payloads of each method are logged.
+type Registry struct {
+	maxBytes int
+	redact   map[string]bool
+
+	mtx     sync.RWMutex
+	enabled map[string]bool
+}
+
+// NewRegistry returns a Registry configured by cfg.
+func NewRegistry(cfg Config) *Registry {
+	r := &Registry{
+		maxBytes: cfg.MaxBytes,
+		redact:   map[string]bool{},
+		enabled:  map[string]bool{},
+	}
+	for _, f := range cfg.Redact {
+		r.redact[strings.ToLower(f)] = true
+	}
+	for _, m := range cfg.Methods {
+		r.enabled[m] = true
+	}
+	return r
+}
+
+// Middleware returns an endpoint middleware logging the request and the
+// response or error of method, when it is enabled.
+func (r *Registry) Middleware(method string, logger log.Logger) endpoint.Middleware {
+	r.mtx.Lock()
+	if _, ok := r.enabled[method]; !ok {
+		r.enabled[method] = false
+	}
+	r.mtx.Unlock()
+
+	logger = log.With(logger, "method", method, "debug", "payload")
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+			if !r.Enabled(method) {
+				return next(ctx, request)
+			}
+			defer func(begin time.Time) {
+				keyvals := []interface{}{"request_id", requestid.FromContext(ctx), "request", r.payload(request), "took", time.Since(begin)}
+				if err != nil {
+					keyvals = append(keyvals, "error", err)
+				} else {
+					keyvals = append(keyvals, "response", r.payload(response))
+				}
+				level.Info(logger).Log(keyvals...)
+			}(time.Now())
+			return next(ctx, request)
+		}
+	}
+}
+
+// Enabled tells whether the payloads of method are logged.
+func (r *Registry) Enabled(method string) bool {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+	return r.enabled[method]
+}
+
+// Set enables or disables the payload logging of known methods.
+func (r *Registry) Set(methods map[string]bool) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	var unknown []string
+	for m := range methods {
+		if _, ok := r.enabled[m]; !ok {
+			unknown = append(unknown, m)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("unknown methods: %s", strings.Join(unknown, ", "))
+	}
+	for m, on := range methods {
+		r.enabled[m] = on
+	}
+	return nil
+}
+
+// ServeHTTP replies to GET with whether each method is logged, as a JSON
+// object such as {"sum":true,"concat":false}, and to PUT with such an
+// object by enabling or disabling the methods it lists.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	switch req.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var methods map[string]bool
+		if err := json.NewDecoder(http.MaxBytesReader(w, req.Body, 1<<16)).Decode(&methods); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		if err := r.Set(methods); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	default:
+		w.Header().Set("Allow", http.MethodGet+", "+http.MethodPut)
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", req.Method))
+		return
+	}
+
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+	json.NewEncoder(w).Encode(r.enabled)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(struct {
+		Error string `json:"error"`
+	}{err.Error()})
+}
+
+// payload returns v as JSON, redacted and truncated.
+func (r *Registry) payload(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%+v", v)
+	}
+	if len(r.redact) > 0 {
+		// Numbers stay json.Number, as float64 would round int64 values
+		// above 2^53.
+		dec := json.NewDecoder(bytes.NewReader(b))
+		dec.UseNumber()
+		var doc interface{}
+		if err := dec.Decode(&doc); err == nil {
+			b, _ = json.Marshal(r.redactValue(doc))
+		}
+	}
+	if r.maxBytes > 0 && len(b) > r.maxBytes {
+		return fmt.Sprintf("%s...(%d bytes truncated)", b[:r.maxBytes], len(b)-r.maxBytes)
+	}
+	return string(b)
+}
+
+func (r *Registry) redactValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, e := range v {
+			if r.redact[strings.ToLower(k)] {
+				v[k] = redacted
+			} else {
+				v[k] = r.redactValue(e)
+			}
+		}
+	case []interface{}:
+		for i, e := range v {
+			v[i] = r.redactValue(e)
+		}
+	}
+	return v
+}
@@ -0,0 +1,53 @@
+package debuglog
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPayload(t *testing.T) {
+	type request struct {
+		A        int64   `json:"a"`
+		B        float64 `json:"b"`
+		Password string  `json:"password"`
+		Nested   struct {
+			Token string  `json:"Token"`
+			IDs   []int64 `json:"ids"`
+		} `json:"nested"`
+	}
+	req := request{A: math.MaxInt64, B: 0.5, Password: "hunter2"}
+	req.Nested.Token = "t0k3n"
+	req.Nested.IDs = []int64{math.MinInt64, 1<<53 + 1}
+
+	for _, tc := range []struct {
+		name string
+		cfg  Config
+		v    interface{}
+		want string
+	}{
+		{
+			name: "plain",
+			v:    req,
+			want: `{"a":9223372036854775807,"b":0.5,"password":"hunter2","nested":{"Token":"t0k3n","ids":[-9223372036854775808,9007199254740993]}}`,
+		},
+		{
+			name: "redacted",
+			cfg:  Config{Redact: []string{"password", "token"}},
+			v:    req,
+			want: `{"a":9223372036854775807,"b":0.5,"nested":{"Token":"xxxxx","ids":[-9223372036854775808,9007199254740993]},"password":"xxxxx"}`,
+		},
+		{
+			name: "truncated",
+			cfg:  Config{MaxBytes: 10, Redact: []string{"password"}},
+			v:    req,
+			want: `{"a":92233...(115 bytes truncated)`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := NewRegistry(tc.cfg).payload(tc.v)
+			if got != tc.want {
+				t.Errorf("payload\n%s\nwant\n%s", got, tc.want)
+			}
+		})
+	}
+}
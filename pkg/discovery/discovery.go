@@ -0,0 +1,81 @@
+package discovery
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/sd"
+	consulsd "github.com/go-kit/kit/sd/consul"
+	"github.com/go-kit/kit/sd/dnssrv"
+	"github.com/go-kit/kit/sd/lb"
+	consulapi "github.com/hashicorp/consul/api"
+)
+
+const (
+	schemeDNS    = "dns://"
+	schemeConsul = "consul://"
+
+	// dnsTTL is how often DNS SRV records are resolved again.
+	dnsTTL = 30 * time.Second
+)
+
+// Balancers supported by NewBalancer.
+const (
+	RoundRobin = "round_robin"
+	Random     = "random"
+)
+
+// NewInstancer returns an sd.Instancer for target, which takes one of the
+// following forms:
+//
+//	host:port[,host:port...]   a fixed list of instances
+//	dns://_grpc._tcp.addsvc    instances resolved from DNS SRV records
+//	consul://addsvc            passing instances of a Consul service, using
+//	                           the agent configured by CONSUL_HTTP_ADDR
+func NewInstancer(target string, logger log.Logger) (sd.Instancer, error) {
+	switch {
+	case strings.HasPrefix(target, schemeDNS):
+		name := strings.TrimPrefix(target, schemeDNS)
+		if name == "" {
+			return nil, fmt.Errorf("missing DNS name in %q", target)
+		}
+		return dnssrv.NewInstancer(name, dnsTTL, log.With(logger, "sd", "dns")), nil
+	case strings.HasPrefix(target, schemeConsul):
+		name := strings.TrimPrefix(target, schemeConsul)
+		if name == "" {
+			return nil, fmt.Errorf("missing service name in %q", target)
+		}
+		client, err := consulapi.NewClient(consulapi.DefaultConfig())
+		if err != nil {
+			return nil, err
+		}
+		return consulsd.NewInstancer(consulsd.NewClient(client), log.With(logger, "sd", "consul"), name, nil, true), nil
+	default:
+		var instances []string
+		for _, instance := range strings.Split(target, ",") {
+			if instance = strings.TrimSpace(instance); instance != "" {
+				instances = append(instances, instance)
+			}
+		}
+		if len(instances) == 0 {
+			return nil, fmt.Errorf("no instances in %q", target)
+		}
+		return sd.FixedInstancer(instances), nil
+	}
+}
+
+// NewBalancer returns a constructor for the named lb.Balancer.
+func NewBalancer(name string) (func(sd.Endpointer) lb.Balancer, error) {
+	switch name {
+	case RoundRobin:
+		return lb.NewRoundRobin, nil
+	case Random:
+		return func(s sd.Endpointer) lb.Balancer {
+			return lb.NewRandom(s, time.Now().UnixNano())
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown balancer %q, want %s or %s", name, RoundRobin, Random)
+	}
+}
@@ -0,0 +1,126 @@
+package discovery
+
+import (
+	"sync"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/sd"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/balancer/roundrobin"
+	"google.golang.org/grpc/grpclog"
+	"google.golang.org/grpc/resolver"
+)
+
+// grpcScheme is the scheme of the gRPC targets resolved by NewInstancer.
+const grpcScheme = "sd"
+
+func init() {
+	resolver.Register(grpcResolverBuilder{})
+}
+
+// Dial returns a gRPC connection to the instances target resolves to, as
+// described by NewInstancer, dialed with options. Calls are spread over the
+// instances in turn, and follow them as they come and go.
+func Dial(target string, options ...grpc.DialOption) (*grpc.ClientConn, error) {
+	return grpc.Dial(grpcScheme+":///"+target, append(options, grpc.WithBalancerName(roundrobin.Name))...)
+}
+
+// Conns holds one connection, made by Dial, to each of the targets it is asked
+// for, so that the calls to a target share its connection.
+type Conns struct {
+	options []grpc.DialOption
+
+	mtx   sync.Mutex
+	conns map[string]*grpc.ClientConn
+}
+
+// NewConns returns Conns dialing targets with options.
+func NewConns(options ...grpc.DialOption) *Conns {
+	return &Conns{options: options, conns: map[string]*grpc.ClientConn{}}
+}
+
+// Get returns the connection to target, dialing it on first use.
+func (c *Conns) Get(target string) (*grpc.ClientConn, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if conn, ok := c.conns[target]; ok {
+		return conn, nil
+	}
+	conn, err := Dial(target, c.options...)
+	if err != nil {
+		return nil, err
+	}
+	c.conns[target] = conn
+	return conn, nil
+}
+
+// Close closes every connection.
+func (c *Conns) Close() {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	for target, conn := range c.conns {
+		conn.Close()
+		delete(c.conns, target)
+	}
+}
+
+// grpcResolverBuilder builds the resolvers of the targets given to Dial.
+type grpcResolverBuilder struct{}
+
+func (grpcResolverBuilder) Build(target resolver.Target, cc resolver.ClientConn, _ resolver.BuildOption) (resolver.Resolver, error) {
+	instancer, err := NewInstancer(target.Endpoint, log.NewNopLogger())
+	if err != nil {
+		return nil, err
+	}
+	r := &grpcResolver{
+		target:    target.Endpoint,
+		instancer: instancer,
+		events:    make(chan sd.Event),
+		done:      make(chan struct{}),
+	}
+	go r.watch(cc)
+	instancer.Register(r.events)
+	return r, nil
+}
+
+func (grpcResolverBuilder) Scheme() string {
+	return grpcScheme
+}
+
+// grpcResolver passes the instances of its Instancer on to gRPC. On discovery
+// errors the instances last found are kept.
+type grpcResolver struct {
+	target    string
+	instancer sd.Instancer
+	events    chan sd.Event
+	done      chan struct{}
+}
+
+func (r *grpcResolver) watch(cc resolver.ClientConn) {
+	for {
+		select {
+		case e := <-r.events:
+			if e.Err != nil {
+				grpclog.Warningf("discovery: resolving %s: %v", r.target, e.Err)
+				continue
+			}
+			addrs := make([]resolver.Address, len(e.Instances))
+			for i, instance := range e.Instances {
+				addrs[i] = resolver.Address{Addr: instance}
+			}
+			cc.UpdateState(resolver.State{Addresses: addrs})
+		case <-r.done:
+			return
+		}
+	}
+}
+
+func (r *grpcResolver) ResolveNow(resolver.ResolveNowOption) {}
+
+// Close stops watching the instances. The resolver is deregistered before
+// watch returns, since the Instancer may be sending it an event.
+func (r *grpcResolver) Close() {
+	r.instancer.Deregister(r.events)
+	close(r.done)
+	r.instancer.Stop()
+}
@@ -0,0 +1,300 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/go-kit/kit/sd"
+	"google.golang.org/grpc"
+	healthgrpc "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// HealthChecker reports whether the instances of an OutlierInstancer are able
+// to serve traffic.
+type HealthChecker interface {
+	// Check reports whether instance is able to serve traffic.
+	Check(ctx context.Context, instance string) error
+	// Forget releases what the checker holds for instance, which is no longer
+	// published by the source.
+	Forget(instance string)
+}
+
+// GRPCHealthChecker returns a HealthChecker that queries the standard gRPC
+// health service of an instance for service, over one connection per
+// instance.
+func GRPCHealthChecker(service string) HealthChecker {
+	return &grpcHealthChecker{service: service, conns: map[string]*grpc.ClientConn{}}
+}
+
+type grpcHealthChecker struct {
+	service string
+
+	mtx   sync.Mutex
+	conns map[string]*grpc.ClientConn
+}
+
+func (c *grpcHealthChecker) Check(ctx context.Context, instance string) error {
+	conn, err := c.conn(instance)
+	if err != nil {
+		return err
+	}
+	resp, err := healthgrpc.NewHealthClient(conn).Check(ctx, &healthgrpc.HealthCheckRequest{Service: c.service})
+	if err != nil {
+		return err
+	}
+	if resp.Status != healthgrpc.HealthCheckResponse_SERVING {
+		return fmt.Errorf("health status %s", resp.Status)
+	}
+	return nil
+}
+
+// conn returns the connection to instance, dialing it on first use. The
+// connection reconnects by itself after failures.
+func (c *grpcHealthChecker) conn(instance string) (*grpc.ClientConn, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if conn, ok := c.conns[instance]; ok {
+		return conn, nil
+	}
+	conn, err := grpc.Dial(instance, grpc.WithInsecure())
+	if err != nil {
+		return nil, err
+	}
+	c.conns[instance] = conn
+	return conn, nil
+}
+
+func (c *grpcHealthChecker) Forget(instance string) {
+	c.mtx.Lock()
+	conn, ok := c.conns[instance]
+	delete(c.conns, instance)
+	c.mtx.Unlock()
+	if ok {
+		conn.Close()
+	}
+}
+
+// OutlierInstancer wraps an sd.Instancer and health checks every instance it
+// publishes. Instances failing a check are ejected from the published set for
+// the ejection time. When every instance would be ejected the full set is
+// published instead, so callers keep trying rather than failing outright.
+type OutlierInstancer struct {
+	src      sd.Instancer
+	check    HealthChecker
+	interval time.Duration
+	ejection time.Duration
+	logger   log.Logger
+
+	mtx       sync.Mutex
+	instances []string
+	err       error
+	ejected   map[string]time.Time
+	published sd.Event
+	registry  map[chan<- sd.Event]struct{}
+
+	events chan sd.Event
+	quit   chan struct{}
+}
+
+// NewOutlierInstancer returns an OutlierInstancer that checks the instances of
+// src every interval and ejects failing ones for ejection.
+func NewOutlierInstancer(src sd.Instancer, check HealthChecker, interval, ejection time.Duration, logger log.Logger) *OutlierInstancer {
+	o := &OutlierInstancer{
+		src:      src,
+		check:    check,
+		interval: interval,
+		ejection: ejection,
+		logger:   logger,
+		ejected:  map[string]time.Time{},
+		registry: map[chan<- sd.Event]struct{}{},
+		events:   make(chan sd.Event),
+		quit:     make(chan struct{}),
+	}
+	go o.loop()
+	src.Register(o.events)
+	return o
+}
+
+// Register implements sd.Instancer.
+func (o *OutlierInstancer) Register(ch chan<- sd.Event) {
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+	o.registry[ch] = struct{}{}
+	ch <- o.published
+}
+
+// Deregister implements sd.Instancer.
+func (o *OutlierInstancer) Deregister(ch chan<- sd.Event) {
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+	delete(o.registry, ch)
+}
+
+// Stop implements sd.Instancer.
+func (o *OutlierInstancer) Stop() {
+	o.src.Deregister(o.events)
+	close(o.quit)
+}
+
+// checkRound holds the results of health checking instances.
+type checkRound struct {
+	instances []string
+	failed    []error
+}
+
+// loop applies the events of the source as they come, while health checks run
+// in the background, one round at a time.
+func (o *OutlierInstancer) loop() {
+	ticker := time.NewTicker(o.interval)
+	defer ticker.Stop()
+
+	var (
+		rounds   = make(chan checkRound, 1)
+		checking bool
+	)
+	for {
+		select {
+		case event := <-o.events:
+			o.mtx.Lock()
+			var gone []string
+			for _, instance := range o.instances {
+				if !contains(event.Instances, instance) {
+					gone = append(gone, instance)
+				}
+			}
+			o.instances, o.err = event.Instances, event.Err
+			o.mtx.Unlock()
+			for _, instance := range gone {
+				o.check.Forget(instance)
+			}
+			o.publish()
+		case <-ticker.C:
+			if checking {
+				continue
+			}
+			checking = true
+			o.mtx.Lock()
+			instances := append([]string(nil), o.instances...)
+			o.mtx.Unlock()
+			go func() { rounds <- o.checkAll(instances) }()
+		case round := <-rounds:
+			checking = false
+			o.eject(round)
+			o.publish()
+		case <-o.quit:
+			o.mtx.Lock()
+			instances := o.instances
+			o.mtx.Unlock()
+			for _, instance := range instances {
+				o.check.Forget(instance)
+			}
+			return
+		}
+	}
+}
+
+// checkAll health checks instances concurrently.
+func (o *OutlierInstancer) checkAll(instances []string) checkRound {
+	var (
+		wg    sync.WaitGroup
+		round = checkRound{instances: instances, failed: make([]error, len(instances))}
+	)
+	for i, instance := range instances {
+		wg.Add(1)
+		go func(i int, instance string) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), o.interval)
+			defer cancel()
+			round.failed[i] = o.check.Check(ctx, instance)
+		}(i, instance)
+	}
+	wg.Wait()
+	return round
+}
+
+// eject ejects the instances that failed their check in round and keeps
+// ejected ones out until their ejection time has passed. Instances the source
+// dropped while they were checked are left alone.
+func (o *OutlierInstancer) eject(round checkRound) {
+	now := time.Now()
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+	for instance := range o.ejected {
+		if !contains(o.instances, instance) {
+			delete(o.ejected, instance)
+		}
+	}
+	for i, instance := range round.instances {
+		if !contains(o.instances, instance) {
+			continue
+		}
+		if err := round.failed[i]; err != nil {
+			if _, ok := o.ejected[instance]; !ok {
+				level.Warn(o.logger).Log("instance", instance, "ejected", o.ejection, "err", err)
+			}
+			o.ejected[instance] = now.Add(o.ejection)
+			continue
+		}
+		if until, ok := o.ejected[instance]; ok && now.After(until) {
+			level.Info(o.logger).Log("instance", instance, "restored", true)
+			delete(o.ejected, instance)
+		}
+	}
+}
+
+// publish sends the current set of healthy instances to every registered
+// channel if it differs from the last published set.
+func (o *OutlierInstancer) publish() {
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+
+	var healthy []string
+	for _, instance := range o.instances {
+		if _, ok := o.ejected[instance]; !ok {
+			healthy = append(healthy, instance)
+		}
+	}
+	allEjected := len(healthy) == 0 && len(o.instances) > 0
+	if allEjected {
+		healthy = append(healthy, o.instances...)
+	}
+	sort.Strings(healthy)
+
+	event := sd.Event{Instances: healthy, Err: o.err}
+	if equal(event, o.published) {
+		return
+	}
+	if allEjected {
+		level.Warn(o.logger).Log("instances", len(healthy), "ejected", "all", "panic", true)
+	}
+	o.published = event
+	for ch := range o.registry {
+		ch <- event
+	}
+}
+
+func equal(a, b sd.Event) bool {
+	if a.Err != b.Err || len(a.Instances) != len(b.Instances) {
+		return false
+	}
+	for i := range a.Instances {
+		if a.Instances[i] != b.Instances[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func contains(instances []string, instance string) bool {
+	for _, i := range instances {
+		if i == instance {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,168 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/sd"
+)
+
+// fakeChecker fails the instances marked failing and blocks the checks of the
+// instances marked blocked until they are unblocked.
+type fakeChecker struct {
+	mtx       sync.Mutex
+	failing   map[string]bool
+	blocked   map[string]chan struct{}
+	forgotten []string
+}
+
+func newFakeChecker() *fakeChecker {
+	return &fakeChecker{failing: map[string]bool{}, blocked: map[string]chan struct{}{}}
+}
+
+func (c *fakeChecker) Check(ctx context.Context, instance string) error {
+	c.mtx.Lock()
+	failing, blocked := c.failing[instance], c.blocked[instance]
+	c.mtx.Unlock()
+	if blocked != nil {
+		<-blocked
+	}
+	if failing {
+		return errors.New("unhealthy")
+	}
+	return nil
+}
+
+func (c *fakeChecker) Forget(instance string) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.forgotten = append(c.forgotten, instance)
+}
+
+func (c *fakeChecker) fail(instance string, failing bool) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.failing[instance] = failing
+}
+
+// fakeInstancer publishes the instances it is given.
+type fakeInstancer struct {
+	mtx   sync.Mutex
+	event sd.Event
+	chans map[chan<- sd.Event]struct{}
+}
+
+func (i *fakeInstancer) Register(ch chan<- sd.Event) {
+	i.mtx.Lock()
+	defer i.mtx.Unlock()
+	i.chans[ch] = struct{}{}
+	ch <- i.event
+}
+
+func (i *fakeInstancer) Deregister(ch chan<- sd.Event) {
+	i.mtx.Lock()
+	defer i.mtx.Unlock()
+	delete(i.chans, ch)
+}
+
+func (i *fakeInstancer) Stop() {}
+
+func (i *fakeInstancer) publish(instances ...string) {
+	i.mtx.Lock()
+	defer i.mtx.Unlock()
+	i.event = sd.Event{Instances: instances}
+	for ch := range i.chans {
+		ch <- i.event
+	}
+}
+
+func newTestOutlierInstancer(check HealthChecker, ejection time.Duration, instances ...string) (*fakeInstancer, *OutlierInstancer, chan sd.Event) {
+	src := &fakeInstancer{event: sd.Event{Instances: instances}, chans: map[chan<- sd.Event]struct{}{}}
+	o := NewOutlierInstancer(src, check, 5*time.Millisecond, ejection, log.NewNopLogger())
+	events := make(chan sd.Event, 100)
+	o.Register(events)
+	return src, o, events
+}
+
+// waitFor waits for events to publish want.
+func waitFor(t *testing.T, events chan sd.Event, want ...string) {
+	t.Helper()
+	timeout := time.After(2 * time.Second)
+	var last sd.Event
+	for {
+		select {
+		case last = <-events:
+			if fmt.Sprint(last.Instances) == fmt.Sprint(want) {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("published %v, want %v", last.Instances, want)
+		}
+	}
+}
+
+func TestOutlierInstancer(t *testing.T) {
+	check := newFakeChecker()
+	_, o, events := newTestOutlierInstancer(check, 50*time.Millisecond, "a", "b", "c")
+	defer o.Stop()
+	waitFor(t, events, "a", "b", "c")
+
+	check.fail("b", true)
+	waitFor(t, events, "a", "c")
+
+	// b stays out for the ejection time once healthy again.
+	check.fail("b", false)
+	begin := time.Now()
+	waitFor(t, events, "a", "b", "c")
+	if d := time.Since(begin); d < 25*time.Millisecond {
+		t.Errorf("restored after %v, want about the ejection time", d)
+	}
+
+	// Every instance failing publishes them all.
+	check.fail("a", true)
+	check.fail("b", true)
+	waitFor(t, events, "c")
+	check.fail("c", true)
+	waitFor(t, events, "a", "b", "c")
+	select {
+	case e := <-events:
+		t.Errorf("published %v with every instance ejected", e.Instances)
+	case <-time.After(50 * time.Millisecond):
+	}
+	check.fail("c", false)
+	waitFor(t, events, "c")
+}
+
+func TestOutlierInstancerEventsDuringChecks(t *testing.T) {
+	check := newFakeChecker()
+	unblock := make(chan struct{})
+	check.blocked["a"] = unblock
+	check.fail("a", true)
+	src, o, events := newTestOutlierInstancer(check, time.Minute, "a", "b")
+	defer o.Stop()
+	waitFor(t, events, "a", "b")
+
+	// Let the check of a start, then change the instances while it runs.
+	time.Sleep(20 * time.Millisecond)
+	src.publish("b", "c")
+	waitFor(t, events, "b", "c")
+
+	close(unblock)
+	select {
+	case e := <-events:
+		t.Errorf("published %v after the check of a dropped instance", e.Instances)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	check.mtx.Lock()
+	forgotten := fmt.Sprint(check.forgotten)
+	check.mtx.Unlock()
+	if forgotten != "[a]" {
+		t.Errorf("forgot %s, want [a]", forgotten)
+	}
+}
@@ -0,0 +1,266 @@
+// Package fault injects delays and errors into the calls of endpoints, to
+// check how their callers cope with a slow or failing service without
+// deploying broken code.
+//
+// Faults are written as method=fault[,fault...][;method=...], where a fault is
+// delay:<duration>[:<percent>] or abort:<gRPC code>[:<percent>], the percent
+// of calls affected defaulting to 100, e.g.
+//
+//	sum=delay:200ms:50,abort:unavailable:10;foo=abort:deadline_exceeded
+//
+// They come from the configuration of a service and, when allowed, from the
+// X-Fault header or x-fault gRPC metadata of a call, which is passed on to the
+// services it calls. Edges facing untrusted callers drop them with StripHTTP
+// and StripStreamServerInterceptor.
+package fault
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	kitgrpc "github.com/go-kit/kit/transport/grpc"
+	kithttp "github.com/go-kit/kit/transport/http"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// Header is the HTTP header of the faults of a call.
+	Header = "X-Fault"
+	// MetadataKey is the gRPC metadata key of the faults of a call.
+	MetadataKey = "x-fault"
+)
+
+// Rule holds the faults injected into the calls of a method.
+type Rule struct {
+	Delay        time.Duration
+	DelayPercent float64
+	Abort        codes.Code
+	AbortPercent float64
+}
+
+// Parse parses faults written as described in the package documentation into
+// the rule of each method.
+func Parse(s string) (map[string]Rule, error) {
+	rules := map[string]Rule{}
+	for _, e := range strings.Split(s, ";") {
+		if e = strings.TrimSpace(e); e == "" {
+			continue
+		}
+		i := strings.Index(e, "=")
+		if i < 0 {
+			return nil, fmt.Errorf("%q is not method=fault[,fault...]", e)
+		}
+		method := strings.TrimSpace(e[:i])
+		r := rules[method]
+		for _, f := range strings.Split(e[i+1:], ",") {
+			parts := strings.Split(strings.TrimSpace(f), ":")
+			if len(parts) < 2 || len(parts) > 3 {
+				return nil, fmt.Errorf("%q is not delay:<duration>[:<percent>] or abort:<code>[:<percent>]", f)
+			}
+			percent := 100.
+			if len(parts) == 3 {
+				var err error
+				if percent, err = strconv.ParseFloat(parts[2], 64); err != nil || percent < 0 || percent > 100 {
+					return nil, fmt.Errorf("%q: percent %q is not between 0 and 100", f, parts[2])
+				}
+			}
+			switch parts[0] {
+			case "delay":
+				d, err := time.ParseDuration(parts[1])
+				if err != nil || d < 0 {
+					return nil, fmt.Errorf("%q: %q is not a positive duration", f, parts[1])
+				}
+				r.Delay, r.DelayPercent = d, percent
+			case "abort":
+				var code codes.Code
+				if err := code.UnmarshalJSON([]byte(strconv.Quote(strings.ToUpper(parts[1])))); err != nil || code == codes.OK {
+					return nil, fmt.Errorf("%q: %q is not a gRPC error code", f, parts[1])
+				}
+				r.Abort, r.AbortPercent = code, percent
+			default:
+				return nil, fmt.Errorf("%q: unknown fault %q, want delay or abort", f, parts[0])
+			}
+		}
+		rules[method] = r
+	}
+	return rules, nil
+}
+
+// Config configures an Injector.
+type Config struct {
+	// Enabled turns fault injection on, it is off by default.
+	Enabled bool
+	// Headers lets calls ask for faults in their X-Fault header.
+	Headers bool
+	// Rules are the faults injected into every call, see Parse.
+	Rules string
+}
+
+// Injector injects faults into the calls of the methods of a service.
+type Injector struct {
+	enabled bool
+	headers bool
+	rules   map[string]Rule
+}
+
+// NewInjector returns an Injector configured by cfg.
+func NewInjector(cfg Config) (*Injector, error) {
+	rules, err := Parse(cfg.Rules)
+	if err != nil {
+		return nil, err
+	}
+	return &Injector{enabled: cfg.Enabled, headers: cfg.Headers, rules: rules}, nil
+}
+
+// Middleware returns an endpoint middleware injecting the faults of method,
+// the configured ones or those of the call's X-Fault header, which take
+// precedence. It is a no-op unless the Injector is enabled. Put it outside the
+// circuit breaker of the endpoint, which would otherwise count the errors it
+// injects and reject the calls it spares.
+func (i *Injector) Middleware(method string, logger log.Logger) endpoint.Middleware {
+	if !i.enabled {
+		return func(next endpoint.Endpoint) endpoint.Endpoint { return next }
+	}
+	logger = log.With(logger, "method", method)
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			r, ok := i.rule(ctx, method, logger)
+			if !ok {
+				return next(ctx, request)
+			}
+			if r.Delay > 0 && hit(r.DelayPercent) {
+				level.Debug(logger).Log("fault", "delay", "delay", r.Delay)
+				select {
+				case <-time.After(r.Delay):
+				case <-ctx.Done():
+					return nil, ctx.Err()
+				}
+			}
+			if r.Abort != codes.OK && hit(r.AbortPercent) {
+				level.Debug(logger).Log("fault", "abort", "code", r.Abort)
+				return nil, status.Errorf(r.Abort, "fault injected into %s", method)
+			}
+			return next(ctx, request)
+		}
+	}
+}
+
+// rule returns the rule of method for the call of ctx.
+func (i *Injector) rule(ctx context.Context, method string, logger log.Logger) (Rule, bool) {
+	if s := FromContext(ctx); i.headers && s != "" {
+		rules, err := Parse(s)
+		if err != nil {
+			level.Warn(logger).Log("fault", "header", "err", err)
+		} else if r, ok := rules[method]; ok {
+			return r, true
+		}
+	}
+	r, ok := i.rules[method]
+	return r, ok
+}
+
+func hit(percent float64) bool {
+	return percent >= 100 || rand.Float64()*100 < percent
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying the faults s.
+func NewContext(ctx context.Context, s string) context.Context {
+	return context.WithValue(ctx, contextKey{}, s)
+}
+
+// FromContext returns the faults of ctx, empty when there are none.
+func FromContext(ctx context.Context) string {
+	s, _ := ctx.Value(contextKey{}).(string)
+	return s
+}
+
+// HTTPToContext moves the X-Fault header of an HTTP request into the
+// context. Use it as a ServerBefore option of Go kit HTTP servers.
+func HTTPToContext() kithttp.RequestFunc {
+	return func(ctx context.Context, r *http.Request) context.Context {
+		if s := r.Header.Get(Header); s != "" {
+			return NewContext(ctx, s)
+		}
+		return ctx
+	}
+}
+
+// ContextToHTTP sets the faults of the context in the X-Fault header of an
+// HTTP request. Use it as a ClientBefore option of Go kit HTTP clients.
+func ContextToHTTP() kithttp.RequestFunc {
+	return func(ctx context.Context, r *http.Request) context.Context {
+		if s := FromContext(ctx); s != "" {
+			r.Header.Set(Header, s)
+		}
+		return ctx
+	}
+}
+
+// GRPCToContext moves the x-fault metadata into the context. Use it as a
+// ServerBefore option of Go kit gRPC servers.
+func GRPCToContext() kitgrpc.ServerRequestFunc {
+	return func(ctx context.Context, md metadata.MD) context.Context {
+		if vs := md.Get(MetadataKey); len(vs) > 0 && vs[0] != "" {
+			return NewContext(ctx, vs[0])
+		}
+		return ctx
+	}
+}
+
+// ContextToGRPC sets the faults of the context in the x-fault metadata. Use
+// it as a ClientBefore option of Go kit gRPC clients.
+func ContextToGRPC() kitgrpc.ClientRequestFunc {
+	return func(ctx context.Context, md *metadata.MD) context.Context {
+		if s := FromContext(ctx); s != "" {
+			md.Set(MetadataKey, s)
+		}
+		return ctx
+	}
+}
+
+// StripHTTP returns a handler removing the X-Fault header of the requests it
+// passes on to next.
+func StripHTTP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.Header[Header]; ok {
+			r = r.Clone(r.Context())
+			r.Header.Del(Header)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// StripStreamServerInterceptor is the gRPC counterpart of StripHTTP. It
+// removes the x-fault metadata from the incoming metadata of the call, which
+// the interceptors after it and the proxy forwarding the call read.
+func StripStreamServerInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx := ss.Context()
+	if md, ok := metadata.FromIncomingContext(ctx); ok && len(md.Get(MetadataKey)) > 0 {
+		md = md.Copy()
+		delete(md, MetadataKey)
+		ss = &serverStream{ServerStream: ss, ctx: metadata.NewIncomingContext(ctx, md)}
+	}
+	return handler(srv, ss)
+}
+
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
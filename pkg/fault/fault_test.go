@@ -0,0 +1,96 @@
+package fault
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestParse(t *testing.T) {
+	for _, c := range []struct {
+		s    string
+		want map[string]Rule
+		err  bool
+	}{
+		{s: "", want: map[string]Rule{}},
+		{s: " ; ", want: map[string]Rule{}},
+		{s: "sum=delay:200ms", want: map[string]Rule{"sum": {Delay: 200 * time.Millisecond, DelayPercent: 100}}},
+		{s: "sum=abort:unavailable:10", want: map[string]Rule{"sum": {Abort: codes.Unavailable, AbortPercent: 10}}},
+		{
+			s: "sum=delay:200ms:50,abort:unavailable:10;foo=abort:DEADLINE_EXCEEDED",
+			want: map[string]Rule{
+				"sum": {Delay: 200 * time.Millisecond, DelayPercent: 50, Abort: codes.Unavailable, AbortPercent: 10},
+				"foo": {Abort: codes.DeadlineExceeded, AbortPercent: 100},
+			},
+		},
+		{s: " sum = delay:1s:0 ; sum=abort:internal:100", want: map[string]Rule{"sum": {Delay: time.Second, DelayPercent: 0, Abort: codes.Internal, AbortPercent: 100}}},
+		{s: "sum=delay:1s,delay:2s:5", want: map[string]Rule{"sum": {Delay: 2 * time.Second, DelayPercent: 5}}},
+		{s: "sum", err: true},
+		{s: "sum=", err: true},
+		{s: "sum=delay", err: true},
+		{s: "sum=delay:1s:50:1", err: true},
+		{s: "sum=delay:soon", err: true},
+		{s: "sum=delay:-1s", err: true},
+		{s: "sum=abort:ok", err: true},
+		{s: "sum=abort:broken", err: true},
+		{s: "sum=abort:unavailable:101", err: true},
+		{s: "sum=abort:unavailable:-1", err: true},
+		{s: "sum=abort:unavailable:half", err: true},
+		{s: "sum=crash:now", err: true},
+	} {
+		got, err := Parse(c.s)
+		if (err != nil) != c.err {
+			t.Errorf("Parse(%q) error %v, want error %v", c.s, err, c.err)
+			continue
+		}
+		if !c.err && !reflect.DeepEqual(got, c.want) {
+			t.Errorf("Parse(%q) = %+v, want %+v", c.s, got, c.want)
+		}
+	}
+}
+
+func TestStripHTTP(t *testing.T) {
+	var got http.Header
+	h := StripHTTP(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header
+	}))
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set(Header, "sum=abort:unavailable")
+	r.Header.Set("X-Request-ID", "a")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	if _, ok := got[Header]; ok {
+		t.Errorf("%s passed on", Header)
+	}
+	if got.Get("X-Request-ID") != "a" {
+		t.Errorf("X-Request-ID dropped")
+	}
+	if r.Header.Get(Header) == "" {
+		t.Errorf("headers of the caller's request changed")
+	}
+}
+
+func TestStripStreamServerInterceptor(t *testing.T) {
+	md := metadata.Pairs(MetadataKey, "sum=abort:unavailable", "x-request-id", "a")
+	ss := &serverStream{ctx: metadata.NewIncomingContext(context.Background(), md)}
+	var got metadata.MD
+	err := StripStreamServerInterceptor(nil, ss, &grpc.StreamServerInfo{}, func(srv interface{}, ss grpc.ServerStream) error {
+		got, _ = metadata.FromIncomingContext(ss.Context())
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, metadata.Pairs("x-request-id", "a")) {
+		t.Errorf("metadata %v passed on", got)
+	}
+	if len(md.Get(MetadataKey)) == 0 {
+		t.Errorf("metadata of the caller changed")
+	}
+}
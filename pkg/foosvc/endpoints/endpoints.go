@@ -2,20 +2,18 @@ package endpoints
 
 import (
 	"context"
-	"time"
 
-	"github.com/go-kit/kit/circuitbreaker"
 	"github.com/go-kit/kit/endpoint"
 	"github.com/go-kit/kit/log"
-	"github.com/go-kit/kit/ratelimit"
 	"github.com/go-kit/kit/tracing/opentracing"
 	"github.com/go-kit/kit/tracing/zipkin"
 	stdopentracing "github.com/opentracing/opentracing-go"
 	stdzipkin "github.com/openzipkin/zipkin-go"
-	"github.com/sony/gobreaker"
-	"golang.org/x/time/rate"
 
+	"github.com/cage1016/gokitconsulk8s/pkg/debuglog"
+	"github.com/cage1016/gokitconsulk8s/pkg/fault"
 	"github.com/cage1016/gokitconsulk8s/pkg/foosvc/service"
+	"github.com/cage1016/gokitconsulk8s/pkg/resilience"
 )
 
 // Endpoints collects all of the endpoints that compose the foosvc service. It's
@@ -26,16 +24,22 @@ type Endpoints struct {
 }
 
 // New return a new instance of the endpoint that wraps the provided service.
-func New(svc service.FoosvcService, logger log.Logger, otTracer stdopentracing.Tracer, zipkinTracer *stdzipkin.Tracer) (ep Endpoints) {
+// The rate limiter, circuit breaker and bulkhead of each method come from rc,
+// dl switches the logging of their payloads and fi injects faults into them.
+// Faults are injected outside the rate limiter, bulkhead and circuit breaker:
+// injected errors do not open the breaker of the method, only those of its
+// callers, whose handling of them is what fault injection tests.
+func New(svc service.FoosvcService, logger log.Logger, otTracer stdopentracing.Tracer, zipkinTracer *stdzipkin.Tracer, rc resilience.Config, dl *debuglog.Registry, fi *fault.Injector) (ep Endpoints) {
 	var fooEndpoint endpoint.Endpoint
 	{
 		method := "foo"
 		fooEndpoint = MakeFooEndpoint(svc)
-		fooEndpoint = ratelimit.NewErroringLimiter(rate.NewLimiter(rate.Every(time.Second), 100))(fooEndpoint)
-		fooEndpoint = circuitbreaker.Gobreaker(gobreaker.NewCircuitBreaker(gobreaker.Settings{}))(fooEndpoint)
+		fooEndpoint = rc.For(method).Middleware("foosvc."+method, logger)(fooEndpoint)
+		fooEndpoint = fi.Middleware(method, logger)(fooEndpoint)
 		fooEndpoint = opentracing.TraceServer(otTracer, method)(fooEndpoint)
 		fooEndpoint = zipkin.TraceEndpoint(zipkinTracer, method)(fooEndpoint)
 		fooEndpoint = LoggingMiddleware(log.With(logger, "method", method))(fooEndpoint)
+		fooEndpoint = dl.Middleware(method, logger)(fooEndpoint)
 		ep.FooEndpoint = fooEndpoint
 	}
 
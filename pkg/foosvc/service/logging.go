@@ -6,6 +6,8 @@ import (
 
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
+
+	"github.com/cage1016/gokitconsulk8s/pkg/requestid"
 )
 
 type loggingMiddleware struct {
@@ -23,7 +25,7 @@ func LoggingMiddleware(logger log.Logger) Middleware {
 
 func (lm loggingMiddleware) Foo(ctx context.Context, s string) (res string, err error) {
 	defer func(begin time.Time) {
-		lm.logger.Log("method", "Foo", "s", s, "err", err)
+		lm.logger.Log("request_id", requestid.FromContext(ctx), "method", "Foo", "s", s, "err", err)
 	}(time.Now())
 
 	return lm.next.Foo(ctx, s)
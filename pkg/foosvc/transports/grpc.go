@@ -2,26 +2,24 @@ package transports
 
 import (
 	"context"
-	"time"
 
-	"github.com/go-kit/kit/circuitbreaker"
 	"github.com/go-kit/kit/endpoint"
 	"github.com/go-kit/kit/log"
-	"github.com/go-kit/kit/ratelimit"
 	"github.com/go-kit/kit/tracing/opentracing"
 	"github.com/go-kit/kit/tracing/zipkin"
 	grpctransport "github.com/go-kit/kit/transport/grpc"
 	stdopentracing "github.com/opentracing/opentracing-go"
 	stdzipkin "github.com/openzipkin/zipkin-go"
-	"github.com/sony/gobreaker"
-	"golang.org/x/time/rate"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
 	pb "github.com/cage1016/gokitconsulk8s/pb/foosvc"
+	"github.com/cage1016/gokitconsulk8s/pkg/fault"
 	"github.com/cage1016/gokitconsulk8s/pkg/foosvc/endpoints"
 	"github.com/cage1016/gokitconsulk8s/pkg/foosvc/service"
+	"github.com/cage1016/gokitconsulk8s/pkg/requestid"
+	"github.com/cage1016/gokitconsulk8s/pkg/resilience"
 )
 
 type grpcServer struct {
@@ -51,6 +49,8 @@ func MakeGRPCServer(endpoints endpoints.Endpoints, otTracer stdopentracing.Trace
 
 	options := []grpctransport.ServerOption{
 		grpctransport.ServerErrorLogger(logger),
+		grpctransport.ServerBefore(requestid.GRPCToContext()),
+		grpctransport.ServerBefore(fault.GRPCToContext()),
 		zipkinServer,
 	}
 
@@ -78,16 +78,13 @@ func encodeGRPCFooResponse(_ context.Context, grpcReply interface{}) (res interf
 	return &pb.FooReply{Res: reply.Res}, grpcEncodeError(reply.Err)
 }
 
-// NewGRPCClient returns an AddService backed by a gRPC server at the other end
+// NewGRPCClient returns a FoosvcService backed by a gRPC server at the other end
 // of the conn. The caller is responsible for constructing the conn, and
 // eventually closing the underlying transport. We bake-in certain middlewares,
 // implementing the client library pattern.
-func NewGRPCClient(conn *grpc.ClientConn, otTracer stdopentracing.Tracer, zipkinTracer *stdzipkin.Tracer, logger log.Logger) service.FoosvcService { // We construct a single ratelimiter middleware, to limit the total outgoing
-	// QPS from this client to all methods on the remote instance. We also
-	// construct per-endpoint circuitbreaker middlewares to demonstrate how
-	// that's done, although they could easily be combined into a single breaker
-	// for the entire remote instance, too.
-	limiter := ratelimit.NewErroringLimiter(rate.NewLimiter(rate.Every(time.Second), 100))
+func NewGRPCClient(conn *grpc.ClientConn, otTracer stdopentracing.Tracer, zipkinTracer *stdzipkin.Tracer, logger log.Logger, rc resilience.Config) service.FoosvcService { // Every endpoint gets its own rate limiter, circuit breaker and bulkhead,
+	// configured per method by rc. Breakers are named after the remote
+	// instance so that their state can be told apart in logs and metrics.
 
 	// Zipkin GRPC Client Trace can either be instantiated per gRPC method with a
 	// provided operation name or a global tracing client can be instantiated
@@ -100,6 +97,8 @@ func NewGRPCClient(conn *grpc.ClientConn, otTracer stdopentracing.Tracer, zipkin
 
 	// global client middlewares
 	options := []grpctransport.ClientOption{
+		grpctransport.ClientBefore(requestid.ContextToGRPC()),
+		grpctransport.ClientBefore(fault.ContextToGRPC()),
 		zipkinClient,
 	}
 
@@ -117,11 +116,7 @@ func NewGRPCClient(conn *grpc.ClientConn, otTracer stdopentracing.Tracer, zipkin
 			append(options, grpctransport.ClientBefore(opentracing.ContextToGRPC(otTracer, logger)))...,
 		).Endpoint()
 		fooEndpoint = opentracing.TraceClient(otTracer, "Foo")(fooEndpoint)
-		fooEndpoint = limiter(fooEndpoint)
-		fooEndpoint = circuitbreaker.Gobreaker(gobreaker.NewCircuitBreaker(gobreaker.Settings{
-			Name:    "Foo",
-			Timeout: 30 * time.Second,
-		}))(fooEndpoint)
+		fooEndpoint = rc.For("foo").Middleware("foosvc.client.foo@"+conn.Target(), logger)(fooEndpoint)
 	}
 
 	return endpoints.Endpoints{
@@ -148,7 +143,7 @@ func grpcEncodeError(err error) error {
 		return nil
 	}
 
-	st, ok := status.FromError(err)
+	st, ok := status.FromError(resilience.Status(err))
 	if ok {
 		return status.Error(st.Code(), st.Message())
 	}
@@ -4,47 +4,54 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
-	"errors"
-	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
 	"strings"
-	"time"
 
-	"github.com/go-kit/kit/circuitbreaker"
 	"github.com/go-kit/kit/endpoint"
 	"github.com/go-kit/kit/log"
-	"github.com/go-kit/kit/ratelimit"
 	"github.com/go-kit/kit/sd/lb"
 	"github.com/go-kit/kit/tracing/opentracing"
 	"github.com/go-kit/kit/tracing/zipkin"
 	httptransport "github.com/go-kit/kit/transport/http"
 	stdopentracing "github.com/opentracing/opentracing-go"
 	stdzipkin "github.com/openzipkin/zipkin-go"
-	"github.com/sony/gobreaker"
-	"golang.org/x/time/rate"
 	"google.golang.org/grpc/status"
 
+	pb "github.com/cage1016/gokitconsulk8s/pb/foosvc"
+	"github.com/cage1016/gokitconsulk8s/pkg/fault"
 	"github.com/cage1016/gokitconsulk8s/pkg/foosvc/endpoints"
 	"github.com/cage1016/gokitconsulk8s/pkg/foosvc/service"
+	"github.com/cage1016/gokitconsulk8s/pkg/openapi"
+	"github.com/cage1016/gokitconsulk8s/pkg/requestid"
+	"github.com/cage1016/gokitconsulk8s/pkg/resilience"
+	"github.com/cage1016/gokitconsulk8s/pkg/transport/httpstatus"
+	"github.com/cage1016/gokitconsulk8s/pkg/transport/negotiation"
 )
 
 type errorWrapper struct {
 	Error string `json:"error"`
 }
 
+// JSONErrorDecoder decodes the error of a failed response as a gRPC status
+// whose code is derived from the response status. A full bulkhead is decoded
+// as resilience.ErrBulkheadFull.
 func JSONErrorDecoder(r *http.Response) error {
+	code := httpstatus.Code(r.StatusCode)
 	contentType := r.Header.Get("Content-Type")
 	if !strings.Contains(contentType, "application/json") {
-		return fmt.Errorf("expected JSON formatted error, got Content-Type %s", contentType)
+		return status.Errorf(code, "expected JSON formatted error, got Content-Type %s", contentType)
 	}
 	var w errorWrapper
 	if err := json.NewDecoder(r.Body).Decode(&w); err != nil {
-		return err
+		return status.Error(code, err.Error())
+	}
+	if r.StatusCode == http.StatusServiceUnavailable && w.Error == status.Convert(resilience.ErrBulkheadFull).Message() {
+		return resilience.ErrBulkheadFull
 	}
-	return errors.New(w.Error)
+	return status.Error(code, w.Error)
 }
 
 // NewHTTPHandler returns a handler that makes a set of endpoints available on
@@ -59,32 +66,70 @@ func NewHTTPHandler(endpoints endpoints.Endpoints, otTracer stdopentracing.Trace
 	options := []httptransport.ServerOption{
 		httptransport.ServerErrorEncoder(httpEncodeError),
 		httptransport.ServerErrorLogger(logger),
+		httptransport.ServerBefore(requestid.HTTPToContext()),
+		httptransport.ServerBefore(fault.HTTPToContext()),
 		zipkinServer,
 	}
 
 	m := http.NewServeMux()
-	m.Handle("/foo", httptransport.NewServer(
+	m.Handle("/foo", negotiation.Handler(httptransport.NewServer(
 		endpoints.FooEndpoint,
 		decodeHTTPFooRequest,
-		httptransport.EncodeJSONResponse,
+		encodeHTTPFooResponse,
 		append(options, httptransport.ServerBefore(opentracing.HTTPToContext(otTracer, "Foo", logger)))...,
-	))
+	), httpEncodeError))
+
+	m.Handle(openapi.Path, OpenAPI())
 	return m
 }
 
+// httpOperations describes the routes of NewHTTPHandler in its OpenAPI
+// document.
+var httpOperations = []openapi.Operation{
+	{
+		Path:          "/foo",
+		ID:            "Foo",
+		Summary:       "Appends bar to a string.",
+		Request:       endpoints.FooRequest{},
+		Response:      endpoints.FooResponse{},
+		ProtoRequest:  &pb.FooRequest{},
+		ProtoResponse: &pb.FooReply{},
+	},
+}
+
+// OpenAPI returns the OpenAPI document of the HTTP API of foosvc.
+func OpenAPI() *openapi.Document {
+	return openapi.New("foosvc", "v1", httpOperations)
+}
+
 // decodeHTTPFooRequest is a transport/http.DecodeRequestFunc that decodes a
-// JSON-encoded request from the HTTP request body. Primarily useful in a server.
-func decodeHTTPFooRequest(_ context.Context, r *http.Request) (interface{}, error) {
+// Foo request from the query string or the JSON, protobuf or form body of
+// the HTTP request. Primarily useful in a server.
+func decodeHTTPFooRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	if negotiation.IsProtobuf(r) {
+		var msg pb.FooRequest
+		if err := negotiation.DecodeProtobuf(r, &msg); err != nil {
+			return nil, err
+		}
+		return decodeGRPCFooRequest(ctx, &msg)
+	}
 	var req endpoints.FooRequest
-	err := json.NewDecoder(r.Body).Decode(&req)
+	err := negotiation.DecodeBody(r, &req)
 	return req, err
 }
 
-// NewHTTPClient returns an AddService backed by an HTTP server living at the
+// encodeHTTPFooResponse is a transport/http.EncodeResponseFunc that encodes
+// a Foo response as JSON or protobuf, as negotiated with the client.
+// Primarily useful in a server.
+func encodeHTTPFooResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	return negotiation.EncodeResponse(ctx, w, response, encodeGRPCFooResponse)
+}
+
+// NewHTTPClient returns a FoosvcService backed by an HTTP server living at the
 // remote instance. We expect instance to come from a service discovery system,
 // so likely of the form "host:port". We bake-in certain middlewares,
 // implementing the client library pattern.
-func NewHTTPClient(instance string, otTracer stdopentracing.Tracer, zipkinTracer *stdzipkin.Tracer, logger log.Logger) (service.FoosvcService, error) { // Quickly sanitize the instance string.
+func NewHTTPClient(instance string, otTracer stdopentracing.Tracer, zipkinTracer *stdzipkin.Tracer, logger log.Logger, rc resilience.Config) (service.FoosvcService, error) { // Quickly sanitize the instance string.
 	if !strings.HasPrefix(instance, "http") {
 		instance = "http://" + instance
 	}
@@ -93,12 +138,9 @@ func NewHTTPClient(instance string, otTracer stdopentracing.Tracer, zipkinTracer
 		return nil, err
 	}
 
-	// We construct a single ratelimiter middleware, to limit the total outgoing
-	// QPS from this client to all methods on the remote instance. We also
-	// construct per-endpoint circuitbreaker middlewares to demonstrate how
-	// that's done, although they could easily be combined into a single breaker
-	// for the entire remote instance, too.
-	limiter := ratelimit.NewErroringLimiter(rate.NewLimiter(rate.Every(time.Second), 100))
+	// Every endpoint gets its own rate limiter, circuit breaker and bulkhead,
+	// configured per method by rc. Breakers are named after the remote
+	// instance so that their state can be told apart in logs and metrics.
 
 	// Zipkin HTTP Client Trace can either be instantiated per endpoint with a
 	// provided operation name or a global tracing client can be instantiated
@@ -108,6 +150,8 @@ func NewHTTPClient(instance string, otTracer stdopentracing.Tracer, zipkinTracer
 
 	// global client middlewares
 	options := []httptransport.ClientOption{
+		httptransport.ClientBefore(requestid.ContextToHTTP()),
+		httptransport.ClientBefore(fault.ContextToHTTP()),
 		zipkinClient,
 	}
 
@@ -130,11 +174,7 @@ func NewHTTPClient(instance string, otTracer stdopentracing.Tracer, zipkinTracer
 		).Endpoint()
 		fooEndpoint = opentracing.TraceClient(otTracer, "Foo")(fooEndpoint)
 		fooEndpoint = zipkin.TraceEndpoint(zipkinTracer, "Foo")(fooEndpoint)
-		fooEndpoint = limiter(fooEndpoint)
-		fooEndpoint = circuitbreaker.Gobreaker(gobreaker.NewCircuitBreaker(gobreaker.Settings{
-			Name:    "Foo",
-			Timeout: 30 * time.Second,
-		}))(fooEndpoint)
+		fooEndpoint = rc.For("foo").Middleware("foosvc.client.foo@"+u.Host, logger)(fooEndpoint)
 		e.FooEndpoint = fooEndpoint
 	}
 
@@ -144,10 +184,11 @@ func NewHTTPClient(instance string, otTracer stdopentracing.Tracer, zipkinTracer
 	return e, nil
 }
 
-//
+// copyURL returns base with path appended to its own path, so that a client
+// can reach the service behind a path prefix such as the router's.
 func copyURL(base *url.URL, path string) *url.URL {
 	next := *base
-	next.Path = path
+	next.Path = strings.TrimSuffix(base.Path, "/") + path
 	return &next
 }
 
@@ -158,12 +199,13 @@ func encodeHTTPFooRequest(_ context.Context, r *http.Request, request interface{
 	if err := json.NewEncoder(&buf).Encode(request); err != nil {
 		return err
 	}
+	r.Header.Set("Content-Type", negotiation.JSONContentType)
 	r.Body = ioutil.NopCloser(&buf)
 	return nil
 }
 
 // decodeHTTPFooResponse is a transport/http.DecodeResponseFunc that decodes a
-// JSON-encoded sum response from the HTTP response body. If the response has a
+// JSON-encoded foo response from the HTTP response body. If the response has a
 // non-200 status code, we will interpret that as an error and attempt to decode
 // the specific error message from the response body. Primarily useful in a client.
 func decodeHTTPFooResponse(_ context.Context, r *http.Response) (interface{}, error) {
@@ -178,14 +220,20 @@ func decodeHTTPFooResponse(_ context.Context, r *http.Response) (interface{}, er
 func httpEncodeError(_ context.Context, err error, w http.ResponseWriter) {
 	w.Header().Set("Content-Type", "application/json")
 
-	if lberr, ok := err.(lb.RetryError); ok {
+	if herr, ok := err.(negotiation.Error); ok {
+		w.WriteHeader(herr.Status)
+		json.NewEncoder(w).Encode(errorWrapper{Error: herr.Msg})
+	} else if resilience.BulkheadFull(err) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(errorWrapper{Error: status.Convert(resilience.ErrBulkheadFull).Message()})
+	} else if lberr, ok := err.(lb.RetryError); ok {
 		st, _ := status.FromError(lberr.Final)
-		w.WriteHeader(HTTPStatusFromCode(st.Code()))
+		w.WriteHeader(httpstatus.FromCode(st.Code()))
 		json.NewEncoder(w).Encode(errorWrapper{Error: st.Message()})
 	} else {
-		st, ok := status.FromError(err)
+		st, ok := status.FromError(resilience.Status(err))
 		if ok {
-			w.WriteHeader(HTTPStatusFromCode(st.Code()))
+			w.WriteHeader(httpstatus.FromCode(st.Code()))
 			json.NewEncoder(w).Encode(errorWrapper{Error: st.Message()})
 		} else {
 			switch err {
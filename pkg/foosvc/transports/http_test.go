@@ -0,0 +1,41 @@
+package transports
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	stdopentracing "github.com/opentracing/opentracing-go"
+	stdzipkin "github.com/openzipkin/zipkin-go"
+
+	addsvcservice "github.com/cage1016/gokitconsulk8s/pkg/addsvc/service"
+	"github.com/cage1016/gokitconsulk8s/pkg/debuglog"
+	"github.com/cage1016/gokitconsulk8s/pkg/fault"
+	"github.com/cage1016/gokitconsulk8s/pkg/foosvc/endpoints"
+	"github.com/cage1016/gokitconsulk8s/pkg/foosvc/service"
+	"github.com/cage1016/gokitconsulk8s/pkg/openapi/openapitest"
+	"github.com/cage1016/gokitconsulk8s/pkg/resilience"
+)
+
+func TestHTTPHandlerMatchesOpenAPI(t *testing.T) {
+	logger := log.NewNopLogger()
+	zipkinTracer, err := stdzipkin.NewTracer(nil, stdzipkin.WithNoopTracer(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fi, err := fault.NewInjector(fault.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracer := stdopentracing.NoopTracer{}
+	svc := service.New(addsvcservice.New(logger), logger)
+	eps := endpoints.New(svc, logger, tracer, zipkinTracer, resilience.Config{}, debuglog.NewRegistry(debuglog.Config{}), fi)
+	handler := NewHTTPHandler(eps, tracer, zipkinTracer, logger)
+
+	openapitest.Run(t, handler, OpenAPI(), []openapitest.Case{
+		{Name: "foo query", Method: http.MethodGet, Path: "/foo", Query: url.Values{"s": {"x"}}, Want: `{"res":"xbar","err":null}`},
+		{Name: "foo", Method: http.MethodPost, Path: "/foo", Body: `{"s":"y"}`, Want: `{"res":"ybar","err":null}`},
+		{Name: "foo empty", Method: http.MethodPost, Path: "/foo", Body: `{}`, Want: `{"res":"bar","err":null}`},
+	})
+}
@@ -0,0 +1,293 @@
+// Package openapi builds the OpenAPI 3 documents describing the JSON over HTTP
+// APIs of the services, from the request and response types of their
+// endpoints.
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Path is where the services and the router serve their OpenAPI document.
+const Path = "/openapi.json"
+
+// Operation describes a method of an HTTP API on Path: a GET whose query
+// parameters or a POST whose JSON or form body decode into Request, and whose
+// successful reply encodes Response. ProtoRequest and ProtoResponse are the
+// protobuf messages a POST may send and ask for instead.
+type Operation struct {
+	Path          string
+	ID            string
+	Summary       string
+	Request       interface{}
+	Response      interface{}
+	ProtoRequest  interface{}
+	ProtoResponse interface{}
+}
+
+// Document is an OpenAPI 3 document, limited to what the services use.
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+}
+
+// Info and the types below mirror the OpenAPI objects of the same name.
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type PathItem struct {
+	Get  *OperationObject `json:"get,omitempty"`
+	Post *OperationObject `json:"post,omitempty"`
+}
+
+type OperationObject struct {
+	OperationID string              `json:"operationId"`
+	Summary     string              `json:"summary,omitempty"`
+	Tags        []string            `json:"tags,omitempty"`
+	Parameters  []Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]Response `json:"responses"`
+}
+
+type Parameter struct {
+	Name   string  `json:"name"`
+	In     string  `json:"in"`
+	Schema *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas"`
+}
+
+// Schema is a JSON schema as understood by OpenAPI 3.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	OneOf                []*Schema          `json:"oneOf,omitempty"`
+}
+
+// Schemer is implemented by the types whose JSON encoding is not the one of
+// their kind, to describe it.
+type Schemer interface {
+	OpenAPISchema() *Schema
+}
+
+var schemerType = reflect.TypeOf((*Schemer)(nil)).Elem()
+
+const (
+	jsonContentType     = "application/json"
+	protobufContentType = "application/x-protobuf"
+	formContentType     = "application/x-www-form-urlencoded"
+	errorSchema         = "Error"
+)
+
+// New returns the document of the API title made of ops.
+func New(title, version string, ops []Operation) *Document {
+	d := &Document{
+		OpenAPI: "3.0.3",
+		Info:    Info{Title: title, Version: version},
+		Paths:   map[string]PathItem{},
+		Components: Components{Schemas: map[string]*Schema{
+			// Failed calls reply with the errorWrapper of the transports.
+			errorSchema: {
+				Type:       "object",
+				Properties: map[string]*Schema{"error": {Type: "string"}},
+				Required:   []string{"error"},
+			},
+		}},
+	}
+	for _, op := range ops {
+		request := d.schema(reflect.TypeOf(op.Request))
+		responses := map[string]Response{
+			"200": {
+				Description: op.ID + " succeeded",
+				Content: map[string]MediaType{
+					jsonContentType:     {Schema: d.schema(reflect.TypeOf(op.Response))},
+					protobufContentType: {Schema: protoSchema(op.ProtoResponse)},
+				},
+			},
+			"default": {
+				Description: op.ID + " failed, the status derives from the gRPC code of the error",
+				Content:     map[string]MediaType{jsonContentType: {Schema: ref(errorSchema)}},
+			},
+		}
+		d.Paths[op.Path] = PathItem{
+			Get: &OperationObject{
+				OperationID: op.ID + "Query",
+				Summary:     op.Summary,
+				Tags:        []string{title},
+				Parameters:  d.queryParameters(reflect.TypeOf(op.Request)),
+				Responses:   responses,
+			},
+			Post: &OperationObject{
+				OperationID: op.ID,
+				Summary:     op.Summary,
+				Tags:        []string{title},
+				RequestBody: &RequestBody{
+					Required: true,
+					Content: map[string]MediaType{
+						jsonContentType:     {Schema: request},
+						formContentType:     {Schema: request},
+						protobufContentType: {Schema: protoSchema(op.ProtoRequest)},
+					},
+				},
+				Responses: responses,
+			},
+		}
+	}
+	return d
+}
+
+// WithPrefix returns a copy of d whose paths start with prefix, as they do
+// behind the router.
+func (d *Document) WithPrefix(prefix string) *Document {
+	next := *d
+	next.Paths = make(map[string]PathItem, len(d.Paths))
+	for path, item := range d.Paths {
+		next.Paths[strings.TrimSuffix(prefix, "/")+path] = item
+	}
+	return &next
+}
+
+// Merge returns the document of the API title made of the paths and schemas
+// of docs. Schemas of the same name are expected to be the same.
+func Merge(title, version string, docs ...*Document) *Document {
+	d := New(title, version, nil)
+	for _, doc := range docs {
+		for path, item := range doc.Paths {
+			d.Paths[path] = item
+		}
+		for name, schema := range doc.Components.Schemas {
+			d.Components.Schemas[name] = schema
+		}
+	}
+	return d
+}
+
+// ServeHTTP serves d as JSON.
+func (d *Document) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", jsonContentType)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(d)
+}
+
+// schema returns the schema of t, adding the schemas of the structs it
+// refers to to the components of d.
+func (d *Document) schema(t reflect.Type) *Schema {
+	if t.Kind() != reflect.Ptr && t.Implements(schemerType) {
+		return reflect.Zero(t).Interface().(Schemer).OpenAPISchema()
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		s := *d.schema(t.Elem())
+		s.Nullable = true
+		return &s
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: d.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: d.schema(t.Elem())}
+	case reflect.Struct:
+		if _, ok := d.Components.Schemas[t.Name()]; !ok {
+			d.Components.Schemas[t.Name()] = &Schema{} // guards recursive types
+			d.Components.Schemas[t.Name()] = d.structSchema(t)
+		}
+		return ref(t.Name())
+	}
+	// Interfaces, such as the err field of the responses, are always null on
+	// success since errors are encoded as an Error reply.
+	return &Schema{Type: "object", Nullable: true}
+}
+
+func (d *Document) structSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		name := f.Name
+		if tag, ok := f.Tag.Lookup("json"); ok {
+			if tag == "-" {
+				continue
+			}
+			if n := strings.Split(tag, ",")[0]; n != "" {
+				name = n
+			}
+		}
+		s.Properties[name] = d.schema(f.Type)
+	}
+	return s
+}
+
+// queryParameters returns the query parameters of a GET, named after the JSON
+// names of the fields of the request struct t.
+func (d *Document) queryParameters(t reflect.Type) []Parameter {
+	s := d.Components.Schemas[t.Name()]
+	names := make([]string, 0, len(s.Properties))
+	for name := range s.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	params := make([]Parameter, 0, len(names))
+	for _, name := range names {
+		params = append(params, Parameter{Name: name, In: "query", Schema: s.Properties[name]})
+	}
+	return params
+}
+
+// protoSchema returns the schema of the protobuf encoding of msg.
+func protoSchema(msg interface{}) *Schema {
+	return &Schema{
+		Type:        "string",
+		Format:      "binary",
+		Description: "protobuf encoded " + strings.TrimPrefix(reflect.TypeOf(msg).String(), "*"),
+	}
+}
+
+func ref(name string) *Schema {
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
@@ -0,0 +1,314 @@
+// Package openapitest checks that an HTTP handler serves the API its OpenAPI
+// document describes, and nothing else.
+package openapitest
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/cage1016/gokitconsulk8s/pkg/openapi"
+)
+
+// Case is a call of an operation of the document, made by Run.
+type Case struct {
+	Name   string
+	Method string
+	Path   string
+	// Query holds the parameters of a GET.
+	Query url.Values
+	// Body is the JSON body of a POST.
+	Body string
+	// Status is the status of the reply, 200 when zero.
+	Status int
+	// Want is the JSON the handler replies with, not compared when empty.
+	Want string
+}
+
+// Run serves every case through handler and checks, in both directions, that
+// the requests and replies match doc: every operation of doc has a case and
+// every case calls an operation of doc; GET parameters and POST bodies are
+// those of the operation; replies have the JSON schema of their status, with
+// no property missing or left undocumented.
+func Run(t *testing.T, handler http.Handler, doc *openapi.Document, cases []Case) {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	covered := map[string]bool{}
+	for _, c := range cases {
+		op := operation(doc, c.Method, c.Path)
+		if op == nil {
+			t.Errorf("%s: %s %s is not in the document", c.Name, c.Method, c.Path)
+			continue
+		}
+		covered[c.Method+" "+c.Path] = true
+		t.Run(c.Name, func(t *testing.T) {
+			run(t, srv.URL, doc, op, c)
+		})
+	}
+	for path, item := range doc.Paths {
+		for method, op := range map[string]*openapi.OperationObject{http.MethodGet: item.Get, http.MethodPost: item.Post} {
+			if op != nil && !covered[method+" "+path] {
+				t.Errorf("%s %s has no case", method, path)
+			}
+		}
+	}
+}
+
+func operation(doc *openapi.Document, method, path string) *openapi.OperationObject {
+	item, ok := doc.Paths[path]
+	if !ok {
+		return nil
+	}
+	switch method {
+	case http.MethodGet:
+		return item.Get
+	case http.MethodPost:
+		return item.Post
+	}
+	return nil
+}
+
+func run(t *testing.T, base string, doc *openapi.Document, op *openapi.OperationObject, c Case) {
+	var req *http.Request
+	switch c.Method {
+	case http.MethodGet:
+		params := map[string]*openapi.Schema{}
+		for _, p := range op.Parameters {
+			params[p.Name] = p.Schema
+		}
+		for name, vs := range c.Query {
+			s, ok := params[name]
+			if !ok {
+				t.Fatalf("parameter %s is not in the document", name)
+			}
+			if err := validateParameter(doc, s, vs[0]); err != nil {
+				t.Fatalf("parameter %s: %v", name, err)
+			}
+		}
+		req, _ = http.NewRequest(c.Method, base+c.Path+"?"+c.Query.Encode(), nil)
+	case http.MethodPost:
+		mt, ok := op.RequestBody.Content["application/json"]
+		if !ok {
+			t.Fatal("the document has no JSON request body")
+		}
+		body, err := decode([]byte(c.Body))
+		if err != nil {
+			t.Fatalf("request body: %v", err)
+		}
+		if err := validate(doc, mt.Schema, body, "request", false); err != nil {
+			t.Fatal(err)
+		}
+		req, _ = http.NewRequest(c.Method, base+c.Path, strings.NewReader(c.Body))
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := c.Status
+	if want == 0 {
+		want = http.StatusOK
+	}
+	if resp.StatusCode != want {
+		t.Fatalf("status %d, want %d: %s", resp.StatusCode, want, b)
+	}
+	r, ok := op.Responses[strconv.Itoa(resp.StatusCode)]
+	if !ok {
+		r = op.Responses["default"]
+	}
+	if mt, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mt != "application/json" {
+		t.Fatalf("Content-Type %q, want application/json", resp.Header.Get("Content-Type"))
+	}
+	mt, ok := r.Content["application/json"]
+	if !ok {
+		t.Fatalf("the document has no JSON reply for status %d", resp.StatusCode)
+	}
+	got, err := decode(b)
+	if err != nil {
+		t.Fatalf("reply %s: %v", b, err)
+	}
+	if err := validate(doc, mt.Schema, got, "reply", true); err != nil {
+		t.Fatalf("%v: %s", err, b)
+	}
+	if c.Want != "" {
+		w, err := decode([]byte(c.Want))
+		if err != nil {
+			t.Fatalf("want: %v", err)
+		}
+		if !reflect.DeepEqual(got, w) {
+			t.Fatalf("reply %s, want %s", bytes.TrimSpace(b), c.Want)
+		}
+	}
+}
+
+func decode(b []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var v interface{}
+	err := dec.Decode(&v)
+	return v, err
+}
+
+func resolve(doc *openapi.Document, s *openapi.Schema) (*openapi.Schema, error) {
+	if s.Ref == "" {
+		return s, nil
+	}
+	name := strings.TrimPrefix(s.Ref, "#/components/schemas/")
+	if r, ok := doc.Components.Schemas[name]; ok {
+		return r, nil
+	}
+	return nil, fmt.Errorf("no schema %s", s.Ref)
+}
+
+// validate checks that the JSON value v, decoded with UseNumber, matches s.
+// Objects may not have undocumented properties and, when complete is set, may
+// not leave documented ones out.
+func validate(doc *openapi.Document, s *openapi.Schema, v interface{}, path string, complete bool) error {
+	s, err := resolve(doc, s)
+	if err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	if v == nil {
+		if s.Nullable {
+			return nil
+		}
+		return fmt.Errorf("%s: null is not nullable", path)
+	}
+	if len(s.OneOf) > 0 {
+		for _, alt := range s.OneOf {
+			if validate(doc, alt, v, path, complete) == nil {
+				return nil
+			}
+		}
+		return fmt.Errorf("%s: %v matches none of oneOf", path, v)
+	}
+
+	switch s.Type {
+	case "object":
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s: %v is not an object", path, v)
+		}
+		for _, name := range s.Required {
+			if _, ok := m[name]; !ok {
+				return fmt.Errorf("%s: required property %s is missing", path, name)
+			}
+		}
+		if s.Properties == nil && s.AdditionalProperties == nil {
+			return nil
+		}
+		names := make([]string, 0, len(m))
+		for name := range m {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			p, ok := s.Properties[name]
+			if !ok {
+				p = s.AdditionalProperties
+			}
+			if p == nil {
+				return fmt.Errorf("%s: property %s is not in the document", path, name)
+			}
+			if err := validate(doc, p, m[name], path+"."+name, complete); err != nil {
+				return err
+			}
+		}
+		if complete {
+			for name := range s.Properties {
+				if _, ok := m[name]; !ok {
+					return fmt.Errorf("%s: property %s is missing", path, name)
+				}
+			}
+		}
+	case "array":
+		a, ok := v.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s: %v is not an array", path, v)
+		}
+		for i, e := range a {
+			if err := validate(doc, s.Items, e, fmt.Sprintf("%s[%d]", path, i), complete); err != nil {
+				return err
+			}
+		}
+	case "string":
+		str, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("%s: %v is not a string", path, v)
+		}
+		if s.Format == "byte" {
+			if _, err := base64.StdEncoding.DecodeString(str); err != nil {
+				return fmt.Errorf("%s: %v", path, err)
+			}
+		}
+	case "integer":
+		n, ok := v.(json.Number)
+		if !ok {
+			return fmt.Errorf("%s: %v is not an integer", path, v)
+		}
+		if _, err := n.Int64(); err != nil {
+			return fmt.Errorf("%s: %v is not an integer", path, v)
+		}
+	case "number":
+		if _, ok := v.(json.Number); !ok {
+			return fmt.Errorf("%s: %v is not a number", path, v)
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return fmt.Errorf("%s: %v is not a boolean", path, v)
+		}
+	default:
+		return fmt.Errorf("%s: unknown type %q", path, s.Type)
+	}
+	return nil
+}
+
+// validateParameter checks that the query parameter value s matches schema.
+func validateParameter(doc *openapi.Document, schema *openapi.Schema, s string) error {
+	schema, err := resolve(doc, schema)
+	if err != nil {
+		return err
+	}
+	if len(schema.OneOf) > 0 {
+		for _, alt := range schema.OneOf {
+			if validateParameter(doc, alt, s) == nil {
+				return nil
+			}
+		}
+		return fmt.Errorf("%q matches none of oneOf", s)
+	}
+	switch schema.Type {
+	case "integer", "number":
+		v, err := decode([]byte(s))
+		if err != nil {
+			return fmt.Errorf("%q is not a number", s)
+		}
+		return validate(doc, schema, v, "parameter", false)
+	case "boolean":
+		_, err := strconv.ParseBool(s)
+		return err
+	case "string":
+		return nil
+	}
+	return fmt.Errorf("unsupported parameter type %q", schema.Type)
+}
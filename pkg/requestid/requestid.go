@@ -0,0 +1,152 @@
+// Package requestid correlates the log lines of a call across the router and
+// the services. The router accepts the X-Request-ID of a call, or generates
+// one, and every hop then carries it in its context, its HTTP headers and its
+// gRPC metadata.
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"time"
+
+	kitgrpc "github.com/go-kit/kit/transport/grpc"
+	kithttp "github.com/go-kit/kit/transport/http"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// Header is the HTTP header of the request ID.
+	Header = "X-Request-ID"
+	// MetadataKey is the gRPC metadata key of the request ID.
+	MetadataKey = "x-request-id"
+)
+
+// maxLength bounds the request IDs accepted from clients.
+const maxLength = 128
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying id.
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the request ID of ctx, empty when there is none.
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+// New returns a new random request ID.
+func New() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(b)
+}
+
+// accept returns id when it is a request ID clients may set, short and made
+// of printable ASCII so that it cannot forge log lines, and a new one
+// otherwise.
+func accept(id string) string {
+	if id == "" || len(id) > maxLength {
+		return New()
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return New()
+		}
+	}
+	return id
+}
+
+// Handler gives every call to next a request ID: the X-Request-ID of the
+// call, or a new one. The ID is put in the context and the headers of the
+// request, so that the handlers of next forward it, and in the headers of
+// the response.
+func Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := accept(r.Header.Get(Header))
+		r.Header.Set(Header, id)
+		w.Header().Set(Header, id)
+		next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), id)))
+	})
+}
+
+// StreamServerInterceptor is the gRPC counterpart of Handler. The ID is put
+// in the context and the incoming metadata of the call, which the proxy
+// forwards, and in the headers of the response.
+func StreamServerInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx := ss.Context()
+	md, _ := metadata.FromIncomingContext(ctx)
+	md = md.Copy()
+	var id string
+	if vs := md.Get(MetadataKey); len(vs) > 0 {
+		id = vs[0]
+	}
+	id = accept(id)
+	md.Set(MetadataKey, id)
+	if err := ss.SetHeader(metadata.Pairs(MetadataKey, id)); err != nil {
+		return err
+	}
+	ctx = NewContext(metadata.NewIncomingContext(ctx, md), id)
+	return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+}
+
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// HTTPToContext moves the request ID of an HTTP request into the context,
+// replacing it with a new one when it is missing or not one clients may set,
+// as Handler does. Use it as a ServerBefore option of Go kit HTTP servers.
+func HTTPToContext() kithttp.RequestFunc {
+	return func(ctx context.Context, r *http.Request) context.Context {
+		return NewContext(ctx, accept(r.Header.Get(Header)))
+	}
+}
+
+// ContextToHTTP sets the request ID of the context on an HTTP request. Use
+// it as a ClientBefore option of Go kit HTTP clients.
+func ContextToHTTP() kithttp.RequestFunc {
+	return func(ctx context.Context, r *http.Request) context.Context {
+		if id := FromContext(ctx); id != "" {
+			r.Header.Set(Header, id)
+		}
+		return ctx
+	}
+}
+
+// GRPCToContext moves the request ID of the gRPC metadata into the context,
+// replacing it like HTTPToContext. Use it as a ServerBefore option of Go kit
+// gRPC servers.
+func GRPCToContext() kitgrpc.ServerRequestFunc {
+	return func(ctx context.Context, md metadata.MD) context.Context {
+		var id string
+		if vs := md.Get(MetadataKey); len(vs) > 0 {
+			id = vs[0]
+		}
+		return NewContext(ctx, accept(id))
+	}
+}
+
+// ContextToGRPC sets the request ID of the context in the gRPC metadata. Use
+// it as a ClientBefore option of Go kit gRPC clients.
+func ContextToGRPC() kitgrpc.ClientRequestFunc {
+	return func(ctx context.Context, md *metadata.MD) context.Context {
+		if id := FromContext(ctx); id != "" {
+			md.Set(MetadataKey, id)
+		}
+		return ctx
+	}
+}
@@ -0,0 +1,71 @@
+package requestid
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/metadata"
+)
+
+func TestAccept(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		id   string
+		kept bool
+	}{
+		{"empty", "", false},
+		{"uuid", "0f8fad5b-d9cb-469f-a165-70867728950e", true},
+		{"printable ascii", "abc~!{}", true},
+		{"longest", strings.Repeat("a", maxLength), true},
+		{"too long", strings.Repeat("a", maxLength+1), false},
+		{"space", "a b", false},
+		{"newline", "a\nlevel=error", false},
+		{"control", "a\x00", false},
+		{"delete", "a\x7f", false},
+		{"non ascii", "é", false},
+	} {
+		got := accept(c.id)
+		if kept := got == c.id; kept != c.kept {
+			t.Errorf("%s: accept(%q) = %q, kept %v, want %v", c.name, c.id, got, kept, c.kept)
+		}
+		if !c.kept && (len(got) != 32 || accept(got) != got) {
+			t.Errorf("%s: accept(%q) = %q, not a new request ID", c.name, c.id, got)
+		}
+	}
+}
+
+func TestToContext(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		id   string
+		set  bool
+		kept bool
+	}{
+		{"valid", "0f8fad5b-d9cb-469f-a165-70867728950e", true, true},
+		{"missing", "", false, false},
+		{"empty", "", true, false},
+		{"forged log line", "a\nlevel=error", true, false},
+		{"too long", strings.Repeat("a", maxLength+1), true, false},
+	} {
+		r := httptest.NewRequest("GET", "/", nil)
+		md := metadata.MD{}
+		if c.set {
+			r.Header.Set(Header, c.id)
+			md.Set(MetadataKey, c.id)
+		}
+		for transport, ctx := range map[string]context.Context{
+			"HTTP": HTTPToContext()(context.Background(), r),
+			"gRPC": GRPCToContext()(context.Background(), md),
+		} {
+			got := FromContext(ctx)
+			if kept := got == c.id; kept != c.kept {
+				t.Errorf("%s %s: request ID %q, kept %v, want %v", c.name, transport, got, kept, c.kept)
+			}
+			if !c.kept && (len(got) != 32 || accept(got) != got) {
+				t.Errorf("%s %s: request ID %q, not a new request ID", c.name, transport, got)
+			}
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package resilience
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/sd/lb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrBulkheadFull is returned when a method already has MaxConcurrency
+// requests in flight and no room left in its wait queue. HTTP transports
+// reply to it with 503 Service Unavailable.
+var ErrBulkheadFull = status.Error(codes.ResourceExhausted, "too many concurrent requests")
+
+// BulkheadFull reports whether err is ErrBulkheadFull, the final error of
+// retries included. Past a gRPC or HTTP hop the error is rebuilt from its
+// code and message, so it is told apart by them.
+func BulkheadFull(err error) bool {
+	if rerr, ok := err.(lb.RetryError); ok {
+		err = rerr.Final
+	}
+	st, ok := status.FromError(err)
+	full := status.Convert(ErrBulkheadFull)
+	return ok && st.Code() == full.Code() && st.Message() == full.Message()
+}
+
+// Bulkhead returns a middleware that allows at most MaxConcurrency requests in
+// flight, or a no-op when MaxConcurrency is not set. Up to MaxQueue further
+// requests wait for a free slot for at most QueueTimeout; everything beyond
+// that fails fast with ErrBulkheadFull. name labels the bulkhead metrics.
+func (s Settings) Bulkhead(name string) endpoint.Middleware {
+	if s.MaxConcurrency <= 0 {
+		return nop
+	}
+
+	var (
+		slots    = make(chan struct{}, s.MaxConcurrency)
+		queued   int64
+		inFlight = bulkheadInFlight.With("name", name)
+		depth    = bulkheadQueueDepth.With("name", name)
+		rejected = func(reason string) error {
+			bulkheadRejections.With("name", name, "reason", reason).Add(1)
+			return ErrBulkheadFull
+		}
+	)
+	acquire := func(ctx context.Context) error {
+		select {
+		case slots <- struct{}{}:
+			return nil
+		default:
+		}
+
+		if n := atomic.AddInt64(&queued, 1); n > int64(s.MaxQueue) {
+			atomic.AddInt64(&queued, -1)
+			return rejected("full")
+		}
+		depth.Add(1)
+		defer func() {
+			atomic.AddInt64(&queued, -1)
+			depth.Add(-1)
+		}()
+
+		var timeout <-chan time.Time
+		if s.QueueTimeout > 0 {
+			t := time.NewTimer(s.QueueTimeout)
+			defer t.Stop()
+			timeout = t.C
+		}
+		select {
+		case slots <- struct{}{}:
+			return nil
+		case <-timeout:
+			return rejected("timeout")
+		case <-ctx.Done():
+			rejected("canceled")
+			return ctx.Err()
+		}
+	}
+
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			if err := acquire(ctx); err != nil {
+				return nil, err
+			}
+			inFlight.Add(1)
+			defer func() {
+				inFlight.Add(-1)
+				<-slots
+			}()
+			return next(ctx, request)
+		}
+	}
+}
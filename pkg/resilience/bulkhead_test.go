@@ -0,0 +1,106 @@
+package resilience
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestBulkhead(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		settings Settings
+		// release frees a slot while the extra call waits.
+		release bool
+		wantErr error
+	}{
+		{
+			name:     "full",
+			settings: Settings{MaxConcurrency: 2},
+			wantErr:  ErrBulkheadFull,
+		},
+		{
+			name:     "queue timeout",
+			settings: Settings{MaxConcurrency: 2, MaxQueue: 1, QueueTimeout: 20 * time.Millisecond},
+			wantErr:  ErrBulkheadFull,
+		},
+		{
+			name:     "queued until a slot frees",
+			settings: Settings{MaxConcurrency: 2, MaxQueue: 1, QueueTimeout: 5 * time.Second},
+			release:  true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				entered = make(chan struct{})
+				block   = make(chan struct{})
+				done    = make(chan error)
+			)
+			e := tc.settings.Bulkhead("test")(func(ctx context.Context, request interface{}) (interface{}, error) {
+				if request == "block" {
+					entered <- struct{}{}
+					<-block
+				}
+				return request, nil
+			})
+			for i := 0; i < tc.settings.MaxConcurrency; i++ {
+				go func() {
+					_, err := e(context.Background(), "block")
+					done <- err
+				}()
+				<-entered
+			}
+
+			if tc.release {
+				go func() {
+					time.Sleep(20 * time.Millisecond)
+					block <- struct{}{}
+				}()
+			}
+			if _, err := e(context.Background(), "extra"); err != tc.wantErr {
+				t.Errorf("extra call: error %v, want %v", err, tc.wantErr)
+			}
+
+			close(block)
+			for i := 0; i < tc.settings.MaxConcurrency; i++ {
+				if err := <-done; err != nil {
+					t.Errorf("blocked call: %v", err)
+				}
+			}
+			// Every slot is free again.
+			for i := 0; i < tc.settings.MaxConcurrency; i++ {
+				go func() {
+					_, err := e(context.Background(), "block")
+					done <- err
+				}()
+				<-entered
+			}
+			for i := 0; i < tc.settings.MaxConcurrency; i++ {
+				if err := <-done; err != nil {
+					t.Errorf("call after release: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestBulkheadCanceled(t *testing.T) {
+	var (
+		entered = make(chan struct{})
+		block   = make(chan struct{})
+	)
+	defer close(block)
+	e := Settings{MaxConcurrency: 1, MaxQueue: 1}.Bulkhead("test")(func(ctx context.Context, request interface{}) (interface{}, error) {
+		entered <- struct{}{}
+		<-block
+		return nil, nil
+	})
+	go e(context.Background(), nil)
+	<-entered
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := e(ctx, nil); err != context.DeadlineExceeded {
+		t.Errorf("queued call: error %v, want %v", err, context.DeadlineExceeded)
+	}
+}
@@ -0,0 +1,58 @@
+package resilience
+
+import "time"
+
+// Settings collects the resilience knobs applied to a single method.
+type Settings struct {
+	// RateLimit is the sustained number of requests per second, 0 disables
+	// rate limiting.
+	RateLimit float64
+	// RateBurst is the number of requests allowed above RateLimit at once.
+	RateBurst int
+	// MaxConcurrency caps the number of in-flight requests, 0 disables the
+	// bulkhead.
+	MaxConcurrency int
+	// MaxQueue is the number of requests allowed to wait for a free slot once
+	// MaxConcurrency is reached; further requests are rejected at once.
+	MaxQueue int
+	// QueueTimeout bounds how long a queued request waits, 0 waits until the
+	// request context is done.
+	QueueTimeout time.Duration
+	// Breaker configures the circuit breaker guarding the method.
+	Breaker BreakerSettings
+}
+
+// BreakerSettings mirrors gobreaker.Settings, replacing ReadyToTrip with the
+// thresholds it is built from.
+type BreakerSettings struct {
+	// MaxRequests is the number of requests allowed through while half-open.
+	MaxRequests uint32
+	// Interval is the cyclic period of the closed state after which counts are
+	// cleared, 0 never clears them.
+	Interval time.Duration
+	// Timeout is how long the breaker stays open before turning half-open.
+	Timeout time.Duration
+	// ConsecutiveFailures trips the breaker after that many failures in a row.
+	// The breaker is disabled when neither it nor FailureRatio is set.
+	ConsecutiveFailures uint32
+	// FailureRatio, when set, trips the breaker once at least MinRequests were
+	// seen in the current interval and this ratio of them failed.
+	FailureRatio float64
+	MinRequests  uint32
+}
+
+// Config holds the Settings for every method of a service. Methods not listed
+// in Methods use Default. It is built from the resilience section of the
+// configuration of the service, see package config.
+type Config struct {
+	Default Settings
+	Methods map[string]Settings
+}
+
+// For returns the Settings of method.
+func (c Config) For(method string) Settings {
+	if s, ok := c.Methods[method]; ok {
+		return s
+	}
+	return c.Default
+}
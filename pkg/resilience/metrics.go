@@ -0,0 +1,62 @@
+package resilience
+
+import (
+	kitprometheus "github.com/go-kit/kit/metrics/prometheus"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	// breakerState reports the current gobreaker.State of every breaker:
+	// 0 closed, 1 half-open, 2 open.
+	breakerState = kitprometheus.NewGauge(register(stdprometheus.NewGaugeVec(stdprometheus.GaugeOpts{
+		Namespace: "gokitconsulk8s",
+		Subsystem: "circuit_breaker",
+		Name:      "state",
+		Help:      "Current circuit breaker state: 0 closed, 1 half-open, 2 open.",
+	}, []string{"name"})).(*stdprometheus.GaugeVec))
+
+	// breakerTransitions counts the state changes of every breaker.
+	breakerTransitions = kitprometheus.NewCounter(register(stdprometheus.NewCounterVec(stdprometheus.CounterOpts{
+		Namespace: "gokitconsulk8s",
+		Subsystem: "circuit_breaker",
+		Name:      "transitions_total",
+		Help:      "Number of circuit breaker state changes.",
+	}, []string{"name", "to"})).(*stdprometheus.CounterVec))
+
+	// bulkheadInFlight reports the number of requests holding a bulkhead slot.
+	bulkheadInFlight = kitprometheus.NewGauge(register(stdprometheus.NewGaugeVec(stdprometheus.GaugeOpts{
+		Namespace: "gokitconsulk8s",
+		Subsystem: "bulkhead",
+		Name:      "in_flight",
+		Help:      "Number of requests currently holding a bulkhead slot.",
+	}, []string{"name"})).(*stdprometheus.GaugeVec))
+
+	// bulkheadQueueDepth reports the number of requests waiting for a slot.
+	bulkheadQueueDepth = kitprometheus.NewGauge(register(stdprometheus.NewGaugeVec(stdprometheus.GaugeOpts{
+		Namespace: "gokitconsulk8s",
+		Subsystem: "bulkhead",
+		Name:      "queue_depth",
+		Help:      "Number of requests waiting for a bulkhead slot.",
+	}, []string{"name"})).(*stdprometheus.GaugeVec))
+
+	// bulkheadRejections counts requests turned away by a bulkhead, by reason.
+	bulkheadRejections = kitprometheus.NewCounter(register(stdprometheus.NewCounterVec(stdprometheus.CounterOpts{
+		Namespace: "gokitconsulk8s",
+		Subsystem: "bulkhead",
+		Name:      "rejections_total",
+		Help:      "Number of requests rejected by a bulkhead.",
+	}, []string{"name", "reason"})).(*stdprometheus.CounterVec))
+)
+
+// register registers c with the default Prometheus registry and returns the
+// collector that ends up registered, so packages sharing a metric name reuse
+// the same collector.
+func register(c stdprometheus.Collector) stdprometheus.Collector {
+	if err := stdprometheus.Register(c); err != nil {
+		if are, ok := err.(stdprometheus.AlreadyRegisteredError); ok {
+			return are.ExistingCollector
+		}
+		panic(err)
+	}
+	return c
+}
@@ -0,0 +1,125 @@
+package resilience
+
+import (
+	"context"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/go-kit/kit/ratelimit"
+	"github.com/sony/gobreaker"
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Middleware chains the rate limiter, bulkhead and circuit breaker described
+// by s, outermost first. The calls the limiter and bulkhead shed never reach
+// the breaker, so that a burst of load does not open it. name identifies the
+// breaker and bulkhead in logs and metrics.
+func (s Settings) Middleware(name string, logger log.Logger) endpoint.Middleware {
+	return endpoint.Chain(
+		s.Limiter(),
+		s.Bulkhead(name),
+		s.CircuitBreaker(name, logger),
+	)
+}
+
+// Status converts the errors of the rate limiter and circuit breaker into gRPC
+// statuses, so that transports report them with a meaningful code. Other
+// errors are returned as is.
+func Status(err error) error {
+	switch err {
+	case ratelimit.ErrLimited:
+		return status.Error(codes.ResourceExhausted, err.Error())
+	case gobreaker.ErrOpenState, gobreaker.ErrTooManyRequests:
+		return status.Error(codes.Unavailable, err.Error())
+	}
+	return err
+}
+
+// CallerError reports whether err blames the call rather than the service,
+// such as invalid arguments. Such errors are not retried and do not count as
+// failures in circuit breakers.
+func CallerError(err error) bool {
+	switch status.Code(err) {
+	case codes.InvalidArgument, codes.OutOfRange, codes.FailedPrecondition:
+		return true
+	}
+	return false
+}
+
+// Limiter returns an erroring rate limiter middleware, or a no-op when
+// RateLimit is not set.
+func (s Settings) Limiter() endpoint.Middleware {
+	if s.RateLimit <= 0 {
+		return nop
+	}
+	return ratelimit.NewErroringLimiter(rate.NewLimiter(rate.Limit(s.RateLimit), s.RateBurst))
+}
+
+// Shed reports whether err is the rejection of a rate limiter or bulkhead,
+// this one's or that of the service called. The calls shed say nothing about
+// the health of the service.
+func Shed(err error) bool {
+	if err == ratelimit.ErrLimited || BulkheadFull(err) {
+		return true
+	}
+	st, ok := status.FromError(err)
+	return ok && st.Code() == codes.ResourceExhausted && st.Message() == ratelimit.ErrLimited.Error()
+}
+
+// CircuitBreaker returns a gobreaker middleware built from s.Breaker, or a
+// no-op when neither ConsecutiveFailures nor FailureRatio is set. State
+// changes are logged and exported through the circuit breaker metrics. Caller
+// errors and shed calls count as successes.
+func (s Settings) CircuitBreaker(name string, logger log.Logger) endpoint.Middleware {
+	b := s.Breaker
+	if b.ConsecutiveFailures == 0 && b.FailureRatio <= 0 {
+		return nop
+	}
+	cb := gobreaker.NewCircuitBreaker(gobreaker.Settings{
+		Name:        name,
+		MaxRequests: b.MaxRequests,
+		Interval:    b.Interval,
+		Timeout:     b.Timeout,
+		ReadyToTrip: func(counts gobreaker.Counts) bool {
+			if b.FailureRatio > 0 {
+				return counts.Requests >= b.MinRequests &&
+					float64(counts.TotalFailures)/float64(counts.Requests) >= b.FailureRatio
+			}
+			return counts.ConsecutiveFailures >= b.ConsecutiveFailures
+		},
+		OnStateChange: func(name string, from gobreaker.State, to gobreaker.State) {
+			logger := log.With(logger, "breaker", name, "from", from, "to", to)
+			if to == gobreaker.StateOpen {
+				level.Warn(logger).Log()
+			} else {
+				level.Info(logger).Log()
+			}
+			breakerState.With("name", name).Set(float64(to))
+			breakerTransitions.With("name", name, "to", to.String()).Add(1)
+		},
+	})
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			var ignored error
+			response, err := cb.Execute(func() (interface{}, error) {
+				response, err := next(ctx, request)
+				if CallerError(err) || Shed(err) {
+					ignored = err
+					return response, nil
+				}
+				return response, err
+			})
+			if ignored != nil {
+				return response, ignored
+			}
+			return response, err
+		}
+	}
+}
+
+func nop(next endpoint.Endpoint) endpoint.Endpoint {
+	return next
+}
@@ -0,0 +1,175 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/ratelimit"
+	"github.com/sony/gobreaker"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestLimiter(t *testing.T) {
+	e := Settings{RateLimit: 0.001, RateBurst: 3}.Limiter()(func(ctx context.Context, request interface{}) (interface{}, error) {
+		return request, nil
+	})
+	for i := 0; i < 3; i++ {
+		if _, err := e(context.Background(), i); err != nil {
+			t.Fatalf("call %d within burst: %v", i, err)
+		}
+	}
+	_, err := e(context.Background(), 3)
+	if err != ratelimit.ErrLimited {
+		t.Fatalf("call over burst: error %v, want %v", err, ratelimit.ErrLimited)
+	}
+	if !Shed(err) {
+		t.Error("limited call not shed")
+	}
+	if code := status.Code(Status(err)); code != codes.ResourceExhausted {
+		t.Errorf("status code %v, want %v", code, codes.ResourceExhausted)
+	}
+}
+
+// transitionLogger records the state changes breakers log.
+type transitionLogger struct {
+	transitions []string
+}
+
+func (l *transitionLogger) Log(keyvals ...interface{}) error {
+	var from, to interface{}
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		switch keyvals[i] {
+		case "from":
+			from = keyvals[i+1]
+		case "to":
+			to = keyvals[i+1]
+		}
+	}
+	l.transitions = append(l.transitions, fmt.Sprintf("%v->%v", from, to))
+	return nil
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	var (
+		errFailed = errors.New("failed")
+		errCaller = status.Error(codes.InvalidArgument, "bad argument")
+	)
+	for _, tc := range []struct {
+		name        string
+		breaker     BreakerSettings
+		errs        []error
+		wantOpen    bool
+		wantHistory []string
+	}{
+		{
+			name:        "consecutive failures",
+			breaker:     BreakerSettings{ConsecutiveFailures: 3, Timeout: time.Minute},
+			errs:        []error{errFailed, errFailed, errFailed},
+			wantOpen:    true,
+			wantHistory: []string{"closed->open"},
+		},
+		{
+			name:    "success resets failures",
+			breaker: BreakerSettings{ConsecutiveFailures: 3, Timeout: time.Minute},
+			errs:    []error{errFailed, errFailed, nil, errFailed, errFailed},
+		},
+		{
+			name:    "caller errors ignored",
+			breaker: BreakerSettings{ConsecutiveFailures: 2, Timeout: time.Minute},
+			errs:    []error{errCaller, errCaller, errCaller},
+		},
+		{
+			name:    "shed calls ignored",
+			breaker: BreakerSettings{ConsecutiveFailures: 2, Timeout: time.Minute},
+			errs:    []error{ErrBulkheadFull, ratelimit.ErrLimited, ErrBulkheadFull},
+		},
+		{
+			name:        "failure ratio",
+			breaker:     BreakerSettings{FailureRatio: 0.5, MinRequests: 4, Timeout: time.Minute},
+			errs:        []error{nil, errFailed, nil, errFailed},
+			wantOpen:    true,
+			wantHistory: []string{"closed->open"},
+		},
+		{
+			name:    "failure ratio below min requests",
+			breaker: BreakerSettings{FailureRatio: 0.5, MinRequests: 4, Timeout: time.Minute},
+			errs:    []error{errFailed, errFailed, errFailed},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				logger transitionLogger
+				calls  int
+				errs   = tc.errs
+			)
+			e := Settings{Breaker: tc.breaker}.CircuitBreaker(tc.name, &logger)(func(ctx context.Context, request interface{}) (interface{}, error) {
+				calls++
+				if len(errs) == 0 {
+					return nil, nil
+				}
+				err := errs[0]
+				errs = errs[1:]
+				return nil, err
+			})
+			for i, want := range tc.errs {
+				if _, err := e(context.Background(), nil); err != want {
+					t.Fatalf("call %d: error %v, want %v", i, err, want)
+				}
+			}
+
+			_, err := e(context.Background(), nil)
+			if open := err == gobreaker.ErrOpenState; open != tc.wantOpen {
+				t.Errorf("breaker open %v, want %v (error %v)", open, tc.wantOpen, err)
+			}
+			if tc.wantOpen && calls != len(tc.errs) {
+				t.Errorf("%d calls reached the endpoint, want %d", calls, len(tc.errs))
+			}
+			if fmt.Sprint(logger.transitions) != fmt.Sprint(tc.wantHistory) {
+				t.Errorf("transitions %v, want %v", logger.transitions, tc.wantHistory)
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerHalfOpen(t *testing.T) {
+	var (
+		logger transitionLogger
+		fail   = true
+	)
+	e := Settings{Breaker: BreakerSettings{ConsecutiveFailures: 1, MaxRequests: 1, Timeout: 20 * time.Millisecond}}.CircuitBreaker("half-open", &logger)(func(ctx context.Context, request interface{}) (interface{}, error) {
+		if fail {
+			return nil, errors.New("failed")
+		}
+		return nil, nil
+	})
+	e(context.Background(), nil)
+	if _, err := e(context.Background(), nil); err != gobreaker.ErrOpenState {
+		t.Fatalf("error %v, want %v", err, gobreaker.ErrOpenState)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	fail = false
+	if _, err := e(context.Background(), nil); err != nil {
+		t.Fatalf("call once half-open: %v", err)
+	}
+	want := []string{"closed->open", "open->half-open", "half-open->closed"}
+	if fmt.Sprint(logger.transitions) != fmt.Sprint(want) {
+		t.Errorf("transitions %v, want %v", logger.transitions, want)
+	}
+}
+
+func TestMiddlewareDisabled(t *testing.T) {
+	e := Settings{}.Middleware("disabled", log.NewNopLogger())(func(ctx context.Context, request interface{}) (interface{}, error) {
+		return nil, errors.New("failed")
+	})
+	for i := 0; i < 100; i++ {
+		if _, err := e(context.Background(), nil); err == nil || err.Error() != "failed" {
+			t.Fatalf("call %d: error %v, want failed", i, err)
+		}
+	}
+}
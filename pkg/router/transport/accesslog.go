@@ -0,0 +1,332 @@
+package transport
+
+import (
+	"context"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/golang/protobuf/proto"
+	"github.com/mwitkow/grpc-proxy/proxy"
+	stdzipkin "github.com/openzipkin/zipkin-go"
+	zipkinhttp "github.com/openzipkin/zipkin-go/middleware/http"
+	"github.com/openzipkin/zipkin-go/propagation/b3"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/cage1016/gokitconsulk8s/pkg/requestid"
+)
+
+// redacted replaces the values of redacted headers and metadata.
+const redacted = "xxxxx"
+
+var proxyCodec = proxy.Codec()
+
+// AccessLogConfig configures the access log written by AccessLogger.
+type AccessLogConfig struct {
+	// SampleRate is the fraction of successful calls logged. Failed calls
+	// are always logged.
+	SampleRate float64
+	// Headers logs the HTTP headers and gRPC metadata of the calls.
+	Headers bool
+	// Redact lists the headers and metadata whose values are not logged.
+	Redact []string
+	// TrustedProxies lists the addresses and CIDR blocks of the proxies
+	// whose X-Forwarded-For header, or metadata, names the client of a call.
+	// Invalid entries are ignored.
+	TrustedProxies []string
+}
+
+// AccessLogger writes a line per call the router handles, over HTTP or gRPC.
+type AccessLogger struct {
+	cfg          AccessLogConfig
+	redact       map[string]bool
+	proxies      []*net.IPNet
+	zipkinTracer *stdzipkin.Tracer
+	logger       log.Logger
+}
+
+// NewAccessLogger returns an AccessLogger.
+func NewAccessLogger(cfg AccessLogConfig, zipkinTracer *stdzipkin.Tracer, logger log.Logger) *AccessLogger {
+	redact := map[string]bool{}
+	for _, h := range cfg.Redact {
+		redact[strings.ToLower(h)] = true
+	}
+	var proxies []*net.IPNet
+	for _, p := range cfg.TrustedProxies {
+		if n, err := parseCIDR(p); err == nil {
+			proxies = append(proxies, n)
+		}
+	}
+	return &AccessLogger{
+		cfg:          cfg,
+		redact:       redact,
+		proxies:      proxies,
+		zipkinTracer: zipkinTracer,
+		logger:       log.With(logger, "access", "router"),
+	}
+}
+
+// Handler logs the calls handled by next. upstreams maps the first element
+// of a path to where its calls are forwarded. Calls are traced from here on,
+// so that every line has a trace ID, and the spans of next are children of
+// the span started here.
+func (a *AccessLogger) Handler(next http.Handler, upstreams map[string]string) http.Handler {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		begin := time.Now()
+		if span := stdzipkin.SpanFromContext(r.Context()); span != nil {
+			b3.InjectHTTP(r)(span.Context())
+		}
+		body := &countingReader{ReadCloser: r.Body}
+		r.Body = body
+		rw := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rw, r)
+
+		route := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"), "/", 2)[0]
+		keyvals := []interface{}{
+			"transport", "http",
+			"method", r.Method,
+			"route", r.URL.Path,
+			"upstream", upstreams[route],
+			"status", rw.status,
+			"took", time.Since(begin),
+			"bytes_in", body.n,
+			"bytes_out", rw.n,
+			"peer_ip", peerIP(r.RemoteAddr),
+			"client_ip", a.clientIP(r.Header.Get("X-Forwarded-For"), r.RemoteAddr),
+			"trace_id", traceID(r.Context(), r.Header.Get(b3.TraceID)),
+			"request_id", r.Header.Get(requestid.Header),
+		}
+		if a.cfg.Headers {
+			for name, vs := range r.Header {
+				keyvals = append(keyvals, "header_"+strings.ToLower(name), a.value(name, vs))
+			}
+		}
+		a.log(rw.status >= http.StatusBadRequest, keyvals)
+	})
+	return zipkinhttp.NewServerMiddleware(a.zipkinTracer, zipkinhttp.SpanName("router"))(h)
+}
+
+// upstreamKey is the context key of where a call is forwarded, recorded by
+// SetUpstream.
+type upstreamKey struct{}
+
+// SetUpstream records, for the access log, that the call of ctx is forwarded
+// to upstream. It does nothing for calls the access log does not see.
+func SetUpstream(ctx context.Context, upstream string) {
+	if p, ok := ctx.Value(upstreamKey{}).(*string); ok {
+		*p = upstream
+	}
+}
+
+// forwardedTo returns where the call of ctx was forwarded, as recorded by
+// SetUpstream, empty when unknown.
+func forwardedTo(ctx context.Context) string {
+	if p, ok := ctx.Value(upstreamKey{}).(*string); ok {
+		return *p
+	}
+	return ""
+}
+
+// StreamInterceptor logs the calls of the gRPC proxy. upstreams maps the
+// lower case name of a gRPC service to where its calls are forwarded, unless
+// the director records otherwise with SetUpstream. The Zipkin server handler
+// of the server provides their trace ID.
+func (a *AccessLogger) StreamInterceptor(upstreams map[string]string) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		begin := time.Now()
+		var upstream string
+		ctx := context.WithValue(ss.Context(), upstreamKey{}, &upstream)
+		stream := &countingStream{ServerStream: ss, ctx: ctx}
+		err := handler(srv, stream)
+		if upstream == "" {
+			upstream = upstreams[grpcService(info.FullMethod)]
+		}
+
+		var remote string
+		if p, ok := peer.FromContext(ctx); ok {
+			remote = p.Addr.String()
+		}
+		md, _ := metadata.FromIncomingContext(ctx)
+		code := status.Code(err)
+		keyvals := []interface{}{
+			"transport", "grpc",
+			"method", info.FullMethod,
+			"route", grpcService(info.FullMethod),
+			"upstream", upstream,
+			"code", code,
+			"took", time.Since(begin),
+			"bytes_in", stream.in,
+			"bytes_out", stream.out,
+			"peer_ip", peerIP(remote),
+			"client_ip", a.clientIP(strings.Join(md.Get("x-forwarded-for"), ","), remote),
+			"trace_id", traceID(ctx, strings.Join(md.Get(b3.TraceID), "")),
+			"request_id", requestid.FromContext(ctx),
+		}
+		if a.cfg.Headers {
+			for name, vs := range md {
+				keyvals = append(keyvals, "metadata_"+name, a.value(name, vs))
+			}
+		}
+		a.log(err != nil, keyvals)
+		return err
+	}
+}
+
+// log writes keyvals, as a warning for failed calls and otherwise as info for
+// the sampled calls.
+func (a *AccessLogger) log(failed bool, keyvals []interface{}) {
+	if failed {
+		level.Warn(a.logger).Log(keyvals...)
+		return
+	}
+	if a.cfg.SampleRate < 1 && rand.Float64() >= a.cfg.SampleRate {
+		return
+	}
+	level.Info(a.logger).Log(keyvals...)
+}
+
+// value returns the values of the header or metadata name as logged.
+func (a *AccessLogger) value(name string, vs []string) string {
+	if a.redact[strings.ToLower(name)] {
+		return redacted
+	}
+	return strings.Join(vs, ",")
+}
+
+// grpcService returns the service of fullMethod as routed by the proxy, e.g.
+// addsvc for /pb.Addsvc/Sum.
+func grpcService(fullMethod string) string {
+	service := strings.SplitN(strings.TrimPrefix(fullMethod, "/"), "/", 2)[0]
+	return strings.ToLower(service[strings.LastIndex(service, ".")+1:])
+}
+
+// clientIP returns the address of the client of a call from the peer remote.
+// forwardedFor is only believed when remote is a trusted proxy: the client is
+// then the last address of forwardedFor that is not a trusted proxy.
+func (a *AccessLogger) clientIP(forwardedFor, remote string) string {
+	ip := peerIP(remote)
+	if !a.trusted(ip) {
+		return ip
+	}
+	hops := strings.Split(forwardedFor, ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if hop == "" {
+			continue
+		}
+		ip = hop
+		if !a.trusted(hop) {
+			break
+		}
+	}
+	return ip
+}
+
+// trusted reports whether ip is the address of a trusted proxy.
+func (a *AccessLogger) trusted(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, n := range a.proxies {
+		if n.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// peerIP returns the host of the peer address remote.
+func peerIP(remote string) string {
+	if host, _, err := net.SplitHostPort(remote); err == nil {
+		return host
+	}
+	return remote
+}
+
+// traceID returns the trace ID of the span of ctx, or the B3 trace ID the
+// call came with when the tracer is a noop.
+func traceID(ctx context.Context, b3TraceID string) string {
+	if span := stdzipkin.SpanFromContext(ctx); span != nil && !span.Context().TraceID.Empty() {
+		return span.Context().TraceID.String()
+	}
+	return b3TraceID
+}
+
+// statusRecorder records the status and size of a response.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	n      int64
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	n, err := r.ResponseWriter.Write(b)
+	r.n += int64(n)
+	return n, err
+}
+
+// countingReader counts the bytes read from a request body.
+type countingReader struct {
+	io.ReadCloser
+	n int64
+}
+
+func (r *countingReader) Read(b []byte) (int, error) {
+	n, err := r.ReadCloser.Read(b)
+	r.n += int64(n)
+	return n, err
+}
+
+// countingStream counts the bytes of the messages of a stream.
+type countingStream struct {
+	grpc.ServerStream
+	ctx     context.Context
+	in, out int
+}
+
+func (s *countingStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *countingStream) RecvMsg(m interface{}) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err == nil {
+		s.in += messageSize(m)
+	}
+	return err
+}
+
+func (s *countingStream) SendMsg(m interface{}) error {
+	err := s.ServerStream.SendMsg(m)
+	if err == nil {
+		s.out += messageSize(m)
+	}
+	return err
+}
+
+// messageSize returns the encoded size of m, either a protobuf message or
+// one of the raw frames of the proxy, which its codec marshals for free.
+func messageSize(m interface{}) int {
+	if msg, ok := m.(proto.Message); ok {
+		return proto.Size(msg)
+	}
+	b, err := proxyCodec.Marshal(m)
+	if err != nil {
+		return 0
+	}
+	return len(b)
+}
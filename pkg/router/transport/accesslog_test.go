@@ -0,0 +1,32 @@
+package transport
+
+import (
+	"testing"
+
+	"github.com/go-kit/kit/log"
+)
+
+func TestAccessLoggerClientIP(t *testing.T) {
+	a := NewAccessLogger(AccessLogConfig{TrustedProxies: []string{"10.0.0.0/8", "192.0.2.1", "invalid"}}, nil, log.NewNopLogger())
+	for _, c := range []struct {
+		name         string
+		forwardedFor string
+		remote       string
+		want         string
+	}{
+		{"direct", "", "198.51.100.7:4242", "198.51.100.7"},
+		{"untrusted peer", "203.0.113.9", "198.51.100.7:4242", "198.51.100.7"},
+		{"trusted peer", "203.0.113.9", "10.1.2.3:4242", "203.0.113.9"},
+		{"trusted address", "203.0.113.9", "192.0.2.1:4242", "203.0.113.9"},
+		{"spoofed first hop", "1.2.3.4, 203.0.113.9", "10.1.2.3:4242", "203.0.113.9"},
+		{"chain of proxies", "203.0.113.9, 10.4.5.6", "10.1.2.3:4242", "203.0.113.9"},
+		{"only proxies", "10.7.7.7, 10.4.5.6", "10.1.2.3:4242", "10.7.7.7"},
+		{"trusted peer not forwarding", "", "10.1.2.3:4242", "10.1.2.3"},
+		{"empty hops", "203.0.113.9, ,", "10.1.2.3:4242", "203.0.113.9"},
+		{"no port", "", "198.51.100.7", "198.51.100.7"},
+	} {
+		if got := a.clientIP(c.forwardedFor, c.remote); got != c.want {
+			t.Errorf("%s: clientIP(%q, %q) = %q, want %q", c.name, c.forwardedFor, c.remote, got, c.want)
+		}
+	}
+}
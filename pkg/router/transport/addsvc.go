@@ -2,10 +2,12 @@ package transport
 
 import (
 	"context"
+	"io"
 	"net/http"
 
 	"github.com/go-kit/kit/endpoint"
 	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/sd"
 	stdopentracing "github.com/opentracing/opentracing-go"
 	stdzipkin "github.com/openzipkin/zipkin-go"
 	"google.golang.org/grpc"
@@ -13,31 +15,52 @@ import (
 	"github.com/cage1016/gokitconsulk8s/pkg/addsvc/endpoints"
 	"github.com/cage1016/gokitconsulk8s/pkg/addsvc/service"
 	"github.com/cage1016/gokitconsulk8s/pkg/addsvc/transports"
+	"github.com/cage1016/gokitconsulk8s/pkg/resilience"
 )
 
-func MakeAddSvcHandler(ctx context.Context, target string, tracer stdopentracing.Tracer, zipkinTracer *stdzipkin.Tracer, logger log.Logger) http.Handler {
+// MakeAddSvcHandler returns the HTTP handler of addsvc, forwarding every call
+// over gRPC to one of the instances of target, which is resolved as described
+// by discovery.NewInstancer. Calls are hedged as configured by hc and
+// retried as configured by retry, and instances dialed with options.
+func MakeAddSvcHandler(ctx context.Context, target string, tracer stdopentracing.Tracer, zipkinTracer *stdzipkin.Tracer, logger log.Logger, rc resilience.Config, hc HedgeConfig, retry RetryConfig, options ...grpc.DialOption) http.Handler {
+	eps := MakeAddSvcEndpoints(ctx, target, tracer, zipkinTracer, logger, rc, hc, retry, options...)
+	return transports.NewHTTPHandler(eps, tracer, zipkinTracer, logger)
+}
+
+// MakeAddSvcEndpoints returns the endpoints of addsvc behind MakeAddSvcHandler.
+func MakeAddSvcEndpoints(ctx context.Context, target string, tracer stdopentracing.Tracer, zipkinTracer *stdzipkin.Tracer, logger log.Logger, rc resilience.Config, hc HedgeConfig, retry RetryConfig, options ...grpc.DialOption) endpoints.Endpoints {
 	var eps = endpoints.Endpoints{}
-	eps.SumEndpoint = addSvcFactory(ctx, target, endpoints.MakeSumEndpoint, tracer, zipkinTracer, logger)
-	eps.ConcatEndpoint = addSvcFactory(ctx, target, endpoints.MakeConcatEndpoint, tracer, zipkinTracer, logger)
+	eps.SumEndpoint = hedgedEndpoint("addsvc.sum", target, addSvcFactory(ctx, endpoints.MakeSumEndpoint, tracer, zipkinTracer, logger, rc, options), hc, retry, tracer, zipkinTracer, logger)
+	eps.ConcatEndpoint = hedgedEndpoint("addsvc.concat", target, addSvcFactory(ctx, endpoints.MakeConcatEndpoint, tracer, zipkinTracer, logger, rc, options), hc, retry, tracer, zipkinTracer, logger)
+	eps.SubtractEndpoint = hedgedEndpoint("addsvc.subtract", target, addSvcFactory(ctx, endpoints.MakeSubtractEndpoint, tracer, zipkinTracer, logger, rc, options), hc, retry, tracer, zipkinTracer, logger)
+	eps.MultiplyEndpoint = hedgedEndpoint("addsvc.multiply", target, addSvcFactory(ctx, endpoints.MakeMultiplyEndpoint, tracer, zipkinTracer, logger, rc, options), hc, retry, tracer, zipkinTracer, logger)
+	eps.DivideEndpoint = hedgedEndpoint("addsvc.divide", target, addSvcFactory(ctx, endpoints.MakeDivideEndpoint, tracer, zipkinTracer, logger, rc, options), hc, retry, tracer, zipkinTracer, logger)
+	eps.ModuloEndpoint = hedgedEndpoint("addsvc.modulo", target, addSvcFactory(ctx, endpoints.MakeModuloEndpoint, tracer, zipkinTracer, logger, rc, options), hc, retry, tracer, zipkinTracer, logger)
+	eps.SumFloatEndpoint = hedgedEndpoint("addsvc.sumfloat", target, addSvcFactory(ctx, endpoints.MakeSumFloatEndpoint, tracer, zipkinTracer, logger, rc, options), hc, retry, tracer, zipkinTracer, logger)
+	eps.SubtractFloatEndpoint = hedgedEndpoint("addsvc.subtractfloat", target, addSvcFactory(ctx, endpoints.MakeSubtractFloatEndpoint, tracer, zipkinTracer, logger, rc, options), hc, retry, tracer, zipkinTracer, logger)
+	eps.MultiplyFloatEndpoint = hedgedEndpoint("addsvc.multiplyfloat", target, addSvcFactory(ctx, endpoints.MakeMultiplyFloatEndpoint, tracer, zipkinTracer, logger, rc, options), hc, retry, tracer, zipkinTracer, logger)
+	eps.DivideFloatEndpoint = hedgedEndpoint("addsvc.dividefloat", target, addSvcFactory(ctx, endpoints.MakeDivideFloatEndpoint, tracer, zipkinTracer, logger, rc, options), hc, retry, tracer, zipkinTracer, logger)
+	eps.ModuloFloatEndpoint = hedgedEndpoint("addsvc.modulofloat", target, addSvcFactory(ctx, endpoints.MakeModuloFloatEndpoint, tracer, zipkinTracer, logger, rc, options), hc, retry, tracer, zipkinTracer, logger)
 
-	return transports.NewHTTPHandler(eps, tracer, zipkinTracer, logger)
+	return eps
 }
 
 func addSvcFactory(
 	ctx context.Context,
-	target string,
 	makeEndpoint func(service.AddsvcService) endpoint.Endpoint,
 	tracer stdopentracing.Tracer,
 	zipkinTracer *stdzipkin.Tracer,
-	logger log.Logger) (endpoint.Endpoint) {
+	logger log.Logger,
+	rc resilience.Config,
+	options []grpc.DialOption) sd.Factory {
 
-	conn, err := grpc.DialContext(ctx, target, grpc.WithInsecure())
-	if err != nil {
-		return func(ctx context.Context, request interface{}) (interface{}, error) {
-			return nil, err
+	return func(instance string) (endpoint.Endpoint, io.Closer, error) {
+		conn, err := grpc.DialContext(ctx, instance, append([]grpc.DialOption{grpc.WithInsecure()}, options...)...)
+		if err != nil {
+			return nil, nil, err
 		}
-	}
-	svc := transports.NewGRPCClient(conn, tracer, zipkinTracer, logger)
+		svc := transports.NewGRPCClient(conn, tracer, zipkinTracer, log.With(logger, "instance", instance), rc)
 
-	return makeEndpoint(svc)
+		return makeEndpoint(svc), conn, nil
+	}
 }
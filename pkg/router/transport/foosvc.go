@@ -2,10 +2,12 @@ package transport
 
 import (
 	"context"
+	"io"
 	"net/http"
 
 	"github.com/go-kit/kit/endpoint"
 	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/sd"
 
 	stdopentracing "github.com/opentracing/opentracing-go"
 	stdzipkin "github.com/openzipkin/zipkin-go"
@@ -14,30 +16,42 @@ import (
 	"github.com/cage1016/gokitconsulk8s/pkg/foosvc/endpoints"
 	"github.com/cage1016/gokitconsulk8s/pkg/foosvc/service"
 	"github.com/cage1016/gokitconsulk8s/pkg/foosvc/transports"
+	"github.com/cage1016/gokitconsulk8s/pkg/resilience"
 )
 
-func MakeFooSvcHandler(ctx context.Context, target string, tracer stdopentracing.Tracer, zipkinTracer *stdzipkin.Tracer, logger log.Logger) http.Handler {
+// MakeFooSvcHandler returns the HTTP handler of foosvc, forwarding every call
+// over gRPC to one of the instances of target, which is resolved as described
+// by discovery.NewInstancer. Calls are hedged as configured by hc and
+// retried as configured by retry, and instances dialed with options.
+func MakeFooSvcHandler(ctx context.Context, target string, tracer stdopentracing.Tracer, zipkinTracer *stdzipkin.Tracer, logger log.Logger, rc resilience.Config, hc HedgeConfig, retry RetryConfig, options ...grpc.DialOption) http.Handler {
+	eps := MakeFooSvcEndpoints(ctx, target, tracer, zipkinTracer, logger, rc, hc, retry, options...)
+	return transports.NewHTTPHandler(eps, tracer, zipkinTracer, logger)
+}
+
+// MakeFooSvcEndpoints returns the endpoints of foosvc behind MakeFooSvcHandler.
+func MakeFooSvcEndpoints(ctx context.Context, target string, tracer stdopentracing.Tracer, zipkinTracer *stdzipkin.Tracer, logger log.Logger, rc resilience.Config, hc HedgeConfig, retry RetryConfig, options ...grpc.DialOption) endpoints.Endpoints {
 	var eps = endpoints.Endpoints{}
-	eps.FooEndpoint = fooSvcFactory(ctx, target, endpoints.MakeFooEndpoint, tracer, zipkinTracer, logger)
+	eps.FooEndpoint = hedgedEndpoint("foosvc.foo", target, fooSvcFactory(ctx, endpoints.MakeFooEndpoint, tracer, zipkinTracer, logger, rc, options), hc, retry, tracer, zipkinTracer, logger)
 
-	return transports.NewHTTPHandler(eps, tracer, zipkinTracer, logger)
+	return eps
 }
 
 func fooSvcFactory(
 	ctx context.Context,
-	target string,
 	makeEndpoint func(service.FoosvcService) endpoint.Endpoint,
 	tracer stdopentracing.Tracer,
 	zipkinTracer *stdzipkin.Tracer,
-	logger log.Logger) (endpoint.Endpoint) {
-
-	conn, err := grpc.DialContext(ctx, target, grpc.WithInsecure())
-	if err != nil {
-		return func(ctx context.Context, request interface{}) (interface{}, error) {
-			return nil, err
+	logger log.Logger,
+	rc resilience.Config,
+	options []grpc.DialOption) sd.Factory {
+
+	return func(instance string) (endpoint.Endpoint, io.Closer, error) {
+		conn, err := grpc.DialContext(ctx, instance, append([]grpc.DialOption{grpc.WithInsecure()}, options...)...)
+		if err != nil {
+			return nil, nil, err
 		}
-	}
-	svc := transports.NewGRPCClient(conn, tracer, zipkinTracer, logger)
+		svc := transports.NewGRPCClient(conn, tracer, zipkinTracer, log.With(logger, "instance", instance), rc)
 
-	return makeEndpoint(svc)
+		return makeEndpoint(svc), conn, nil
+	}
 }
@@ -0,0 +1,229 @@
+package transport
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/go-kit/kit/sd"
+	"github.com/go-kit/kit/sd/lb"
+	stdopentracing "github.com/opentracing/opentracing-go"
+	stdzipkin "github.com/openzipkin/zipkin-go"
+
+	"github.com/cage1016/gokitconsulk8s/pkg/discovery"
+)
+
+const (
+	// hedgeWindow is the number of recent latencies the hedge delay is
+	// computed from.
+	hedgeWindow = 1000
+	// hedgeMinSamples is the number of latencies needed before the percentile
+	// is trusted; until then MaxDelay is used.
+	hedgeMinSamples = 20
+	// hedgeBurst caps the hedge budget accumulated while traffic is fast.
+	hedgeBurst = 10
+)
+
+// HedgeConfig configures request hedging. When the first attempt has not
+// answered after the Percentile latency of recent calls, clamped between
+// MinDelay and MaxDelay, a second attempt is sent to another instance and the
+// first successful response wins.
+type HedgeConfig struct {
+	// Percentile of recent latencies to wait for, e.g. 0.95. 0 disables
+	// hedging.
+	Percentile float64
+	MinDelay   time.Duration
+	MaxDelay   time.Duration
+	// MaxRatio caps hedged attempts to this fraction of requests.
+	MaxRatio float64
+}
+
+func (c HedgeConfig) enabled() bool {
+	return c.Percentile > 0 && c.MaxRatio > 0
+}
+
+// hedgedEndpoint spreads calls over every instance of target in turn, hedging
+// them as configured by hc and retrying them as configured by retry.
+func hedgedEndpoint(name, target string, factory sd.Factory, hc HedgeConfig, retry RetryConfig, tracer stdopentracing.Tracer, zipkinTracer *stdzipkin.Tracer, logger log.Logger) endpoint.Endpoint {
+	instancer, err := discovery.NewInstancer(target, logger)
+	if err != nil {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			return nil, err
+		}
+	}
+	endpointer := sd.NewEndpointer(instancer, factory, logger)
+	return retry.retry(newHedger(name, hc, tracer, zipkinTracer, logger).endpoint(endpointer))
+}
+
+type hedger struct {
+	next uint64 // first for 64-bit alignment of atomic access
+
+	name         string
+	cfg          HedgeConfig
+	tracer       stdopentracing.Tracer
+	zipkinTracer *stdzipkin.Tracer
+	logger       log.Logger
+
+	mtx       sync.Mutex
+	latencies []time.Duration
+	pos       int
+	observed  int
+	delay     time.Duration
+	budget    float64
+}
+
+func newHedger(name string, cfg HedgeConfig, tracer stdopentracing.Tracer, zipkinTracer *stdzipkin.Tracer, logger log.Logger) *hedger {
+	return &hedger{
+		name:         name,
+		cfg:          cfg,
+		tracer:       tracer,
+		zipkinTracer: zipkinTracer,
+		logger:       log.With(logger, "hedge", name),
+		latencies:    make([]time.Duration, 0, hedgeWindow),
+		delay:        cfg.MaxDelay,
+	}
+}
+
+type hedgeResult struct {
+	response interface{}
+	err      error
+}
+
+func (h *hedger) endpoint(endpointer sd.Endpointer) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		endpoints, err := endpointer.Endpoints()
+		if err != nil {
+			return nil, err
+		}
+		if len(endpoints) == 0 {
+			return nil, lb.ErrNoEndpoints
+		}
+		i := atomic.AddUint64(&h.next, 1) - 1
+		if !h.cfg.enabled() {
+			return endpoints[i%uint64(len(endpoints))](ctx, request)
+		}
+
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		results := make(chan hedgeResult, 2)
+		attempt := func(ctx context.Context, e endpoint.Endpoint, done func()) {
+			go func() {
+				defer done()
+				begin := time.Now()
+				response, err := e(ctx, request)
+				if err == nil {
+					h.observe(time.Since(begin))
+				}
+				results <- hedgeResult{response, err}
+			}()
+		}
+
+		delay, allowed := h.admit()
+		attempt(ctx, endpoints[i%uint64(len(endpoints))], func() {})
+		pending := 1
+
+		var hedge <-chan time.Time
+		if allowed && len(endpoints) > 1 {
+			timer := time.NewTimer(delay)
+			defer timer.Stop()
+			hedge = timer.C
+		}
+		for {
+			select {
+			case <-hedge:
+				hedge = nil
+				if !h.spend() {
+					continue
+				}
+				hctx, done := h.trace(ctx, delay)
+				attempt(hctx, endpoints[(i+1)%uint64(len(endpoints))], done)
+				pending++
+			case r := <-results:
+				pending--
+				if r.err == nil || pending == 0 {
+					return r.response, r.err
+				}
+				level.Debug(h.logger).Log("attempt", "failed", "err", r.err)
+			}
+		}
+	}
+}
+
+// admit adds the share of a hedge that every request earns to the budget and
+// returns the current hedge delay.
+func (h *hedger) admit() (time.Duration, bool) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	h.budget += h.cfg.MaxRatio
+	if h.budget > hedgeBurst {
+		h.budget = hedgeBurst
+	}
+	return h.delay, h.budget >= 1
+}
+
+// spend takes a whole hedge out of the budget, reporting false when the
+// hedge ratio has been used up.
+func (h *hedger) spend() bool {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	if h.budget < 1 {
+		return false
+	}
+	h.budget--
+	return true
+}
+
+// observe records the latency of a successful attempt, recomputing the hedge
+// delay every so often.
+func (h *hedger) observe(d time.Duration) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	if len(h.latencies) < hedgeWindow {
+		h.latencies = append(h.latencies, d)
+	} else {
+		h.latencies[h.pos] = d
+		h.pos = (h.pos + 1) % hedgeWindow
+	}
+	h.observed++
+	if len(h.latencies) < hedgeMinSamples || h.observed%hedgeMinSamples != 0 {
+		return
+	}
+
+	sorted := append([]time.Duration(nil), h.latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	delay := sorted[int(h.cfg.Percentile*float64(len(sorted)-1))]
+	if delay < h.cfg.MinDelay {
+		delay = h.cfg.MinDelay
+	}
+	if h.cfg.MaxDelay > 0 && delay > h.cfg.MaxDelay {
+		delay = h.cfg.MaxDelay
+	}
+	h.delay = delay
+}
+
+// trace starts the spans marking a hedged attempt. The returned func finishes
+// them.
+func (h *hedger) trace(ctx context.Context, delay time.Duration) (context.Context, func()) {
+	if parent := stdzipkin.SpanFromContext(ctx); parent != nil {
+		parent.Tag("hedged", "true")
+	}
+	zspan, ctx := h.zipkinTracer.StartSpanFromContext(ctx, "hedge "+h.name, stdzipkin.Tags(map[string]string{
+		"hedge":       "true",
+		"hedge.delay": delay.String(),
+	}))
+	otspan, ctx := stdopentracing.StartSpanFromContextWithTracer(ctx, h.tracer, "hedge "+h.name)
+	otspan.SetTag("hedge", true)
+	otspan.SetTag("hedge.delay", delay.String())
+
+	level.Debug(h.logger).Log("attempt", "hedged", "delay", delay)
+	return ctx, func() {
+		otspan.Finish()
+		zspan.Finish()
+	}
+}
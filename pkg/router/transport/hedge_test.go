@@ -0,0 +1,223 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/sd"
+	stdopentracing "github.com/opentracing/opentracing-go"
+	stdzipkin "github.com/openzipkin/zipkin-go"
+)
+
+func newTestHedger(t *testing.T, cfg HedgeConfig) *hedger {
+	t.Helper()
+	zipkinTracer, err := stdzipkin.NewTracer(nil, stdzipkin.WithNoopTracer(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return newHedger("test", cfg, stdopentracing.NoopTracer{}, zipkinTracer, log.NewNopLogger())
+}
+
+func TestHedgerBudget(t *testing.T) {
+	for _, c := range []struct {
+		name   string
+		ratio  float64
+		admits int
+		// allowed lists what every admit reports.
+		allowed []bool
+		spends  int
+	}{
+		{"earned over requests", 0.25, 4, []bool{false, false, false, true}, 1},
+		{"one hedge per request", 1, 2, []bool{true, true}, 2},
+		{"capped at burst", 1, 2 * hedgeBurst, nil, hedgeBurst},
+	} {
+		h := newTestHedger(t, HedgeConfig{Percentile: 0.9, MaxRatio: c.ratio})
+		for i := 0; i < c.admits; i++ {
+			if _, allowed := h.admit(); c.allowed != nil && allowed != c.allowed[i] {
+				t.Errorf("%s: admit %d allowed %v, want %v", c.name, i, allowed, c.allowed[i])
+			}
+		}
+		spends := 0
+		for h.spend() {
+			spends++
+		}
+		if spends != c.spends {
+			t.Errorf("%s: %d hedges spent, want %d", c.name, spends, c.spends)
+		}
+	}
+}
+
+// hedgeInstance is an instance answering with its name after delay, or
+// failing with err. A negative delay never answers.
+type hedgeInstance struct {
+	delay time.Duration
+	err   error
+}
+
+// hedgeInstances records the calls to and cancellations of instances.
+type hedgeInstances struct {
+	mtx      sync.Mutex
+	called   []string
+	canceled map[string]chan struct{}
+}
+
+func (is *hedgeInstances) endpoint(name string, i hedgeInstance) endpoint.Endpoint {
+	canceled := make(chan struct{}, 1)
+	is.canceled[name] = canceled
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		is.mtx.Lock()
+		is.called = append(is.called, name)
+		is.mtx.Unlock()
+
+		var answer <-chan time.Time
+		if i.delay >= 0 {
+			answer = time.After(i.delay)
+		}
+		select {
+		case <-answer:
+			return name, i.err
+		case <-ctx.Done():
+			select {
+			case canceled <- struct{}{}:
+			default:
+			}
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func TestHedgerEndpoint(t *testing.T) {
+	failed := errors.New("failed")
+	hedged := HedgeConfig{Percentile: 0.9, MaxDelay: 5 * time.Millisecond, MaxRatio: 1}
+	for _, c := range []struct {
+		name      string
+		cfg       HedgeConfig
+		instances []hedgeInstance
+		want      string
+		wantErr   error
+		called    []string
+		canceled  []string
+	}{
+		{
+			name:      "answered before the hedge delay",
+			cfg:       hedged,
+			instances: []hedgeInstance{{delay: 0}, {delay: 0}},
+			want:      "0",
+			called:    []string{"0"},
+		},
+		{
+			name:      "hedge wins",
+			cfg:       hedged,
+			instances: []hedgeInstance{{delay: -1}, {delay: 0}},
+			want:      "1",
+			called:    []string{"0", "1"},
+			canceled:  []string{"0"},
+		},
+		{
+			name:      "first attempt wins after the hedge",
+			cfg:       hedged,
+			instances: []hedgeInstance{{delay: 50 * time.Millisecond}, {delay: -1}},
+			want:      "0",
+			called:    []string{"0", "1"},
+			canceled:  []string{"1"},
+		},
+		{
+			name:      "failed attempt waits for the hedge",
+			cfg:       hedged,
+			instances: []hedgeInstance{{delay: 30 * time.Millisecond, err: failed}, {delay: 60 * time.Millisecond}},
+			want:      "1",
+			called:    []string{"0", "1"},
+		},
+		{
+			name:      "both attempts failed",
+			cfg:       hedged,
+			instances: []hedgeInstance{{delay: 30 * time.Millisecond, err: failed}, {delay: 10 * time.Millisecond, err: failed}},
+			wantErr:   failed,
+			called:    []string{"0", "1"},
+		},
+		{
+			name:      "single instance",
+			cfg:       hedged,
+			instances: []hedgeInstance{{delay: 30 * time.Millisecond}},
+			want:      "0",
+			called:    []string{"0"},
+		},
+		{
+			name:      "hedging disabled",
+			cfg:       HedgeConfig{MaxDelay: 5 * time.Millisecond, MaxRatio: 1},
+			instances: []hedgeInstance{{delay: 30 * time.Millisecond}, {delay: 0}},
+			want:      "0",
+			called:    []string{"0"},
+		},
+		{
+			name:      "no budget",
+			cfg:       HedgeConfig{Percentile: 0.9, MaxDelay: 5 * time.Millisecond, MaxRatio: 0.5},
+			instances: []hedgeInstance{{delay: 30 * time.Millisecond}, {delay: 0}},
+			want:      "0",
+			called:    []string{"0"},
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			is := &hedgeInstances{canceled: map[string]chan struct{}{}}
+			var endpoints []endpoint.Endpoint
+			for i, instance := range c.instances {
+				endpoints = append(endpoints, is.endpoint(fmt.Sprint(i), instance))
+			}
+			e := newTestHedger(t, c.cfg).endpoint(sd.FixedEndpointer(endpoints))
+
+			response, err := e(context.Background(), nil)
+			if err != c.wantErr {
+				t.Fatalf("error %v, want %v", err, c.wantErr)
+			}
+			if c.wantErr == nil && response != c.want {
+				t.Errorf("response %v, want %s", response, c.want)
+			}
+			for _, name := range c.canceled {
+				select {
+				case <-is.canceled[name]:
+				case <-time.After(time.Second):
+					t.Errorf("instance %s not canceled", name)
+				}
+			}
+			is.mtx.Lock()
+			defer is.mtx.Unlock()
+			sort.Strings(is.called)
+			if fmt.Sprint(is.called) != fmt.Sprint(c.called) {
+				t.Errorf("instances called %v, want %v", is.called, c.called)
+			}
+		})
+	}
+}
+
+// TestHedgerSpendsBudget checks that once the budget is used up calls are not
+// hedged until enough calls earned another hedge.
+func TestHedgerSpendsBudget(t *testing.T) {
+	is := &hedgeInstances{canceled: map[string]chan struct{}{}}
+	endpoints := []endpoint.Endpoint{
+		is.endpoint("0", hedgeInstance{delay: 20 * time.Millisecond}),
+		is.endpoint("1", hedgeInstance{delay: 20 * time.Millisecond}),
+	}
+	h := newTestHedger(t, HedgeConfig{Percentile: 0.9, MaxDelay: 5 * time.Millisecond, MaxRatio: 0.5})
+	e := h.endpoint(sd.FixedEndpointer(endpoints))
+
+	for i, want := range []int{1, 2, 1, 2} {
+		is.mtx.Lock()
+		is.called = nil
+		is.mtx.Unlock()
+		if _, err := e(context.Background(), nil); err != nil {
+			t.Fatalf("call %d: %v", i, err)
+		}
+		is.mtx.Lock()
+		if len(is.called) != want {
+			t.Errorf("call %d: %d attempts, want %d", i, len(is.called), want)
+		}
+		is.mtx.Unlock()
+	}
+}
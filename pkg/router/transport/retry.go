@@ -0,0 +1,38 @@
+package transport
+
+import (
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/sd/lb"
+
+	"github.com/cage1016/gokitconsulk8s/pkg/resilience"
+)
+
+// RetryConfig configures the retries of the calls the router forwards.
+type RetryConfig struct {
+	// Max is the number of attempts of a call, the first one included.
+	Max int
+	// Timeout is the time budget of a call, retries included. 0 disables
+	// retries, and the budget.
+	Timeout time.Duration
+}
+
+// retry returns e retrying failed calls as configured by c, unless they are
+// caller errors such as division by zero. Every attempt goes through e, which
+// sends it to the next instance.
+func (c RetryConfig) retry(e endpoint.Endpoint) endpoint.Endpoint {
+	if c.Timeout <= 0 {
+		return e
+	}
+	return lb.RetryWithCallback(c.Timeout, endpointBalancer(e), func(n int, err error) (bool, error) {
+		return n < c.Max && !resilience.CallerError(err), nil
+	})
+}
+
+// endpointBalancer is an lb.Balancer always returning the same endpoint.
+type endpointBalancer endpoint.Endpoint
+
+func (b endpointBalancer) Endpoint() (endpoint.Endpoint, error) {
+	return endpoint.Endpoint(b), nil
+}
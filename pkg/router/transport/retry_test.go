@@ -0,0 +1,38 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cage1016/gokitconsulk8s/pkg/addsvc/service"
+)
+
+func TestRetryConfig(t *testing.T) {
+	unavailable := errors.New("unavailable")
+	for _, c := range []struct {
+		name     string
+		cfg      RetryConfig
+		errs     []error
+		attempts int
+		ok       bool
+	}{
+		{"disabled", RetryConfig{Max: 3}, []error{unavailable, nil}, 1, false},
+		{"single attempt", RetryConfig{Max: 1, Timeout: time.Second}, []error{unavailable, nil}, 1, false},
+		{"retried", RetryConfig{Max: 3, Timeout: time.Second}, []error{unavailable, unavailable, nil}, 3, true},
+		{"attempts used up", RetryConfig{Max: 2, Timeout: time.Second}, []error{unavailable, unavailable, nil}, 2, false},
+		{"caller error", RetryConfig{Max: 3, Timeout: time.Second}, []error{service.ErrDivideByZero, nil}, 1, false},
+	} {
+		attempts := 0
+		e := c.cfg.retry(func(ctx context.Context, request interface{}) (interface{}, error) {
+			err := c.errs[attempts]
+			attempts++
+			return "ok", err
+		})
+		_, err := e(context.Background(), nil)
+		if attempts != c.attempts || (err == nil) != c.ok {
+			t.Errorf("%s: %d attempts, error %v, want %d attempts, success %v", c.name, attempts, err, c.attempts, c.ok)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package transport
+
+import (
+	"fmt"
+	"net"
+	"path"
+	"strings"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// RouteConfig configures a Route.
+type RouteConfig struct {
+	Name string
+	// Method is a path.Match pattern of the full method of the calls, empty
+	// for any.
+	Method string
+	// Metadata maps metadata keys to the value calls must have, * for any.
+	Metadata map[string]string
+	// Callers lists the IP addresses or CIDR blocks calls must come from,
+	// empty for any. Calls are matched by the IP address of their peer
+	// only, never by forwarding headers, so the calls of the WebSocket
+	// gateway, which have no IP peer, match no route with callers.
+	Callers []string
+	// Upstream is the gRPC target of the calls matched, resolved as
+	// described by discovery.NewInstancer.
+	Upstream string
+}
+
+// Route sends the gRPC calls matching all of its conditions to its
+// upstream.
+type Route struct {
+	Name     string
+	Upstream string
+
+	method   string
+	metadata map[string]string
+	callers  []*net.IPNet
+}
+
+// Routes are tried in order, the first route matching a call wins.
+type Routes []Route
+
+// NewRoutes returns the routes configured by cfgs.
+func NewRoutes(cfgs []RouteConfig) (Routes, error) {
+	rs := make(Routes, 0, len(cfgs))
+	for _, cfg := range cfgs {
+		if _, err := path.Match(cfg.Method, ""); err != nil {
+			return nil, fmt.Errorf("route %s: method %q: %v", cfg.Name, cfg.Method, err)
+		}
+		r := Route{
+			Name:     cfg.Name,
+			Upstream: cfg.Upstream,
+			method:   cfg.Method,
+			metadata: map[string]string{},
+		}
+		for k, v := range cfg.Metadata {
+			r.metadata[strings.ToLower(k)] = v
+		}
+		for _, c := range cfg.Callers {
+			n, err := parseCIDR(c)
+			if err != nil {
+				return nil, fmt.Errorf("route %s: %v", cfg.Name, err)
+			}
+			r.callers = append(r.callers, n)
+		}
+		rs = append(rs, r)
+	}
+	return rs, nil
+}
+
+// parseCIDR parses a CIDR block, or an IP address as the block of that
+// address alone.
+func parseCIDR(s string) (*net.IPNet, error) {
+	if _, n, err := net.ParseCIDR(s); err == nil {
+		return n, nil
+	}
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return nil, fmt.Errorf("%q is not an IP address or a CIDR block", s)
+	}
+	bits := 8 * net.IPv6len
+	if ip.To4() != nil {
+		ip, bits = ip.To4(), 8*net.IPv4len
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+}
+
+// Match returns the first route matching a call to fullMethod with md, from
+// the peer IP address caller, which may be nil when unknown.
+func (rs Routes) Match(fullMethod string, md metadata.MD, caller net.IP) (Route, bool) {
+	for _, r := range rs {
+		if r.matches(fullMethod, md, caller) {
+			return r, true
+		}
+	}
+	return Route{}, false
+}
+
+func (r Route) matches(fullMethod string, md metadata.MD, caller net.IP) bool {
+	if r.method != "" {
+		if ok, _ := path.Match(r.method, fullMethod); !ok {
+			return false
+		}
+	}
+	for k, want := range r.metadata {
+		vs := md.Get(k)
+		if len(vs) == 0 || (want != "*" && !contains(vs, want)) {
+			return false
+		}
+	}
+	if len(r.callers) == 0 {
+		return true
+	}
+	for _, n := range r.callers {
+		if caller != nil && n.Contains(caller) {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(vs []string, s string) bool {
+	for _, v := range vs {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
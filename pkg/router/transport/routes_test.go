@@ -0,0 +1,56 @@
+package transport
+
+import (
+	"net"
+	"testing"
+
+	"google.golang.org/grpc/metadata"
+)
+
+func TestRoutesMatch(t *testing.T) {
+	routes, err := NewRoutes([]RouteConfig{
+		{Name: "beta", Method: "/pb.Addsvc/*", Metadata: map[string]string{"X-Beta": "1"}, Upstream: "beta:8181"},
+		{Name: "office", Method: "/pb.Foosvc/Foo", Callers: []string{"10.0.0.0/8", "2001:db8::1"}, Upstream: "office:7181"},
+		{Name: "tenant", Metadata: map[string]string{"x-tenant": "*"}, Upstream: "tenant:8181"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		name   string
+		method string
+		md     metadata.MD
+		caller string
+		want   string
+	}{
+		{"metadata", "/pb.Addsvc/Sum", metadata.Pairs("x-beta", "1"), "", "beta"},
+		{"one of the values", "/pb.Addsvc/Sum", metadata.Pairs("x-beta", "0", "x-beta", "1"), "", "beta"},
+		{"other value", "/pb.Addsvc/Sum", metadata.Pairs("x-beta", "2"), "", ""},
+		{"other method", "/pb.Foosvc/Foo", metadata.Pairs("x-beta", "1"), "", ""},
+		{"pattern does not cross /", "/pb.Addsvc/Sum/x", metadata.Pairs("x-beta", "1"), "", ""},
+		{"first route wins", "/pb.Addsvc/Sum", metadata.Pairs("x-beta", "1", "x-tenant", "a"), "", "beta"},
+		{"caller in block", "/pb.Foosvc/Foo", nil, "10.1.2.3", "office"},
+		{"caller address", "/pb.Foosvc/Foo", nil, "2001:db8::1", "office"},
+		{"caller outside", "/pb.Foosvc/Foo", nil, "192.0.2.1", ""},
+		{"caller unknown", "/pb.Foosvc/Foo", nil, "", ""},
+		{"any value", "/pb.Foosvc/Foo", metadata.Pairs("x-tenant", "a"), "192.0.2.1", "tenant"},
+		{"empty value", "/pb.Foosvc/Foo", metadata.Pairs("x-tenant", ""), "", "tenant"},
+		{"no metadata", "/pb.Addsvc/Sum", nil, "", ""},
+	} {
+		r, ok := routes.Match(c.method, c.md, net.ParseIP(c.caller))
+		if ok != (c.want != "") || r.Name != c.want {
+			t.Errorf("%s: Match = %q, %v, want %q", c.name, r.Name, ok, c.want)
+		}
+	}
+}
+
+func TestNewRoutesErrors(t *testing.T) {
+	for _, cfg := range []RouteConfig{
+		{Name: "pattern", Method: "/pb.Addsvc/[", Upstream: "x:1"},
+		{Name: "callers", Callers: []string{"10.0.0.0/33"}, Upstream: "x:1"},
+	} {
+		if _, err := NewRoutes([]RouteConfig{cfg}); err == nil {
+			t.Errorf("NewRoutes(%+v) succeeded", cfg)
+		}
+	}
+}
@@ -0,0 +1,263 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"math/rand"
+	"path"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	kitprometheus "github.com/go-kit/kit/metrics/prometheus"
+	stdzipkin "github.com/openzipkin/zipkin-go"
+	zipkingrpc "github.com/openzipkin/zipkin-go/middleware/grpc"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/cage1016/gokitconsulk8s/pkg/discovery"
+	"github.com/cage1016/gokitconsulk8s/pkg/requestid"
+	"github.com/cage1016/gokitconsulk8s/pkg/resilience"
+)
+
+// Results of the mirrored calls, as counted by shadowCalls.
+const (
+	shadowMatch    = "match"
+	shadowMismatch = "mismatch"
+	shadowDropped  = "dropped"
+)
+
+// shadowCalls counts the calls mirrored to shadows by how the response of the
+// shadow compares with the primary one, or dropped when too many shadow calls
+// were in progress.
+var shadowCalls = kitprometheus.NewCounterFrom(stdprometheus.CounterOpts{
+	Namespace: "gokitconsulk8s",
+	Subsystem: "router",
+	Name:      "shadow_calls_total",
+	Help:      "Number of calls mirrored to a shadow, by result: match, mismatch or dropped.",
+}, []string{"service", "method", "result"})
+
+// ShadowConfig configures a Shadow.
+type ShadowConfig struct {
+	// Target is the gRPC target of the shadow.
+	Target string
+	// Percent is the percentage of calls mirrored.
+	Percent float64
+	// Timeout bounds every shadow call.
+	Timeout time.Duration
+	// MaxInFlight caps the shadow calls in progress, calls beyond it are not
+	// mirrored.
+	MaxInFlight int
+}
+
+// Shadow mirrors a share of the calls to a service to a shadow upstream, such
+// as a new version of the service, once the service has answered them. The
+// responses of the shadow are compared with those of the service, counted as
+// matching or not in the shadow calls metric, and discarded: callers never
+// wait for the shadow nor see its responses. Mismatches are logged with the
+// request ID of the call.
+type Shadow struct {
+	service string
+	cfg     ShadowConfig
+	slots   chan struct{}
+	conn    *grpc.ClientConn
+	logger  log.Logger
+}
+
+// NewShadow returns the Shadow of service configured by cfg. The gRPC calls
+// are mirrored over a connection to the instances cfg.Target resolves to, as
+// described by discovery.NewInstancer, dialed with options.
+func NewShadow(service string, cfg ShadowConfig, zipkinTracer *stdzipkin.Tracer, logger log.Logger, options ...grpc.DialOption) (*Shadow, error) {
+	conn, err := discovery.Dial(cfg.Target, append([]grpc.DialOption{
+		grpc.WithInsecure(),
+		grpc.WithStatsHandler(zipkingrpc.NewClientHandler(zipkinTracer)),
+		grpc.WithDefaultCallOptions(grpc.CallCustomCodec(rawCodec{})),
+	}, options...)...)
+	if err != nil {
+		return nil, err
+	}
+	return &Shadow{
+		service: service,
+		cfg:     cfg,
+		slots:   make(chan struct{}, cfg.MaxInFlight),
+		conn:    conn,
+		logger:  log.With(logger, "shadow", cfg.Target),
+	}, nil
+}
+
+// Target returns the gRPC target of the shadow.
+func (s *Shadow) Target() string {
+	return s.cfg.Target
+}
+
+func (s *Shadow) sample() bool {
+	return s.cfg.Percent >= 100 || rand.Float64()*100 < s.cfg.Percent
+}
+
+// outcome is the result of a call, as compared between the service and its
+// shadow.
+type outcome struct {
+	response interface{}
+	err      error
+}
+
+func (o outcome) code() codes.Code {
+	if o.err == nil {
+		return codes.OK
+	}
+	return status.Code(resilience.Status(o.err))
+}
+
+// mirror calls shadow in the background, with the values of ctx but not its
+// deadline nor its cancellation, and compares its outcome with primary using
+// equal, which is only called when both succeeded. The call is dropped when
+// MaxInFlight shadow calls are in progress.
+func (s *Shadow) mirror(ctx context.Context, method string, primary outcome, shadow func(context.Context) outcome, equal func(a, b interface{}) bool) {
+	select {
+	case s.slots <- struct{}{}:
+	default:
+		shadowCalls.With("service", s.service, "method", method, "result", shadowDropped).Add(1)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), s.cfg.Timeout)
+	go func() {
+		defer func() { <-s.slots }()
+		defer cancel()
+
+		o := shadow(ctx)
+		result := shadowMatch
+		if o.code() != primary.code() || (o.err == nil && !equal(primary.response, o.response)) {
+			result = shadowMismatch
+		}
+		shadowCalls.With("service", s.service, "method", method, "result", result).Add(1)
+		if result == shadowMatch {
+			return
+		}
+		logger := log.With(s.logger, "method", method, "request_id", requestid.FromContext(ctx))
+		level.Warn(logger).Log(
+			"result", result,
+			"code", primary.code(),
+			"shadow_code", o.code(),
+			"shadow_err", o.err,
+		)
+		level.Debug(logger).Log("response", primary.response, "shadow_response", o.response)
+	}()
+}
+
+// Middleware returns an endpoint middleware mirroring the sampled calls of
+// method to shadow, an endpoint of the shadow. Responses are compared with
+// reflect.DeepEqual.
+func (s *Shadow) Middleware(method string, shadow endpoint.Endpoint) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			response, err := next(ctx, request)
+			if s.sample() {
+				s.mirror(ctx, method, outcome{response, err}, func(ctx context.Context) outcome {
+					response, err := shadow(ctx, request)
+					return outcome{response, err}
+				}, reflect.DeepEqual)
+			}
+			return response, err
+		}
+	}
+}
+
+// Shadows holds the Shadow of each service, keyed by the lower case name of
+// its gRPC service, e.g. addsvc.
+type Shadows map[string]*Shadow
+
+// StreamInterceptor mirrors the sampled calls of the gRPC proxy to the Shadow
+// of their service. upstreams maps the lower case name of a gRPC service to its
+// stable target: calls that the access log, placed ahead of the interceptor,
+// sees forwarded elsewhere by a route or a split are not mirrored. As the
+// proxy forwards messages without decoding them, only calls made of a single
+// request and a single response are mirrored, and the encoded responses are
+// compared byte for byte. The shadow gets the incoming metadata of the call,
+// its request ID included.
+func (ss Shadows) StreamInterceptor(upstreams map[string]string) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		service := grpcService(info.FullMethod)
+		s, ok := ss[service]
+		if !ok || !s.sample() {
+			return handler(srv, stream)
+		}
+		rs := &recordingStream{ServerStream: stream}
+		err := handler(srv, rs)
+		if rs.requests != 1 || rs.responses > 1 {
+			return err
+		}
+		if to := forwardedTo(stream.Context()); to != "" && to != upstreams[service] {
+			return err
+		}
+		md, _ := metadata.FromIncomingContext(stream.Context())
+		method := strings.ToLower(path.Base(info.FullMethod))
+		s.mirror(stream.Context(), method, outcome{rs.response, err}, func(ctx context.Context) outcome {
+			request, response := rs.request, []byte(nil)
+			err := s.conn.Invoke(metadata.NewOutgoingContext(ctx, md.Copy()), info.FullMethod, &request, &response)
+			return outcome{response, err}
+		}, func(a, b interface{}) bool {
+			return bytes.Equal(a.([]byte), b.([]byte))
+		})
+		return err
+	}
+}
+
+// recordingStream records the encoded messages of a call of the proxy, as
+// long as it has no more than one request and one response.
+type recordingStream struct {
+	grpc.ServerStream
+	requests, responses int
+	request, response   []byte
+}
+
+func (s *recordingStream) RecvMsg(m interface{}) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err == nil {
+		if s.requests++; s.requests == 1 {
+			s.request = encoded(m)
+		} else {
+			s.request = nil
+		}
+	}
+	return err
+}
+
+func (s *recordingStream) SendMsg(m interface{}) error {
+	if s.responses++; s.responses == 1 {
+		s.response = encoded(m)
+	} else {
+		s.response = nil
+	}
+	return s.ServerStream.SendMsg(m)
+}
+
+// encoded returns a copy of the bytes of a message of the proxy.
+func encoded(m interface{}) []byte {
+	b, err := proxyCodec.Marshal(m)
+	if err != nil {
+		return nil
+	}
+	return append([]byte{}, b...)
+}
+
+// rawCodec sends and receives messages that are already encoded, as *[]byte.
+type rawCodec struct{}
+
+func (rawCodec) Marshal(v interface{}) ([]byte, error) {
+	return *v.(*[]byte), nil
+}
+
+func (rawCodec) Unmarshal(data []byte, v interface{}) error {
+	*v.(*[]byte) = append([]byte{}, data...)
+	return nil
+}
+
+func (rawCodec) String() string {
+	return "raw"
+}
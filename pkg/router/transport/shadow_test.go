@@ -0,0 +1,319 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/golang/protobuf/proto"
+	stdzipkin "github.com/openzipkin/zipkin-go"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/cage1016/gokitconsulk8s/pb/addsvc"
+)
+
+// shadowCount returns the shadow calls counted for service and method with
+// result.
+func shadowCount(t *testing.T, service, method, result string) float64 {
+	t.Helper()
+	mfs, err := stdprometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, mf := range mfs {
+		if mf.GetName() != "gokitconsulk8s_router_shadow_calls_total" {
+			continue
+		}
+	metrics:
+		for _, m := range mf.GetMetric() {
+			for _, l := range m.GetLabel() {
+				want := map[string]string{"service": service, "method": method, "result": result}[l.GetName()]
+				if l.GetValue() != want {
+					continue metrics
+				}
+			}
+			return m.GetCounter().GetValue()
+		}
+	}
+	return 0
+}
+
+// waitShadowCount waits for the shadow calls of service and method with
+// result to reach want. Counts add up over the runs of a test, so want is
+// computed from the count before the calls.
+func waitShadowCount(t *testing.T, service, method, result string, want float64) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		got := shadowCount(t, service, method, result)
+		if got == want {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%s %s shadow calls %s: %v, want %v", service, method, result, got, want)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func newTestShadow(service string, cfg ShadowConfig) *Shadow {
+	return &Shadow{
+		service: service,
+		cfg:     cfg,
+		slots:   make(chan struct{}, cfg.MaxInFlight),
+		logger:  log.NewNopLogger(),
+	}
+}
+
+type shadowReply struct {
+	Rs int64
+}
+
+func TestShadowMiddleware(t *testing.T) {
+	failed := status.Error(codes.Unavailable, "failed")
+	for _, c := range []struct {
+		name      string
+		primary   interface{}
+		err       error
+		shadow    interface{}
+		shadowErr error
+		result    string
+	}{
+		{"match", shadowReply{3}, nil, shadowReply{3}, nil, shadowMatch},
+		{"different response", shadowReply{3}, nil, shadowReply{4}, nil, shadowMismatch},
+		{"shadow failed", shadowReply{3}, nil, nil, failed, shadowMismatch},
+		{"both failed", nil, failed, nil, status.Error(codes.Unavailable, "other"), shadowMatch},
+		{"different failures", nil, failed, nil, status.Error(codes.Internal, "failed"), shadowMismatch},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			s := newTestShadow("middleware "+c.name, ShadowConfig{Percent: 100, Timeout: time.Second, MaxInFlight: 1})
+			e := s.Middleware("sum", func(ctx context.Context, request interface{}) (interface{}, error) {
+				return c.shadow, c.shadowErr
+			})(func(ctx context.Context, request interface{}) (interface{}, error) {
+				return c.primary, c.err
+			})
+			before := shadowCount(t, s.service, "sum", c.result)
+			response, err := e(context.Background(), nil)
+			if response != c.primary || err != c.err {
+				t.Errorf("response %v, error %v, want %v, %v", response, err, c.primary, c.err)
+			}
+			waitShadowCount(t, s.service, "sum", c.result, before+1)
+		})
+	}
+}
+
+// TestShadowMiddlewareDetached checks that the shadow neither delays nor
+// changes the response, and is not canceled with the call.
+func TestShadowMiddlewareDetached(t *testing.T) {
+	s := newTestShadow("middleware detached", ShadowConfig{Percent: 100, Timeout: time.Second, MaxInFlight: 1})
+	var (
+		release = make(chan struct{})
+		ctxErr  = make(chan error, 1)
+	)
+	e := s.Middleware("sum", func(ctx context.Context, request interface{}) (interface{}, error) {
+		<-release
+		ctxErr <- ctx.Err()
+		return nil, errors.New("shadow failed")
+	})(func(ctx context.Context, request interface{}) (interface{}, error) {
+		return shadowReply{3}, nil
+	})
+
+	dropped := shadowCount(t, s.service, "sum", shadowDropped)
+	mismatched := shadowCount(t, s.service, "sum", shadowMismatch)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	begin := time.Now()
+	response, err := e(ctx, nil)
+	if d := time.Since(begin); d > 100*time.Millisecond {
+		t.Errorf("call took %v waiting for the shadow", d)
+	}
+	if response != (shadowReply{3}) || err != nil {
+		t.Errorf("response %v, error %v, want %v", response, err, shadowReply{3})
+	}
+
+	// A second call is dropped while the first shadow call holds the only
+	// slot.
+	if _, err := e(context.Background(), nil); err != nil {
+		t.Errorf("dropped call: %v", err)
+	}
+	waitShadowCount(t, s.service, "sum", shadowDropped, dropped+1)
+
+	cancel()
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	if err := <-ctxErr; err != nil {
+		t.Errorf("shadow context done with the call: %v", err)
+	}
+	waitShadowCount(t, s.service, "sum", shadowMismatch, mismatched+1)
+}
+
+// shadowAddsvc answers Sum with a + b and records the request ID of its calls.
+type shadowAddsvc struct {
+	pb.AddsvcServer
+
+	mtx        sync.Mutex
+	requestIDs []string
+}
+
+func (s *shadowAddsvc) Sum(ctx context.Context, req *pb.SumRequest) (*pb.SumReply, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	s.mtx.Lock()
+	s.requestIDs = append(s.requestIDs, md.Get("x-request-id")...)
+	s.mtx.Unlock()
+	return &pb.SumReply{Rs: req.A + req.B}, nil
+}
+
+func (s *shadowAddsvc) calls() []string {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return append([]string(nil), s.requestIDs...)
+}
+
+// proxyStream is a stream of the gRPC proxy, receiving requests and recording
+// the responses sent.
+type proxyStream struct {
+	grpc.ServerStream
+	ctx       context.Context
+	requests  []proto.Message
+	responses []interface{}
+}
+
+func (s *proxyStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *proxyStream) RecvMsg(m interface{}) error {
+	if len(s.requests) == 0 {
+		return io.EOF
+	}
+	proto.Merge(m.(proto.Message), s.requests[0])
+	s.requests = s.requests[1:]
+	return nil
+}
+
+func (s *proxyStream) SendMsg(m interface{}) error {
+	s.responses = append(s.responses, m)
+	return nil
+}
+
+func TestShadowsStreamInterceptor(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := &shadowAddsvc{}
+	server := grpc.NewServer()
+	pb.RegisterAddsvcServer(server, svc)
+	go server.Serve(lis)
+	defer server.Stop()
+	zipkinTracer, err := stdzipkin.NewTracer(nil, stdzipkin.WithNoopTracer(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	failed := status.Error(codes.Unavailable, "failed")
+	for _, c := range []struct {
+		name      string
+		requests  []proto.Message
+		responses []proto.Message
+		err       error
+		upstream  string
+		// result is empty when the call is not mirrored.
+		result string
+	}{
+		{
+			name:      "match",
+			requests:  []proto.Message{&pb.SumRequest{A: 1, B: 2}},
+			responses: []proto.Message{&pb.SumReply{Rs: 3}},
+			result:    shadowMatch,
+		},
+		{
+			name:      "different response",
+			requests:  []proto.Message{&pb.SumRequest{A: 1, B: 2}},
+			responses: []proto.Message{&pb.SumReply{Rs: 4}},
+			result:    shadowMismatch,
+		},
+		{
+			name:     "primary failed",
+			requests: []proto.Message{&pb.SumRequest{A: 1, B: 2}},
+			err:      failed,
+			result:   shadowMismatch,
+		},
+		{
+			name:      "client stream",
+			requests:  []proto.Message{&pb.SumRequest{A: 1}, &pb.SumRequest{B: 2}},
+			responses: []proto.Message{&pb.SumReply{Rs: 3}},
+		},
+		{
+			name:      "server stream",
+			requests:  []proto.Message{&pb.SumRequest{A: 1, B: 2}},
+			responses: []proto.Message{&pb.SumReply{Rs: 3}, &pb.SumReply{Rs: 3}},
+		},
+		{
+			name:      "routed elsewhere",
+			requests:  []proto.Message{&pb.SumRequest{A: 1, B: 2}},
+			responses: []proto.Message{&pb.SumReply{Rs: 3}},
+			upstream:  "canary:8181",
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			s, err := NewShadow("interceptor "+c.name, ShadowConfig{Target: lis.Addr().String(), Percent: 100, Timeout: time.Second, MaxInFlight: 1}, zipkinTracer, log.NewNopLogger())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer s.conn.Close()
+			before := len(svc.calls())
+			counted := shadowCount(t, s.service, "sum", c.result)
+
+			upstream := c.upstream
+			ctx, cancel := context.WithCancel(context.WithValue(context.Background(), upstreamKey{}, &upstream))
+			ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("x-request-id", c.name))
+			stream := &proxyStream{ctx: ctx, requests: c.requests}
+			interceptor := Shadows{"addsvc": s}.StreamInterceptor(map[string]string{"addsvc": "stable:8181"})
+			err = interceptor(nil, stream, &grpc.StreamServerInfo{FullMethod: "/pb.Addsvc/Sum"}, func(srv interface{}, stream grpc.ServerStream) error {
+				for {
+					var req pb.SumRequest
+					if err := stream.RecvMsg(&req); err == io.EOF {
+						break
+					} else if err != nil {
+						return err
+					}
+				}
+				for _, resp := range c.responses {
+					if err := stream.SendMsg(resp); err != nil {
+						return err
+					}
+				}
+				return c.err
+			})
+			// The call ends before the shadow answers.
+			cancel()
+			if err != c.err {
+				t.Errorf("error %v, want %v", err, c.err)
+			}
+			if len(stream.responses) != len(c.responses) {
+				t.Errorf("%d responses, want %d", len(stream.responses), len(c.responses))
+			}
+
+			if c.result == "" {
+				time.Sleep(50 * time.Millisecond)
+				if calls := svc.calls()[before:]; len(calls) > 0 {
+					t.Errorf("mirrored: %v", calls)
+				}
+				return
+			}
+			waitShadowCount(t, s.service, "sum", c.result, counted+1)
+			if calls := svc.calls()[before:]; len(calls) != 1 || calls[0] != c.name {
+				t.Errorf("shadow calls with request IDs %q, want [%q]", calls, c.name)
+			}
+		})
+	}
+}
@@ -0,0 +1,243 @@
+package transport
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// StableVersion names the version of a service at its usual target.
+const StableVersion = "stable"
+
+// SplitPath is where the admin server of the router serves the weights of
+// its Splits.
+const SplitPath = "/split"
+
+// Version is a version of a service that calls are split to.
+type Version struct {
+	Name string
+	// Target is the gRPC target of the version, resolved as described by
+	// discovery.NewInstancer over HTTP and gRPC alike.
+	Target string
+	// Weight is the percentage of calls the version gets.
+	Weight int
+}
+
+// SplitConfig configures a Splitter.
+type SplitConfig struct {
+	// Header names the header, or gRPC metadata, that pins a call to the
+	// version it names.
+	Header string
+	// HashHeader names the header, or gRPC metadata, whose value is hashed to
+	// pick the version of a call.
+	HashHeader string
+	// Versions lists the versions besides the stable one, which gets the
+	// calls they leave.
+	Versions []Version
+}
+
+// Splitter splits the calls to a service between its stable version and
+// others, such as canaries, by weight. A call goes to the version named by its
+// Header, otherwise to the version its HashHeader hashes to, so that calls
+// with the same key stick to a version, and otherwise to a version picked at
+// random. Weights are changed at runtime through Splits; raising the weight of
+// a version keeps the keys already hashed to it.
+type Splitter struct {
+	header     string
+	hashHeader string
+	stable     string
+
+	mtx      sync.RWMutex
+	versions []Version
+}
+
+// NewSplitter returns a Splitter whose stable version is at stable.
+func NewSplitter(stable string, cfg SplitConfig) *Splitter {
+	return &Splitter{
+		header:     cfg.Header,
+		hashHeader: cfg.HashHeader,
+		stable:     stable,
+		versions:   append([]Version(nil), cfg.Versions...),
+	}
+}
+
+// Pick returns the version of a call whose headers or metadata are read by
+// get.
+func (s *Splitter) Pick(get func(key string) string) Version {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	if s.header != "" {
+		if name := get(s.header); name != "" {
+			for _, v := range s.versions {
+				if v.Name == name {
+					return v
+				}
+			}
+			if name == StableVersion {
+				return s.stableVersion()
+			}
+		}
+	}
+
+	var bucket int
+	if key := s.key(get); key != "" {
+		h := fnv.New32a()
+		h.Write([]byte(key))
+		bucket = int(h.Sum32() % 100)
+	} else {
+		bucket = rand.Intn(100)
+	}
+	for _, v := range s.versions {
+		if bucket < v.Weight {
+			return v
+		}
+		bucket -= v.Weight
+	}
+	return s.stableVersion()
+}
+
+func (s *Splitter) key(get func(string) string) string {
+	if s.hashHeader == "" {
+		return ""
+	}
+	return get(s.hashHeader)
+}
+
+// stableVersion must be called with s.mtx held.
+func (s *Splitter) stableVersion() Version {
+	weight := 100
+	for _, v := range s.versions {
+		weight -= v.Weight
+	}
+	return Version{Name: StableVersion, Target: s.stable, Weight: weight}
+}
+
+// Weights returns the weight of every version, the stable one included.
+func (s *Splitter) Weights() map[string]int {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	weights := map[string]int{StableVersion: s.stableVersion().Weight}
+	for _, v := range s.versions {
+		weights[v.Name] = v.Weight
+	}
+	return weights
+}
+
+// withWeights returns the versions of s with weights applied, leaving the
+// versions weights does not list as they are. It must be called with s.mtx
+// held.
+func (s *Splitter) withWeights(weights map[string]int) ([]Version, error) {
+	next := append([]Version(nil), s.versions...)
+	index := map[string]int{}
+	for i, v := range next {
+		index[v.Name] = i
+	}
+	var problems []string
+	for name, weight := range weights {
+		i, ok := index[name]
+		switch {
+		case name == StableVersion:
+			problems = append(problems, "the weight of stable is what the other versions leave")
+		case !ok:
+			problems = append(problems, fmt.Sprintf("unknown version %q", name))
+		case weight < 0 || weight > 100:
+			problems = append(problems, fmt.Sprintf("weight of %s %d is not between 0 and 100", name, weight))
+		default:
+			next[i].Weight = weight
+		}
+	}
+	total := 0
+	for _, v := range next {
+		total += v.Weight
+	}
+	if total > 100 {
+		problems = append(problems, fmt.Sprintf("weights add up to %d%%, more than 100%%", total))
+	}
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return nil, fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+	return next, nil
+}
+
+// Handler returns a handler passing every call to the handler of its version
+// in handlers, keyed by version name.
+func (s *Splitter) Handler(handlers map[string]http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlers[s.Pick(r.Header.Get).Name].ServeHTTP(w, r)
+	})
+}
+
+// Splits holds the Splitter of each service, keyed by service name.
+type Splits map[string]*Splitter
+
+// ServeHTTP replies to GET with the weights of every service, as a JSON
+// object such as {"addsvc":{"stable":90,"canary":10}}, and to PUT with such an
+// object by setting the weights it lists. A PUT sets every weight or none.
+func (ss Splits) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var weights map[string]map[string]int
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(&weights); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		if err := ss.set(weights); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	default:
+		w.Header().Set("Allow", http.MethodGet+", "+http.MethodPut)
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+
+	weights := map[string]map[string]int{}
+	for service, s := range ss {
+		weights[service] = s.Weights()
+	}
+	json.NewEncoder(w).Encode(weights)
+}
+
+// set checks the weights of every service before setting any.
+func (ss Splits) set(weights map[string]map[string]int) error {
+	services := make([]string, 0, len(weights))
+	for service := range weights {
+		if _, ok := ss[service]; !ok {
+			return fmt.Errorf("unknown service %q", service)
+		}
+		services = append(services, service)
+	}
+	sort.Strings(services)
+	for _, service := range services {
+		ss[service].mtx.Lock()
+		defer ss[service].mtx.Unlock()
+	}
+	next := map[string][]Version{}
+	for _, service := range services {
+		versions, err := ss[service].withWeights(weights[service])
+		if err != nil {
+			return fmt.Errorf("%s: %v", service, err)
+		}
+		next[service] = versions
+	}
+	for service, versions := range next {
+		ss[service].versions = versions
+	}
+	return nil
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(struct {
+		Error string `json:"error"`
+	}{err.Error()})
+}
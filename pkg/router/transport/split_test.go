@@ -0,0 +1,126 @@
+package transport
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestSplitterPick(t *testing.T) {
+	s := NewSplitter("stable:8181", SplitConfig{
+		Header:     "x-version",
+		HashHeader: "x-user",
+		Versions:   []Version{{Name: "canary", Target: "canary:8181", Weight: 20}, {Name: "dark", Target: "dark:8181", Weight: 0}},
+	})
+	for _, c := range []struct {
+		name    string
+		headers map[string]string
+		want    string
+	}{
+		{"pinned", map[string]string{"x-version": "canary", "x-user": "u"}, "canary"},
+		{"pinned to a version without weight", map[string]string{"x-version": "dark"}, "dark"},
+		{"pinned to stable", map[string]string{"x-version": "stable"}, "stable"},
+		// fnv32a("user-1") % 100 is 0, within the 20% of canary, and
+		// fnv32a("user-2") % 100 is 57, beyond it.
+		{"hashed to canary", map[string]string{"x-user": "user-1"}, "canary"},
+		{"hashed to stable", map[string]string{"x-user": "user-2"}, "stable"},
+		{"unknown version hashed", map[string]string{"x-version": "gone", "x-user": "user-1"}, "canary"},
+	} {
+		get := func(key string) string { return c.headers[key] }
+		if got := s.Pick(get); got.Name != c.want {
+			t.Errorf("%s: Pick = %s, want %s", c.name, got.Name, c.want)
+		}
+	}
+	if got := s.Pick(func(string) string { return "" }).Target; got != "stable:8181" && got != "canary:8181" {
+		t.Errorf("random Pick target %s", got)
+	}
+}
+
+func TestSplitterPickKeepsKeys(t *testing.T) {
+	s := NewSplitter("stable:8181", SplitConfig{
+		HashHeader: "x-user",
+		Versions:   []Version{{Name: "canary", Target: "canary:8181", Weight: 10}},
+	})
+	pick := func() map[string]string {
+		picked := map[string]string{}
+		for i := 0; i < 1000; i++ {
+			key := fmt.Sprintf("user-%d", i)
+			picked[key] = s.Pick(func(string) string { return key }).Name
+		}
+		return picked
+	}
+	before := pick()
+	if err := (Splits{"addsvc": s}).set(map[string]map[string]int{"addsvc": {"canary": 50}}); err != nil {
+		t.Fatal(err)
+	}
+	after := pick()
+	canary := 0
+	for key, name := range after {
+		if name == "canary" {
+			canary++
+		}
+		if before[key] == "canary" && name != "canary" {
+			t.Errorf("%s left canary when its weight was raised", key)
+		}
+	}
+	if canary < 400 || canary > 600 {
+		t.Errorf("%d keys of 1000 picked canary at 50%%", canary)
+	}
+}
+
+func TestSplitsSet(t *testing.T) {
+	for _, c := range []struct {
+		name    string
+		weights map[string]map[string]int
+		err     string
+		want    map[string]map[string]int
+	}{
+		{
+			name:    "both services",
+			weights: map[string]map[string]int{"addsvc": {"canary": 30}, "foosvc": {"blue": 50, "green": 50}},
+			want:    map[string]map[string]int{"addsvc": {"stable": 70, "canary": 30}, "foosvc": {"stable": 0, "blue": 50, "green": 50}},
+		},
+		{
+			name:    "unlisted versions kept",
+			weights: map[string]map[string]int{"foosvc": {"blue": 0}},
+			want:    map[string]map[string]int{"addsvc": {"stable": 90, "canary": 10}, "foosvc": {"stable": 80, "blue": 0, "green": 20}},
+		},
+		{
+			name:    "unknown service",
+			weights: map[string]map[string]int{"addsvc": {"canary": 30}, "barsvc": {"canary": 30}},
+			err:     `unknown service "barsvc"`,
+		},
+		{
+			name:    "one service invalid",
+			weights: map[string]map[string]int{"addsvc": {"canary": 30}, "foosvc": {"blue": 90}},
+			err:     "foosvc: weights add up to 110%, more than 100%",
+		},
+		{
+			name:    "every problem",
+			weights: map[string]map[string]int{"addsvc": {"stable": 50, "canary": 101, "gone": 1}},
+			err:     `addsvc: the weight of stable is what the other versions leave; unknown version "gone"; weight of canary 101 is not between 0 and 100`,
+		},
+	} {
+		ss := Splits{
+			"addsvc": NewSplitter("addsvc:8181", SplitConfig{Versions: []Version{{Name: "canary", Weight: 10}}}),
+			"foosvc": NewSplitter("foosvc:7181", SplitConfig{Versions: []Version{{Name: "blue", Weight: 10}, {Name: "green", Weight: 20}}}),
+		}
+		before := map[string]map[string]int{"addsvc": ss["addsvc"].Weights(), "foosvc": ss["foosvc"].Weights()}
+		err := ss.set(c.weights)
+		got := map[string]map[string]int{"addsvc": ss["addsvc"].Weights(), "foosvc": ss["foosvc"].Weights()}
+		if c.err != "" {
+			if err == nil || err.Error() != c.err {
+				t.Errorf("%s: error %v, want %s", c.name, err, c.err)
+			}
+			if !reflect.DeepEqual(got, before) {
+				t.Errorf("%s: weights changed to %v by a failed update", c.name, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+		} else if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: weights %v, want %v", c.name, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package transport
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/improbable-eng/grpc-web/go/grpcweb"
+	"github.com/rs/cors"
+	"google.golang.org/grpc"
+)
+
+// CORSConfig configures the cross-origin calls browsers may make to the
+// router.
+type CORSConfig struct {
+	// AllowedOrigins lists the origins allowed, such as https://example.com.
+	// * allows any origin, and a single * within an origin any string, e.g.
+	// https://*.example.com. Empty allows none.
+	AllowedOrigins []string
+	AllowedMethods []string
+	AllowedHeaders []string
+	// ExposedHeaders lists the response headers scripts may read.
+	ExposedHeaders   []string
+	AllowCredentials bool
+	// MaxAge is how long browsers may cache the answer to a preflight
+	// request.
+	MaxAge time.Duration
+}
+
+// allowOrigin reports whether origin is one of the allowed origins.
+func (c CORSConfig) allowOrigin(origin string) bool {
+	origin = strings.ToLower(origin)
+	for _, o := range c.AllowedOrigins {
+		o = strings.ToLower(o)
+		if o == "*" || o == origin {
+			return true
+		}
+		if i := strings.Index(o, "*"); i >= 0 {
+			prefix, suffix := o[:i], o[i+1:]
+			if len(origin) >= len(prefix)+len(suffix) && strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// CORS returns next answering the preflight requests and setting the CORS
+// headers of the calls from the origins cfg allows. It returns next as it is
+// when cfg allows no origin.
+func CORS(next http.Handler, cfg CORSConfig) http.Handler {
+	if len(cfg.AllowedOrigins) == 0 {
+		return next
+	}
+	return cors.New(cors.Options{
+		AllowOriginFunc:  cfg.allowOrigin,
+		AllowedMethods:   cfg.AllowedMethods,
+		AllowedHeaders:   cfg.AllowedHeaders,
+		ExposedHeaders:   cfg.ExposedHeaders,
+		AllowCredentials: cfg.AllowCredentials,
+		MaxAge:           int(cfg.MaxAge / time.Second),
+	}).Handler(next)
+}
+
+// grpcWebHeaders are the request headers of gRPC-Web clients.
+var grpcWebHeaders = []string{"Content-Type", "Grpc-Timeout", "X-Grpc-Web", "X-User-Agent"}
+
+// GRPCWeb returns a handler translating the gRPC-Web calls, in binary or
+// text format, into gRPC calls served by server, and passing the other calls
+// to next. The preflight requests and CORS headers of gRPC-Web calls follow
+// cfg like those of the HTTP routes, except that the headers of gRPC-Web
+// clients are always allowed and the response headers of the calls always
+// exposed, as gRPC-Web clients read their status from them.
+func GRPCWeb(server *grpc.Server, next http.Handler, cfg CORSConfig) http.Handler {
+	web := grpcweb.WrapServer(server,
+		grpcweb.WithOriginFunc(cfg.allowOrigin),
+		// The services of the proxy are not registered with server.
+		grpcweb.WithCorsForRegisteredEndpointsOnly(false),
+	)
+	// The CORS handling of web always allows credentials and sets its own
+	// max age, so calls bypass it. The exposed headers are left to web,
+	// which sets them on every response.
+	handler := cors.New(cors.Options{
+		AllowOriginFunc:  cfg.allowOrigin,
+		AllowedMethods:   cfg.AllowedMethods,
+		AllowedHeaders:   append(append([]string{}, grpcWebHeaders...), cfg.AllowedHeaders...),
+		AllowCredentials: cfg.AllowCredentials,
+		MaxAge:           int(cfg.MaxAge / time.Second),
+	}).Handler(http.HandlerFunc(web.HandleGrpcWebRequest))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if web.IsGrpcWebRequest(r) || web.IsAcceptableGrpcCorsRequest(r) {
+			handler.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,314 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/mwitkow/grpc-proxy/proxy"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/cage1016/gokitconsulk8s/pb/addsvc"
+)
+
+// teapot marks the responses of the handler behind CORS and GRPCWeb.
+var teapot = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusTeapot)
+})
+
+// corsCase is a request to a CORS handler and the CORS headers of its
+// response, an empty header standing for a header that is not set.
+type corsCase struct {
+	name    string
+	method  string
+	path    string
+	header  map[string]string
+	status  int
+	want    map[string]string
+	wantHas map[string]string
+}
+
+func (c corsCase) run(t *testing.T, url string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(c.method, url+c.path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range c.header {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != c.status {
+		t.Errorf("status %d, want %d", resp.StatusCode, c.status)
+	}
+	for k, want := range c.want {
+		if got := resp.Header.Get(k); got != want {
+			t.Errorf("%s %q, want %q", k, got, want)
+		}
+	}
+	for k, want := range c.wantHas {
+		if got := strings.ToLower(resp.Header.Get(k)); !strings.Contains(got, strings.ToLower(want)) {
+			t.Errorf("%s %q, want it to contain %q", k, got, want)
+		}
+	}
+	return resp
+}
+
+func TestCORS(t *testing.T) {
+	cfg := CORSConfig{
+		AllowedOrigins:   []string{"https://app.example.com", "https://*.example.org"},
+		AllowedMethods:   []string{"GET", "POST"},
+		AllowedHeaders:   []string{"X-Request-Id"},
+		ExposedHeaders:   []string{"X-Request-Id"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	}
+	srv := httptest.NewServer(CORS(teapot, cfg))
+	defer srv.Close()
+	none := httptest.NewServer(CORS(teapot, CORSConfig{}))
+	defer none.Close()
+
+	preflight := func(origin string) map[string]string {
+		return map[string]string{
+			"Origin":                         origin,
+			"Access-Control-Request-Method":  "POST",
+			"Access-Control-Request-Headers": "x-request-id",
+		}
+	}
+	for _, c := range []corsCase{
+		{
+			name:   "preflight",
+			method: http.MethodOptions,
+			header: preflight("https://app.example.com"),
+			status: http.StatusOK,
+			want: map[string]string{
+				"Access-Control-Allow-Origin":      "https://app.example.com",
+				"Access-Control-Allow-Methods":     "POST",
+				"Access-Control-Allow-Headers":     "X-Request-Id",
+				"Access-Control-Allow-Credentials": "true",
+				"Access-Control-Max-Age":           "600",
+			},
+		},
+		{
+			name:   "preflight of a pattern origin",
+			method: http.MethodOptions,
+			header: preflight("https://api.example.org"),
+			status: http.StatusOK,
+			want:   map[string]string{"Access-Control-Allow-Origin": "https://api.example.org"},
+		},
+		{
+			name:   "preflight of another origin",
+			method: http.MethodOptions,
+			header: preflight("https://evil.example.net"),
+			status: http.StatusOK,
+			want: map[string]string{
+				"Access-Control-Allow-Origin":  "",
+				"Access-Control-Allow-Methods": "",
+				"Access-Control-Max-Age":       "",
+			},
+		},
+		{
+			name:   "preflight of a method not allowed",
+			method: http.MethodOptions,
+			header: map[string]string{"Origin": "https://app.example.com", "Access-Control-Request-Method": "DELETE"},
+			status: http.StatusOK,
+			want:   map[string]string{"Access-Control-Allow-Origin": ""},
+		},
+		{
+			name:   "call",
+			method: http.MethodGet,
+			header: map[string]string{"Origin": "https://app.example.com"},
+			status: http.StatusTeapot,
+			want: map[string]string{
+				"Access-Control-Allow-Origin":      "https://app.example.com",
+				"Access-Control-Expose-Headers":    "X-Request-Id",
+				"Access-Control-Allow-Credentials": "true",
+			},
+		},
+		{
+			name:   "call from another origin",
+			method: http.MethodGet,
+			header: map[string]string{"Origin": "https://evil.example.net"},
+			status: http.StatusTeapot,
+			want: map[string]string{
+				"Access-Control-Allow-Origin":   "",
+				"Access-Control-Expose-Headers": "",
+			},
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			c.run(t, srv.URL).Body.Close()
+		})
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		c := corsCase{
+			method: http.MethodOptions,
+			header: preflight("https://app.example.com"),
+			status: http.StatusTeapot,
+			want:   map[string]string{"Access-Control-Allow-Origin": ""},
+		}
+		c.run(t, none.URL).Body.Close()
+	})
+}
+
+// grpcWebFrame returns a gRPC-Web frame carrying b, flag 0x80 marking
+// trailers.
+func grpcWebFrame(flag byte, b []byte) []byte {
+	frame := make([]byte, 5, 5+len(b))
+	frame[0] = flag
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(b)))
+	return append(frame, b...)
+}
+
+// newGRPCWebTestServer returns a server of GRPCWeb in front of a gRPC proxy
+// forwarding every call to wsAddsvc, as the router does.
+func newGRPCWebTestServer(t *testing.T, cfg CORSConfig) *httptest.Server {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	backend := grpc.NewServer()
+	pb.RegisterAddsvcServer(backend, wsAddsvc{})
+	go backend.Serve(lis)
+	t.Cleanup(backend.Stop)
+	conn, err := grpc.Dial("bufconn", grpc.WithInsecure(),
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return lis.Dial() }),
+		grpc.WithDefaultCallOptions(grpc.CallCustomCodec(proxy.Codec())),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	server := grpc.NewServer(
+		grpc.CustomCodec(proxy.Codec()),
+		grpc.UnknownServiceHandler(proxy.TransparentHandler(func(ctx context.Context, fullMethodName string) (context.Context, *grpc.ClientConn, error) {
+			return ctx, conn, nil
+		})),
+	)
+	t.Cleanup(server.Stop)
+	srv := httptest.NewServer(GRPCWeb(server, teapot, cfg))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestGRPCWeb(t *testing.T) {
+	cfg := CORSConfig{
+		AllowedOrigins:   []string{"https://app.example.com"},
+		AllowedMethods:   []string{"GET", "POST"},
+		AllowedHeaders:   []string{"X-Request-Id"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	}
+	srv := newGRPCWebTestServer(t, cfg)
+
+	preflight := func(origin string) map[string]string {
+		return map[string]string{
+			"Origin":                         origin,
+			"Access-Control-Request-Method":  "POST",
+			"Access-Control-Request-Headers": "content-type,x-grpc-web,x-user-agent,x-request-id",
+		}
+	}
+	for _, c := range []corsCase{
+		{
+			name:   "preflight",
+			method: http.MethodOptions,
+			path:   "/pb.Addsvc/Concat",
+			header: preflight("https://app.example.com"),
+			status: http.StatusOK,
+			want: map[string]string{
+				"Access-Control-Allow-Origin":      "https://app.example.com",
+				"Access-Control-Allow-Methods":     "POST",
+				"Access-Control-Allow-Credentials": "true",
+				"Access-Control-Max-Age":           "600",
+			},
+			wantHas: map[string]string{"Access-Control-Allow-Headers": "X-Grpc-Web"},
+		},
+		{
+			name:   "preflight of another origin",
+			method: http.MethodOptions,
+			path:   "/pb.Addsvc/Concat",
+			header: preflight("https://evil.example.net"),
+			status: http.StatusOK,
+			want: map[string]string{
+				"Access-Control-Allow-Origin":  "",
+				"Access-Control-Allow-Headers": "",
+			},
+		},
+		{
+			name:   "not gRPC-Web",
+			method: http.MethodGet,
+			path:   "/addsvc/sum",
+			header: map[string]string{"Origin": "https://app.example.com"},
+			status: http.StatusTeapot,
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			c.run(t, srv.URL).Body.Close()
+		})
+	}
+
+	t.Run("call", func(t *testing.T) {
+		msg, err := proto.Marshal(&pb.ConcatRequest{A: "x", B: "y"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, err := http.NewRequest(http.MethodPost, srv.URL+"/pb.Addsvc/Concat", bytes.NewReader(grpcWebFrame(0, msg)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Origin", "https://app.example.com")
+		req.Header.Set("Content-Type", "application/grpc-web+proto")
+		req.Header.Set("X-Grpc-Web", "1")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("status %d, want %d", resp.StatusCode, http.StatusOK)
+		}
+		if got := resp.Header.Get("Access-Control-Allow-Origin"); got != "https://app.example.com" {
+			t.Errorf("Access-Control-Allow-Origin %q, want https://app.example.com", got)
+		}
+		if got := resp.Header.Get("Access-Control-Expose-Headers"); !strings.Contains(strings.ToLower(got), "grpc-status") {
+			t.Errorf("Access-Control-Expose-Headers %q, want grpc-status exposed", got)
+		}
+
+		body, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var (
+			reply   pb.ConcatReply
+			trailer string
+		)
+		for len(body) >= 5 {
+			n := binary.BigEndian.Uint32(body[1:5])
+			frame := body[5 : 5+n]
+			if body[0]&0x80 != 0 {
+				trailer = string(frame)
+			} else if err := proto.Unmarshal(frame, &reply); err != nil {
+				t.Fatal(err)
+			}
+			body = body[5+n:]
+		}
+		if reply.Rs != "xy" {
+			t.Errorf("reply %q, want xy", reply.Rs)
+		}
+		if !strings.Contains(trailer, "grpc-status: 0") {
+			t.Errorf("trailer %q, want grpc-status 0", trailer)
+		}
+	})
+}
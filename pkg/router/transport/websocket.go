@@ -0,0 +1,484 @@
+package transport
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	descpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/gorilla/websocket"
+	stdzipkin "github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/model"
+	"github.com/openzipkin/zipkin-go/propagation/b3"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// WebSocketPath is where the router serves its WebSocket gateway.
+const WebSocketPath = "/ws"
+
+const (
+	// wsWriteTimeout bounds the writing of a message to a socket.
+	wsWriteTimeout = 10 * time.Second
+	// wsCallQueue is the number of messages of a client stream queued for a
+	// call until they can be sent upstream.
+	wsCallQueue = 16
+)
+
+// wsRequest is a message from the client of a WebSocket. The first message of
+// a call names its method; the following ones carry the messages of client
+// streams, end them or cancel the call.
+type wsRequest struct {
+	// ID correlates the messages of a call, it is chosen by the client.
+	ID       string            `json:"id"`
+	Method   string            `json:"method,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+	Message  json.RawMessage   `json:"message,omitempty"`
+	// End closes the client stream of the call.
+	End    bool `json:"end,omitempty"`
+	Cancel bool `json:"cancel,omitempty"`
+}
+
+// wsResponse is a message to the client of a WebSocket: the headers of a
+// call, one of its messages, or its end with its status and trailers.
+type wsResponse struct {
+	ID      string          `json:"id"`
+	Header  metadata.MD     `json:"header,omitempty"`
+	Message json.RawMessage `json:"message,omitempty"`
+	End     bool            `json:"end,omitempty"`
+	Status  *wsStatus       `json:"status,omitempty"`
+	Trailer metadata.MD     `json:"trailer,omitempty"`
+}
+
+type wsStatus struct {
+	Code    codes.Code `json:"code"`
+	Message string     `json:"message,omitempty"`
+}
+
+// wsMethod is a gRPC method callable through the gateway.
+type wsMethod struct {
+	desc     grpc.StreamDesc
+	request  reflect.Type
+	response reflect.Type
+}
+
+// WebSocketConfig configures a WebSocketGateway.
+type WebSocketConfig struct {
+	// MaxCalls caps the calls in progress on a socket.
+	MaxCalls int
+	// MaxMessageBytes caps the size of the messages read from a socket.
+	MaxMessageBytes int64
+}
+
+// WebSocketGateway lets browsers make gRPC calls, unary or streaming, by
+// exchanging JSON messages on a WebSocket. A socket multiplexes calls, the
+// messages of a call being correlated by the id the client gives it:
+//
+//	→ {"id":"1","method":"/pb.Addsvc/Sum","metadata":{"x-request-id":"abc"},"message":{"a":1,"b":2}}
+//	← {"id":"1","header":{"x-request-id":["abc"]}}
+//	← {"id":"1","message":{"rs":"3"}}
+//	← {"id":"1","end":true,"status":{"code":0}}
+//
+// The messages of client streams follow with the same id, {"id":"1","end":true}
+// closes the stream and {"id":"1","cancel":true} cancels the call. Messages are
+// written in the JSON mapping of protobuf.
+//
+// Every call gets a Zipkin span, child of the B3 headers of its metadata or
+// else of those of the WebSocket handshake, and its metadata is forwarded,
+// along with the address of the client in x-forwarded-for.
+type WebSocketGateway struct {
+	conn         *grpc.ClientConn
+	methods      map[string]wsMethod
+	cfg          WebSocketConfig
+	upgrader     websocket.Upgrader
+	zipkinTracer *stdzipkin.Tracer
+	logger       log.Logger
+}
+
+// NewWebSocketGateway returns a gateway making its calls over conn to the
+// methods of the services of the protobuf files, as registered by their
+// generated code, e.g. addsvc.proto. The WebSocket handshakes of other
+// origins than the router's are accepted when cors allows them.
+func NewWebSocketGateway(conn *grpc.ClientConn, files []string, cfg WebSocketConfig, cors CORSConfig, zipkinTracer *stdzipkin.Tracer, logger log.Logger) (*WebSocketGateway, error) {
+	methods := map[string]wsMethod{}
+	for _, file := range files {
+		if err := addMethods(methods, file); err != nil {
+			return nil, err
+		}
+	}
+	return &WebSocketGateway{
+		conn:    conn,
+		methods: methods,
+		cfg:     cfg,
+		upgrader: websocket.Upgrader{
+			CheckOrigin: func(r *http.Request) bool {
+				origin := r.Header.Get("Origin")
+				if origin == "" || cors.allowOrigin(origin) {
+					return true
+				}
+				u, err := url.Parse(origin)
+				return err == nil && strings.EqualFold(u.Host, r.Host)
+			},
+		},
+		zipkinTracer: zipkinTracer,
+		logger:       log.With(logger, "gateway", "websocket"),
+	}, nil
+}
+
+// addMethods adds the methods of the services of file to methods.
+func addMethods(methods map[string]wsMethod, file string) error {
+	gz := proto.FileDescriptor(file)
+	if gz == nil {
+		return fmt.Errorf("protobuf file %s is not registered", file)
+	}
+	r, err := gzip.NewReader(bytes.NewReader(gz))
+	if err != nil {
+		return fmt.Errorf("protobuf file %s: %v", file, err)
+	}
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("protobuf file %s: %v", file, err)
+	}
+	var fd descpb.FileDescriptorProto
+	if err := proto.Unmarshal(b, &fd); err != nil {
+		return fmt.Errorf("protobuf file %s: %v", file, err)
+	}
+	for _, sd := range fd.GetService() {
+		service := sd.GetName()
+		if fd.GetPackage() != "" {
+			service = fd.GetPackage() + "." + service
+		}
+		for _, md := range sd.GetMethod() {
+			m := wsMethod{
+				desc: grpc.StreamDesc{
+					StreamName:    md.GetName(),
+					ClientStreams: md.GetClientStreaming(),
+					ServerStreams: md.GetServerStreaming(),
+				},
+				request:  proto.MessageType(strings.TrimPrefix(md.GetInputType(), ".")),
+				response: proto.MessageType(strings.TrimPrefix(md.GetOutputType(), ".")),
+			}
+			if m.request == nil || m.response == nil {
+				return fmt.Errorf("protobuf file %s: types of %s.%s are not registered", file, service, md.GetName())
+			}
+			methods["/"+service+"/"+md.GetName()] = m
+		}
+	}
+	return nil
+}
+
+// ServeHTTP upgrades the connection to a WebSocket and serves its calls until
+// it is closed.
+func (g *WebSocketGateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ws, err := g.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		level.Debug(g.logger).Log("remote", r.RemoteAddr, "err", err)
+		return
+	}
+	ws.SetReadLimit(g.cfg.MaxMessageBytes)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &wsSession{
+		g:         g,
+		ws:        ws,
+		ctx:       ctx,
+		parent:    g.zipkinTracer.Extract(b3.ExtractHTTP(r)),
+		forwarded: forwardedFor(r),
+		calls:     map[string]*wsCall{},
+	}
+	level.Debug(g.logger).Log("remote", r.RemoteAddr, "socket", "open")
+	err = s.serve()
+	cancel()
+	s.wg.Wait()
+	ws.Close()
+	level.Debug(g.logger).Log("remote", r.RemoteAddr, "socket", "closed", "err", err)
+}
+
+// forwardedFor returns the X-Forwarded-For header of r with the address of
+// its client appended.
+func forwardedFor(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+		return xff + ", " + host
+	}
+	return host
+}
+
+// wsSession serves the calls of a socket.
+type wsSession struct {
+	g         *WebSocketGateway
+	ws        *websocket.Conn
+	ctx       context.Context
+	parent    model.SpanContext
+	forwarded string
+	wg        sync.WaitGroup
+
+	wmtx sync.Mutex // serializes writes to ws
+
+	mtx   sync.Mutex
+	calls map[string]*wsCall
+}
+
+// serve reads the messages of the socket until it fails or is closed.
+func (s *wsSession) serve() error {
+	for {
+		var req wsRequest
+		if err := s.ws.ReadJSON(&req); err != nil {
+			if websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				return nil
+			}
+			switch err.(type) {
+			case *json.SyntaxError, *json.UnmarshalTypeError:
+				s.end(req.ID, status.New(codes.InvalidArgument, err.Error()), nil)
+				continue
+			}
+			return err
+		}
+		s.handle(req)
+	}
+}
+
+func (s *wsSession) handle(req wsRequest) {
+	if req.ID == "" {
+		s.end("", status.New(codes.InvalidArgument, "missing call id"), nil)
+		return
+	}
+	s.mtx.Lock()
+	c, ok := s.calls[req.ID]
+	s.mtx.Unlock()
+	if ok {
+		c.handle(req)
+		return
+	}
+	if req.Cancel || (req.Method == "" && (req.End || req.Message != nil)) {
+		// The call has just ended.
+		return
+	}
+
+	m, ok := s.g.methods[req.Method]
+	if !ok {
+		s.end(req.ID, status.Newf(codes.Unimplemented, "unknown method %q", req.Method), nil)
+		return
+	}
+	s.mtx.Lock()
+	if len(s.calls) >= s.g.cfg.MaxCalls {
+		s.mtx.Unlock()
+		s.end(req.ID, status.Newf(codes.ResourceExhausted, "more than %d calls in progress", s.g.cfg.MaxCalls), nil)
+		return
+	}
+	c = newWSCall(s, req, m)
+	s.calls[req.ID] = c
+	s.mtx.Unlock()
+	if req.Message == nil && !m.desc.ClientStreams {
+		req.Message = json.RawMessage("{}")
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		c.run()
+		s.mtx.Lock()
+		delete(s.calls, req.ID)
+		s.mtx.Unlock()
+	}()
+	c.handle(req)
+}
+
+func (s *wsSession) write(resp wsResponse) {
+	s.wmtx.Lock()
+	defer s.wmtx.Unlock()
+	s.ws.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	if err := s.ws.WriteJSON(resp); err != nil {
+		level.Debug(s.g.logger).Log("id", resp.ID, "err", err)
+	}
+}
+
+func (s *wsSession) end(id string, st *status.Status, trailer metadata.MD) {
+	s.write(wsResponse{
+		ID:      id,
+		End:     true,
+		Status:  &wsStatus{Code: st.Code(), Message: st.Message()},
+		Trailer: trailer,
+	})
+}
+
+// wsCall is a call in progress on a socket. The messages of the client are
+// queued in in, closed when the client ends its stream.
+type wsCall struct {
+	s      *wsSession
+	id     string
+	method string
+	m      wsMethod
+	md     metadata.MD
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	in     chan json.RawMessage
+	closed bool // in is closed, only accessed by wsSession.serve
+
+	mtx sync.Mutex
+	err *status.Status // set when the client breaks the call
+}
+
+func newWSCall(s *wsSession, req wsRequest, m wsMethod) *wsCall {
+	md := metadata.MD{}
+	for k, v := range req.Metadata {
+		md.Set(k, v)
+	}
+	md.Set("x-forwarded-for", s.forwarded)
+	ctx, cancel := context.WithCancel(s.ctx)
+	return &wsCall{
+		s:      s,
+		id:     req.ID,
+		method: req.Method,
+		m:      m,
+		md:     md,
+		ctx:    ctx,
+		cancel: cancel,
+		in:     make(chan json.RawMessage, wsCallQueue),
+	}
+}
+
+// handle passes a message of the client to the call.
+func (c *wsCall) handle(req wsRequest) {
+	switch {
+	case req.Cancel:
+		c.fail(status.New(codes.Canceled, "call canceled by the client"))
+		return
+	case c.closed && (req.Message != nil || req.End):
+		c.fail(status.New(codes.InvalidArgument, "message after the end of the client stream"))
+		return
+	}
+	if req.Message != nil {
+		// Waiting for room would hold up every call of the socket, so a
+		// client outpacing the upstream fails its call instead.
+		select {
+		case c.in <- req.Message:
+		case <-c.ctx.Done():
+			return
+		default:
+			c.fail(status.Newf(codes.ResourceExhausted, "more than %d messages of the client stream queued", cap(c.in)))
+			return
+		}
+	}
+	if req.End || !c.m.desc.ClientStreams {
+		c.closed = true
+		close(c.in)
+	}
+}
+
+// fail ends the call with st, unless it has already failed.
+func (c *wsCall) fail(st *status.Status) {
+	c.mtx.Lock()
+	if c.err == nil {
+		c.err = st
+	}
+	c.mtx.Unlock()
+	c.cancel()
+}
+
+func (c *wsCall) failure() *status.Status {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.err
+}
+
+// run makes the call and writes its responses to the socket.
+func (c *wsCall) run() {
+	defer c.cancel()
+
+	sc := c.s.parent
+	if psc := c.s.g.zipkinTracer.Extract(b3.ExtractGRPC(&c.md)); psc.Err == nil && !psc.TraceID.Empty() {
+		sc = psc
+	}
+	span := c.s.g.zipkinTracer.StartSpan("websocket "+c.method, stdzipkin.Parent(sc))
+	span.Tag("websocket.id", c.id)
+	defer span.Finish()
+	ctx := metadata.NewOutgoingContext(stdzipkin.NewContext(c.ctx, span), c.md)
+
+	stream, err := c.s.g.conn.NewStream(ctx, &c.m.desc, c.method)
+	if err != nil {
+		c.finish(err, nil)
+		return
+	}
+	go c.send(stream)
+
+	if header, err := stream.Header(); err == nil && len(header) > 0 {
+		c.s.write(wsResponse{ID: c.id, Header: header})
+	}
+	marshaler := jsonpb.Marshaler{EmitDefaults: true}
+	for {
+		resp := reflect.New(c.m.response.Elem()).Interface().(proto.Message)
+		if err := stream.RecvMsg(resp); err != nil {
+			if err == io.EOF {
+				err = nil
+			}
+			c.finish(err, stream.Trailer())
+			return
+		}
+		var b bytes.Buffer
+		if err := marshaler.Marshal(&b, resp); err != nil {
+			c.fail(status.Newf(codes.Internal, "encoding response: %v", err))
+			continue
+		}
+		c.s.write(wsResponse{ID: c.id, Message: b.Bytes()})
+		if !c.m.desc.ServerStreams {
+			c.finish(nil, stream.Trailer())
+			return
+		}
+	}
+}
+
+// send sends the messages of the client on stream.
+func (c *wsCall) send(stream grpc.ClientStream) {
+	for {
+		select {
+		case msg, ok := <-c.in:
+			if !ok {
+				stream.CloseSend()
+				return
+			}
+			req := reflect.New(c.m.request.Elem()).Interface().(proto.Message)
+			if err := jsonpb.Unmarshal(bytes.NewReader(msg), req); err != nil {
+				c.fail(status.Newf(codes.InvalidArgument, "decoding message: %v", err))
+				return
+			}
+			if err := stream.SendMsg(req); err != nil {
+				// The error is returned by RecvMsg.
+				return
+			}
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+// finish writes the end of the call, failed with err unless the client broke
+// it first.
+func (c *wsCall) finish(err error, trailer metadata.MD) {
+	st := c.failure()
+	if st == nil {
+		st = status.Convert(err)
+	}
+	c.s.end(c.id, st, trailer)
+}
@@ -0,0 +1,266 @@
+package transport
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/gorilla/websocket"
+	stdzipkin "github.com/openzipkin/zipkin-go"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	testpb "google.golang.org/grpc/interop/grpc_testing"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/cage1016/gokitconsulk8s/pb/addsvc"
+)
+
+// wsAddsvc answers Sum after the delay of the x-delay metadata of the call,
+// echoing its x-call metadata in the headers, and fails it when a is
+// negative. Its other methods are not called.
+type wsAddsvc struct {
+	pb.AddsvcServer
+}
+
+func (wsAddsvc) Sum(ctx context.Context, req *pb.SumRequest) (*pb.SumReply, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	grpc.SendHeader(ctx, metadata.Pairs("x-call", strings.Join(md.Get("x-call"), ",")))
+	if vs := md.Get("x-delay"); len(vs) > 0 {
+		d, _ := time.ParseDuration(vs[0])
+		select {
+		case <-time.After(d):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if req.A < 0 {
+		return nil, status.Error(codes.FailedPrecondition, "negative")
+	}
+	return &pb.SumReply{Rs: req.A + req.B}, nil
+}
+
+func (wsAddsvc) Concat(ctx context.Context, req *pb.ConcatRequest) (*pb.ConcatReply, error) {
+	return &pb.ConcatReply{Rs: req.A + req.B}, nil
+}
+
+// wsTestService does not read the messages of StreamingInputCall until the
+// call is canceled. Its other methods are not called.
+type wsTestService struct {
+	testpb.TestServiceServer
+}
+
+func (wsTestService) StreamingInputCall(stream testpb.TestService_StreamingInputCallServer) error {
+	<-stream.Context().Done()
+	return stream.Context().Err()
+}
+
+// newWSTestServer returns a server of a WebSocketGateway to wsAddsvc and
+// wsTestService.
+func newWSTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	pb.RegisterAddsvcServer(server, wsAddsvc{})
+	testpb.RegisterTestServiceServer(server, wsTestService{})
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+	conn, err := grpc.Dial("bufconn", grpc.WithInsecure(), grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+		return lis.Dial()
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	zipkinTracer, err := stdzipkin.NewTracer(nil, stdzipkin.WithNoopTracer(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gateway, err := NewWebSocketGateway(conn, []string{"addsvc.proto", "grpc_testing/test.proto"}, WebSocketConfig{MaxCalls: 2, MaxMessageBytes: 1 << 16}, CORSConfig{}, zipkinTracer, log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(gateway)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestWebSocketGateway(t *testing.T) {
+	srv := newWSTestServer(t)
+
+	slow := func(id, delay string) string {
+		return `{"id":"` + id + `","method":"/pb.Addsvc/Sum","metadata":{"x-call":"` + id + `","x-delay":"` + delay + `"},"message":{"a":2,"b":1}}`
+	}
+	for _, c := range []struct {
+		name string
+		send []string
+		// want lists the responses of each call but its headers, in the
+		// order they are written.
+		want map[string][]string
+		// headers holds the x-call header of the calls that reach the
+		// service.
+		headers map[string]string
+		// ends lists the calls in the order they end, when it is known.
+		ends []string
+	}{
+		{
+			name: "calls interleaved",
+			send: []string{
+				slow("1", "200ms"),
+				`{"id":"2","method":"/pb.Addsvc/Concat","message":{"a":"x","b":"y"}}`,
+			},
+			want: map[string][]string{
+				"1": {`{"id":"1","message":{"rs":"3","err":""}}`, `{"id":"1","end":true,"status":{"code":0}}`},
+				"2": {`{"id":"2","message":{"rs":"xy","err":""}}`, `{"id":"2","end":true,"status":{"code":0}}`},
+			},
+			headers: map[string]string{"1": "1"},
+			ends:    []string{"2", "1"},
+		},
+		{
+			name: "calls beyond the limit",
+			send: []string{slow("1", "200ms"), slow("2", "300ms"), slow("3", "0s")},
+			want: map[string][]string{
+				"1": {`{"id":"1","message":{"rs":"3","err":""}}`, `{"id":"1","end":true,"status":{"code":0}}`},
+				"2": {`{"id":"2","message":{"rs":"3","err":""}}`, `{"id":"2","end":true,"status":{"code":0}}`},
+				"3": {`{"id":"3","end":true,"status":{"code":8,"message":"more than 2 calls in progress"}}`},
+			},
+			headers: map[string]string{"1": "1", "2": "2"},
+			ends:    []string{"3", "1", "2"},
+		},
+		{
+			name: "call canceled",
+			send: []string{slow("1", "200ms"), `{"id":"2","method":"/pb.Addsvc/Sum","message":{"a":1}}`, `{"id":"1","cancel":true}`},
+			want: map[string][]string{
+				"1": {`{"id":"1","end":true,"status":{"code":1,"message":"call canceled by the client"}}`},
+				"2": {`{"id":"2","message":{"rs":"1","err":""}}`, `{"id":"2","end":true,"status":{"code":0}}`},
+			},
+		},
+		{
+			name: "message after the end",
+			send: []string{slow("1", "200ms"), `{"id":"1","message":{"a":1}}`},
+			want: map[string][]string{
+				"1": {`{"id":"1","end":true,"status":{"code":3,"message":"message after the end of the client stream"}}`},
+			},
+		},
+		{
+			name: "failures",
+			send: []string{
+				`{"id":"1","method":"/pb.Addsvc/Sum","message":{"a":-1}}`,
+				`{"id":"2","method":"/pb.Nosvc/Sum"}`,
+				`{"method":"/pb.Addsvc/Sum"}`,
+				`{"id":"3","method":"/pb.Addsvc/Sum","message":{"c":1}}`,
+			},
+			want: map[string][]string{
+				"1": {`{"id":"1","end":true,"status":{"code":9,"message":"negative"}}`},
+				"2": {`{"id":"2","end":true,"status":{"code":12,"message":"unknown method \"/pb.Nosvc/Sum\""}}`},
+				"":  {`{"id":"","end":true,"status":{"code":3,"message":"missing call id"}}`},
+				"3": {`{"id":"3","end":true,"status":{"code":3,"message":"decoding message: unknown field \"c\" in pb.SumRequest"}}`},
+			},
+			headers: map[string]string{"1": ""},
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer ws.Close()
+			for _, m := range c.send {
+				if err := ws.WriteMessage(websocket.TextMessage, []byte(m)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			ws.SetReadDeadline(time.Now().Add(5 * time.Second))
+			got := map[string][]string{}
+			headers := map[string]string{}
+			var ends []string
+			for len(ends) < len(c.want) {
+				_, b, err := ws.ReadMessage()
+				if err != nil {
+					t.Fatalf("after %v: %v", got, err)
+				}
+				var resp wsResponse
+				if err := json.Unmarshal(b, &resp); err != nil {
+					t.Fatal(err)
+				}
+				if resp.Header != nil {
+					headers[resp.ID] = strings.Join(resp.Header.Get("x-call"), ",")
+					continue
+				}
+				got[resp.ID] = append(got[resp.ID], strings.TrimSpace(string(b)))
+				if resp.End {
+					ends = append(ends, resp.ID)
+				}
+			}
+			for id, want := range c.want {
+				if !reflect.DeepEqual(got[id], want) {
+					t.Errorf("call %q: got\n%s\nwant\n%s", id, strings.Join(got[id], "\n"), strings.Join(want, "\n"))
+				}
+			}
+			for id, want := range c.headers {
+				if got, ok := headers[id]; !ok || got != want {
+					t.Errorf("call %q: x-call header %q, %v, want %q", id, got, ok, want)
+				}
+			}
+			if c.ends != nil && !reflect.DeepEqual(ends, c.ends) {
+				t.Errorf("calls ended in the order %q, want %q", ends, c.ends)
+			}
+		})
+	}
+}
+
+// TestWebSocketGatewayQueue checks that a client stream the upstream does not
+// take fails its call without holding up the other calls of the socket.
+func TestWebSocketGatewayQueue(t *testing.T) {
+	srv := newWSTestServer(t)
+	ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Close()
+
+	// Enough messages to fill the flow control window of the stream and
+	// then the queue of the call.
+	payload := `{"payload":{"body":"` + base64.StdEncoding.EncodeToString(make([]byte, 16<<10)) + `"}}`
+	send := []string{`{"id":"1","method":"/grpc.testing.TestService/StreamingInputCall"}`}
+	for i := 0; i < 100; i++ {
+		send = append(send, `{"id":"1","message":`+payload+`}`)
+	}
+	send = append(send, `{"id":"2","method":"/pb.Addsvc/Concat","message":{"a":"x","b":"y"}}`)
+	for _, m := range send {
+		if err := ws.WriteMessage(websocket.TextMessage, []byte(m)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := map[string]string{
+		"1": `{"id":"1","end":true,"status":{"code":8,"message":"more than 16 messages of the client stream queued"}}`,
+		"2": `{"id":"2","end":true,"status":{"code":0}}`,
+	}
+	ws.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for len(want) > 0 {
+		_, b, err := ws.ReadMessage()
+		if err != nil {
+			t.Fatalf("calls %v not ended: %v", want, err)
+		}
+		var resp wsResponse
+		if err := json.Unmarshal(b, &resp); err != nil {
+			t.Fatal(err)
+		}
+		if !resp.End {
+			continue
+		}
+		if got := strings.TrimSpace(string(b)); got != want[resp.ID] {
+			t.Errorf("call %q: got\n%s\nwant\n%s", resp.ID, got, want[resp.ID])
+		}
+		delete(want, resp.ID)
+	}
+}
@@ -0,0 +1,228 @@
+// Package negotiation restricts the HTTP handlers of the services to the
+// request formats they accept, JSON, protobuf, form bodies and query strings,
+// and negotiates the format of their responses.
+package negotiation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/golang/protobuf/proto"
+)
+
+const (
+	// JSONContentType is the content type of JSON bodies.
+	JSONContentType     = "application/json; charset=utf-8"
+	protobufContentType = "application/x-protobuf"
+	formContentType     = "application/x-www-form-urlencoded"
+
+	// maxBodyBytes bounds the size of the request bodies Handler reads.
+	maxBodyBytes = 1 << 20
+)
+
+type contextKey int
+
+// responseFormatKey holds the media type Handler picked for the response.
+const responseFormatKey contextKey = iota
+
+// Error is an error of the HTTP transport itself, replied with Status.
+type Error struct {
+	Status int
+	Msg    string
+}
+
+func (e Error) Error() string {
+	return e.Msg
+}
+
+// Handler restricts h to GET requests carrying their arguments in the query
+// string and POST requests with a JSON, protobuf or form body of at most
+// maxBodyBytes. It picks the format of the response from the Accept header,
+// JSON unless protobuf is preferred. Other requests are replied to with an
+// Error, encoded by encodeError.
+func Handler(h http.Handler, encodeError httptransport.ErrorEncoder) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPost:
+			mt, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+			if err != nil || (mt != "application/json" && mt != protobufContentType && mt != formContentType) {
+				encodeError(r.Context(), Error{http.StatusUnsupportedMediaType,
+					"Content-Type must be application/json, " + protobufContentType + " or " + formContentType}, w)
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+		default:
+			w.Header().Set("Allow", http.MethodGet+", "+http.MethodPost)
+			encodeError(r.Context(), Error{http.StatusMethodNotAllowed, fmt.Sprintf("method %s not allowed", r.Method)}, w)
+			return
+		}
+
+		format, ok := responseFormat(r.Header.Get("Accept"))
+		if !ok {
+			encodeError(r.Context(), Error{http.StatusNotAcceptable, "Accept must allow application/json or " + protobufContentType}, w)
+			return
+		}
+		h.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), responseFormatKey, format)))
+	})
+}
+
+// responseFormat returns the first media type of accept the handlers can
+// reply with, skipping those with a zero quality. An empty accept gets JSON.
+func responseFormat(accept string) (string, bool) {
+	if accept == "" {
+		return "application/json", true
+	}
+	for _, v := range strings.Split(accept, ",") {
+		mt, params, err := mime.ParseMediaType(v)
+		if err != nil {
+			continue
+		}
+		if q, err := strconv.ParseFloat(params["q"], 64); err == nil && q == 0 {
+			continue
+		}
+		switch mt {
+		case "application/json", "application/*", "*/*":
+			return "application/json", true
+		case protobufContentType:
+			return protobufContentType, true
+		}
+	}
+	return "", false
+}
+
+// IsProtobuf tells whether the body of r is a protobuf message.
+func IsProtobuf(r *http.Request) bool {
+	mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return r.Method == http.MethodPost && mt == protobufContentType
+}
+
+// DecodeBody decodes the arguments of r into req, from the query string
+// of a GET or the JSON or form body of a POST.
+func DecodeBody(r *http.Request, req interface{}) error {
+	if r.Method == http.MethodGet {
+		return decodeValues(r.URL.Query(), req)
+	}
+	if mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mt == formContentType {
+		if err := r.ParseForm(); err != nil {
+			return bodyError(err)
+		}
+		return decodeValues(r.PostForm, req)
+	}
+	return decodeJSON(r, req)
+}
+
+// decodeJSON decodes the JSON body of r into v, rejecting unknown fields.
+func decodeJSON(r *http.Request, v interface{}) error {
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	return bodyError(dec.Decode(v))
+}
+
+// DecodeProtobuf decodes the protobuf body of r into msg.
+func DecodeProtobuf(r *http.Request, msg proto.Message) error {
+	b, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return bodyError(err)
+	}
+	return bodyError(proto.Unmarshal(b, msg))
+}
+
+// decodeValues sets the fields of the struct req points to from the values
+// named after their JSON names, rejecting unknown names.
+func decodeValues(values url.Values, req interface{}) error {
+	v := reflect.ValueOf(req).Elem()
+	fields := map[string]reflect.Value{}
+	for i := 0; i < v.NumField(); i++ {
+		name := strings.Split(v.Type().Field(i).Tag.Get("json"), ",")[0]
+		if name != "" && name != "-" {
+			fields[name] = v.Field(i)
+		}
+	}
+
+	for name, vs := range values {
+		f, ok := fields[name]
+		if !ok {
+			return Error{http.StatusBadRequest, fmt.Sprintf("unknown parameter %q", name)}
+		}
+		var err error
+		switch s := vs[0]; f.Kind() {
+		case reflect.String:
+			f.SetString(s)
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			var n int64
+			n, err = strconv.ParseInt(s, 10, f.Type().Bits())
+			f.SetInt(n)
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			var n uint64
+			n, err = strconv.ParseUint(s, 10, f.Type().Bits())
+			f.SetUint(n)
+		case reflect.Float32, reflect.Float64:
+			var n float64
+			n, err = strconv.ParseFloat(s, f.Type().Bits())
+			f.SetFloat(n)
+		case reflect.Bool:
+			var b bool
+			b, err = strconv.ParseBool(s)
+			f.SetBool(b)
+		default:
+			err = fmt.Errorf("unsupported type %s", f.Type())
+		}
+		if err != nil {
+			return Error{http.StatusBadRequest, fmt.Sprintf("parameter %s: %v", name, err)}
+		}
+	}
+	return nil
+}
+
+// bodyError converts an error reading or decoding a request body into the
+// Error replied to the client.
+func bodyError(err error) error {
+	var tooLarge *http.MaxBytesError
+	switch {
+	case err == nil:
+		return nil
+	case err == io.EOF:
+		return Error{http.StatusBadRequest, "request body is empty"}
+	case errors.As(err, &tooLarge):
+		return Error{http.StatusRequestEntityTooLarge, fmt.Sprintf("request body is larger than %d bytes", tooLarge.Limit)}
+	default:
+		return Error{http.StatusBadRequest, err.Error()}
+	}
+}
+
+// wantsProtobuf tells whether Handler picked protobuf for the response.
+func wantsProtobuf(ctx context.Context) bool {
+	format, _ := ctx.Value(responseFormatKey).(string)
+	return format == protobufContentType
+}
+
+// EncodeResponse writes response as JSON, or as the protobuf message toPB
+// converts it to when Handler picked protobuf.
+func EncodeResponse(ctx context.Context, w http.ResponseWriter, response interface{}, toPB func(context.Context, interface{}) (interface{}, error)) error {
+	if !wantsProtobuf(ctx) {
+		return httptransport.EncodeJSONResponse(ctx, w, response)
+	}
+	msg, err := toPB(ctx, response)
+	if err != nil {
+		return err
+	}
+	b, err := proto.Marshal(msg.(proto.Message))
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", protobufContentType)
+	_, err = w.Write(b)
+	return err
+}